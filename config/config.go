@@ -3,6 +3,7 @@ package config
 
 import (
 	"os"
+	"strconv"
 )
 
 type Config struct {
@@ -10,7 +11,6 @@ type Config struct {
 	AppPort      string
 	GinMode      string
 	DatabaseURL  string
-	JWTSecret    string
 	JWTExpiry    string
 	CORSOrigin   string
 	CORSMethods  string
@@ -18,6 +18,28 @@ type Config struct {
 	ResendAPIKey string
 	FromEmail    string
 	FrontendURL  string
+
+	// Reverse proxies allowed to set X-Forwarded-For, comma-separated IPs or CIDRs. None
+	// when empty, so the connection's address is used.
+	TrustedProxies string
+
+	// Email transport: resend, smtp, file or log
+	EmailTransport string
+	EmailFileDir   string
+	SMTPHost       string
+	SMTPPort       int
+	SMTPUsername   string
+	SMTPPassword   string
+	SMTPTLS        string
+
+	// Email templates and branding
+	EmailTemplateDir   string
+	EmailDefaultLocale string
+	EmailAppName       string
+	EmailCompanyName   string
+	EmailLogoURL       string
+	EmailPrimaryColor  string
+	EmailAccentColor   string
 	
 	// Add individual DB config fields for local development
 	DBHost     string
@@ -36,14 +58,31 @@ func GetConfig() *Config {
 			AppPort:      getEnv("PORT", "8080"),
 			GinMode:      getEnv("GIN_MODE", "debug"),
 			DatabaseURL:  os.Getenv("DATABASE_URL"),
-			JWTSecret:    getEnv("JWT_SECRET", "super-secret-jwt-key-here"),
-			JWTExpiry:    getEnv("JWT_EXPIRY", "24h"),
+			JWTExpiry:    getEnv("JWT_EXPIRY", "15m"),
 			CORSOrigin:   getEnv("CORS_ALLOW_ORIGIN", "*"),
 			CORSMethods:  getEnv("CORS_ALLOW_METHODS", "GET,POST,PUT,DELETE,OPTIONS"),
 			CORSHeaders:  getEnv("CORS_ALLOW_HEADERS", "*"),
 			ResendAPIKey: getEnv("RESEND_API_KEY", ""),
 			FromEmail:    getEnv("FROM_EMAIL", "onboarding@resend.dev"),
 			FrontendURL:  getEnv("FRONTEND_URL", "http://localhost:3000"),
+
+			TrustedProxies: os.Getenv("TRUSTED_PROXIES"),
+
+			EmailTransport: os.Getenv("EMAIL_TRANSPORT"),
+			EmailFileDir:   getEnv("EMAIL_FILE_DIR", "emails"),
+			SMTPHost:       os.Getenv("SMTP_HOST"),
+			SMTPPort:       getIntEnv("SMTP_PORT", 0),
+			SMTPUsername:   os.Getenv("SMTP_USERNAME"),
+			SMTPPassword:   os.Getenv("SMTP_PASSWORD"),
+			SMTPTLS:        getEnv("SMTP_TLS", "starttls"),
+
+			EmailTemplateDir:   os.Getenv("EMAIL_TEMPLATE_DIR"),
+			EmailDefaultLocale: getEnv("EMAIL_DEFAULT_LOCALE", "en"),
+			EmailAppName:       getEnv("EMAIL_APP_NAME", "Attendance System"),
+			EmailCompanyName:   getEnv("EMAIL_COMPANY_NAME", "Attendance System"),
+			EmailLogoURL:       os.Getenv("EMAIL_LOGO_URL"),
+			EmailPrimaryColor:  getEnv("EMAIL_PRIMARY_COLOR", "#667eea"),
+			EmailAccentColor:   getEnv("EMAIL_ACCENT_COLOR", "#764ba2"),
 			
 			// Individual DB config
 			DBHost:     getEnv("DB_HOST", "localhost"),
@@ -61,4 +100,12 @@ func getEnv(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
 }
\ No newline at end of file
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"attendance-system/models"
+	"attendance-system/services"
+	"attendance-system/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AlertRuleController struct {
+	alertService *services.AlertService
+}
+
+func NewAlertRuleController() *AlertRuleController {
+	return &AlertRuleController{
+		alertService: services.NewAlertService(),
+	}
+}
+
+// GetAlertRules godoc
+// @Summary List attendance alert rules
+// @Description List the rules that notify employees and managers about late arrivals, missing clock-ins and sessions left open
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]models.AlertRule}
+// @Failure 500 {object} utils.Response
+// @Router /admin/alert-rules [get]
+func (c *AlertRuleController) GetAlertRules(ctx *gin.Context) {
+	rules, err := c.alertService.GetRules()
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Alert rules retrieved successfully", rules)
+}
+
+// CreateAlertRule godoc
+// @Summary Create attendance alert rule
+// @Description Create a rule. late_arrival fires on clock-in when more than threshold_minutes late, missing_clock_in when there's no clock-in threshold_minutes after the shift start, open_session when still clocked in threshold_minutes after the shift end.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param rule body models.AlertRuleRequest true "Alert rule"
+// @Success 201 {object} utils.Response{data=models.AlertRule}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/alert-rules [post]
+func (c *AlertRuleController) CreateAlertRule(ctx *gin.Context) {
+	var req models.AlertRuleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	rule, err := c.alertService.CreateRule(req, utils.GetAuditActor(ctx))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusCreated, "Alert rule created successfully", rule)
+}
+
+// UpdateAlertRule godoc
+// @Summary Update attendance alert rule
+// @Description Replace an alert rule's settings
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Alert rule ID"
+// @Param rule body models.AlertRuleRequest true "Alert rule"
+// @Success 200 {object} utils.Response{data=models.AlertRule}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/alert-rules/{id} [put]
+func (c *AlertRuleController) UpdateAlertRule(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid alert rule ID")
+		return
+	}
+
+	var req models.AlertRuleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	rule, err := c.alertService.UpdateRule(uint(id), req, utils.GetAuditActor(ctx))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Alert rule updated successfully", rule)
+}
+
+// DeleteAlertRule godoc
+// @Summary Delete attendance alert rule
+// @Description Delete an alert rule
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Alert rule ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/alert-rules/{id} [delete]
+func (c *AlertRuleController) DeleteAlertRule(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid alert rule ID")
+		return
+	}
+
+	if err := c.alertService.DeleteRule(uint(id), utils.GetAuditActor(ctx)); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Alert rule deleted successfully", nil)
+}
@@ -29,6 +29,7 @@ func NewAttendanceController() *AttendanceController {
 // @Param attendance body models.AttendanceRequest true "Clock in data"
 // @Success 201 {object} utils.Response{data=models.Attendance}
 // @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
 // @Failure 404 {object} utils.Response
 // @Failure 409 {object} utils.Response
 // @Failure 500 {object} utils.Response
@@ -40,7 +41,12 @@ func (c *AttendanceController) ClockIn(ctx *gin.Context) {
 		return
 	}
 
-	attendance, err := c.attendanceService.ClockIn(req)
+	if !utils.CanAccessEmployee(ctx, req.EmployeeID) {
+		utils.ErrorJSON(ctx, http.StatusForbidden, "You can only clock in for yourself")
+		return
+	}
+
+	attendance, err := c.attendanceService.ClockIn(req, utils.GetAuditActor(ctx))
 	if err != nil {
 		utils.HandleError(ctx, err)
 		return
@@ -58,6 +64,7 @@ func (c *AttendanceController) ClockIn(ctx *gin.Context) {
 // @Param attendance body models.ClockOutRequest true "Clock out data"
 // @Success 200 {object} utils.Response{data=models.Attendance}
 // @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
 // @Failure 404 {object} utils.Response
 // @Failure 409 {object} utils.Response
 // @Failure 500 {object} utils.Response
@@ -69,7 +76,12 @@ func (c *AttendanceController) ClockOut(ctx *gin.Context) {
 		return
 	}
 
-	attendance, err := c.attendanceService.ClockOut(req)
+	if !utils.CanAccessEmployee(ctx, req.EmployeeID) {
+		utils.ErrorJSON(ctx, http.StatusForbidden, "You can only clock out for yourself")
+		return
+	}
+
+	attendance, err := c.attendanceService.ClockOut(req, utils.GetAuditActor(ctx))
 	if err != nil {
 		utils.HandleError(ctx, err)
 		return
@@ -97,6 +109,15 @@ func (c *AttendanceController) GetAttendanceLogs(ctx *gin.Context) {
 	startDate := ctx.Query("start_date")
 	endDate := ctx.Query("end_date")
 	employeeID := ctx.Query("employee_id")
+
+	// Employees can only list their own attendance
+	if !utils.HasPermission(ctx, models.PermissionAttendanceViewAll) {
+		employeeID = utils.GetEmployeeIDFromContext(ctx)
+		if employeeID == "" {
+			utils.ErrorJSON(ctx, http.StatusForbidden, "No employee record linked to this account")
+			return
+		}
+	}
 	
 	departmentID, _ := strconv.ParseUint(ctx.Query("department_id"), 10, 32)
 	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
@@ -128,6 +149,7 @@ func (c *AttendanceController) GetAttendanceLogs(ctx *gin.Context) {
 // @Param start_date query string false "Start date (YYYY-MM-DD)"
 // @Param end_date query string false "End date (YYYY-MM-DD)"
 // @Success 200 {object} utils.Response{data=[]models.AttendanceResponse}
+// @Failure 403 {object} utils.Response
 // @Failure 500 {object} utils.Response
 // @Router /attendance/employee/{employee_id} [get]
 func (c *AttendanceController) GetEmployeeAttendance(ctx *gin.Context) {
@@ -154,6 +176,7 @@ func (c *AttendanceController) GetEmployeeAttendance(ctx *gin.Context) {
 // @Param month query int false "Month (1-12)"
 // @Param year query int false "Year"
 // @Success 200 {object} utils.Response{data=map[string]interface{}}
+// @Failure 403 {object} utils.Response
 // @Failure 500 {object} utils.Response
 // @Router /attendance/stats/{employee_id} [get]
 func (c *AttendanceController) GetAttendanceStats(ctx *gin.Context) {
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"attendance-system/models"
+	"attendance-system/services"
+	"attendance-system/utils"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditController struct {
+	auditService *services.AuditService
+}
+
+func NewAuditController() *AuditController {
+	return &AuditController{
+		auditService: services.NewAuditService(),
+	}
+}
+
+// GetAuditLogs godoc
+// @Summary List audit log entries
+// @Description List administrative changes, newest first, with the values before and after each change
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param actor_id query string false "User or service account ID of the actor"
+// @Param action query string false "Action, e.g. employee.delete or user.role_assign"
+// @Param entity_type query string false "Entity type (employee, department, user, role, attendance, session)"
+// @Param entity_id query string false "Entity ID"
+// @Param request_id query string false "Request ID"
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} utils.Response{data=[]models.AuditLogResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/audit-logs [get]
+func (c *AuditController) GetAuditLogs(ctx *gin.Context) {
+	var filter models.AuditLogFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid query parameters: "+err.Error())
+		return
+	}
+	page, limit := utils.GetPaginationParams(ctx)
+
+	entries, pagination, err := c.auditService.GetLogs(filter, page, limit)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"entries":    entries,
+		"pagination": pagination,
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Audit log retrieved successfully", response)
+}
+
+// ExportAuditLogs godoc
+// @Summary Export audit log
+// @Description Export audit log entries matching the filters as CSV, newest first
+// @Tags admin
+// @Produce text/csv
+// @Security BearerAuth
+// @Param actor_id query string false "User or service account ID of the actor"
+// @Param action query string false "Action"
+// @Param entity_type query string false "Entity type"
+// @Param entity_id query string false "Entity ID"
+// @Param request_id query string false "Request ID"
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Success 200 {file} file "Exported file"
+// @Failure 400 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/audit-logs/export [get]
+func (c *AuditController) ExportAuditLogs(ctx *gin.Context) {
+	var filter models.AuditLogFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid query parameters: "+err.Error())
+		return
+	}
+
+	entries, err := c.auditService.GetLogsForExport(filter)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	filename := fmt.Sprintf("audit-log-%s", time.Now().Format("2006-01-02"))
+	ctx.Header("Content-Type", "text/csv")
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", filename))
+	ctx.Header("Content-Transfer-Encoding", "binary")
+	ctx.Header("Expires", "0")
+
+	writer := csv.NewWriter(ctx.Writer)
+	defer writer.Flush()
+
+	headers := []string{"ID", "Time", "Actor Type", "Actor ID", "Actor", "Action", "Entity Type", "Entity ID", "Changes", "IP Address", "Request ID"}
+	if err := writer.Write(headers); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	for _, entry := range entries {
+		// Keep the changes as one compact JSON cell
+		changes := entry.Changes
+		var compact interface{}
+		if json.Unmarshal([]byte(changes), &compact) == nil {
+			if encoded, err := json.Marshal(compact); err == nil {
+				changes = string(encoded)
+			}
+		}
+
+		record := []string{
+			strconv.FormatUint(uint64(entry.ID), 10),
+			entry.CreatedAt.Format("2006-01-02 15:04:05"),
+			entry.ActorType,
+			entry.ActorID,
+			entry.ActorName,
+			entry.Action,
+			entry.EntityType,
+			entry.EntityID,
+			changes,
+			entry.IPAddress,
+			entry.RequestID,
+		}
+		if err := writer.Write(record); err != nil {
+			return
+		}
+	}
+}
@@ -6,17 +6,20 @@ import (
 	"attendance-system/utils"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type AuthController struct {
-	authService *services.AuthService
+	authService     *services.AuthService
+	throttleService *services.LoginThrottleService
 }
 
 func NewAuthController() *AuthController {
 	return &AuthController{
-		authService: services.NewAuthService(),
+		authService:     services.NewAuthService(),
+		throttleService: services.NewLoginThrottleService(),
 	}
 }
 
@@ -41,7 +44,7 @@ func (c *AuthController) SetupAccount(ctx *gin.Context) {
 		return
 	}
 
-	response, err := c.authService.SetupAccount(req)
+	response, err := c.authService.SetupAccount(req, utils.GetAuditActor(ctx))
 	if err != nil {
 		utils.HandleError(ctx, err)
 		return
@@ -70,7 +73,7 @@ func (c *AuthController) VerifySetupToken(ctx *gin.Context) {
 		return
 	}
 
-	user, err := c.authService.VerifySetupToken(token)
+	setupToken, err := c.authService.VerifySetupToken(token)
 	if err != nil {
 		utils.HandleError(ctx, err)
 		return
@@ -78,9 +81,9 @@ func (c *AuthController) VerifySetupToken(ctx *gin.Context) {
 
 	response := map[string]interface{}{
 		"valid":      true,
-		"email":      user.Email,
-		"username":   user.Username,
-		"expires_at": user.TokenExpires,
+		"email":      setupToken.User.Email,
+		"username":   setupToken.User.Username,
+		"expires_at": setupToken.ExpiresAt,
 	}
 
 	utils.SuccessJSON(ctx, http.StatusOK, "Setup token is valid", response)
@@ -96,6 +99,7 @@ func (c *AuthController) VerifySetupToken(ctx *gin.Context) {
 // @Success 200 {object} utils.Response
 // @Failure 400 {object} utils.Response
 // @Failure 404 {object} utils.Response
+// @Failure 429 {object} utils.Response
 // @Failure 500 {object} utils.Response
 // @Router /auth/forgot-password [post]
 func (c *AuthController) ForgotPassword(ctx *gin.Context) {
@@ -108,7 +112,7 @@ func (c *AuthController) ForgotPassword(ctx *gin.Context) {
 		return
 	}
 
-	err := c.authService.ForgotPassword(req.Email)
+	err := c.authService.ForgotPassword(req.Email, utils.GetClientIP(ctx))
 	if err != nil {
 		// Don't reveal if email exists or not for security
 		utils.SuccessJSON(ctx, http.StatusOK, "If the email exists, password reset instructions have been sent", nil)
@@ -135,7 +139,7 @@ func (c *AuthController) ForgotPassword(ctx *gin.Context) {
 func (c *AuthController) ResetPassword(ctx *gin.Context) {
 	var req struct {
 		Token       string `json:"token" binding:"required"`
-		NewPassword string `json:"new_password" binding:"required,min=8"`
+		NewPassword string `json:"new_password" binding:"required"`
 	}
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -143,7 +147,7 @@ func (c *AuthController) ResetPassword(ctx *gin.Context) {
 		return
 	}
 
-	err := c.authService.ResetPassword(req.Token, req.NewPassword)
+	err := c.authService.ResetPassword(req.Token, req.NewPassword, utils.GetAuditActor(ctx))
 	if err != nil {
 		utils.HandleError(ctx, err)
 		return
@@ -152,22 +156,154 @@ func (c *AuthController) ResetPassword(ctx *gin.Context) {
 	utils.SuccessJSON(ctx, http.StatusOK, "Password reset successfully", nil)
 }
 
+// RequestEmailVerification godoc
+// @Summary Request email verification
+// @Description Email the current user a link to verify their email address
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /auth/verify-email/request [post]
+func (c *AuthController) RequestEmailVerification(ctx *gin.Context) {
+	userID, err := strconv.ParseUint(utils.GetUserIDFromContext(ctx), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if err := c.authService.RequestEmailVerification(uint(userID), utils.GetClientIP(ctx)); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Verification email sent", nil)
+}
+
+// VerifyEmail godoc
+// @Summary Verify email
+// @Description Confirm the user's email address using the emailed token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param verifyData body models.VerifyEmailRequest true "Verification token"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /auth/verify-email [post]
+func (c *AuthController) VerifyEmail(ctx *gin.Context) {
+	var req models.VerifyEmailRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	if err := c.authService.VerifyEmail(req.Token, utils.GetAuditActor(ctx)); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Email verified successfully", nil)
+}
+
 // Logout godoc
 // @Summary User logout
-// @Description Logout user and invalidate token (client-side)
+// @Description Revoke the current access token and its refresh token
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Success 200 {object} utils.Response
+// @Failure 401 {object} utils.Response
 // @Failure 500 {object} utils.Response
 // @Router /auth/logout [post]
 func (c *AuthController) Logout(ctx *gin.Context) {
-	// In a stateless JWT system, logout is handled client-side
-	// We could implement token blacklisting if needed
+	claims := utils.GetClaimsFromContext(ctx)
+	if claims == nil {
+		utils.ErrorJSON(ctx, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if err := c.authService.Logout(claims); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
 	utils.SuccessJSON(ctx, http.StatusOK, "Logged out successfully", nil)
 }
 
+// LogoutAll godoc
+// @Summary Sign out everywhere
+// @Description Revoke every access and refresh token issued to the current user
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /auth/logout-all [post]
+func (c *AuthController) LogoutAll(ctx *gin.Context) {
+	userID, err := strconv.ParseUint(utils.GetUserIDFromContext(ctx), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if err := c.authService.SignOutEverywhere(uint(userID), time.Now(), utils.GetAuditActor(ctx)); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Signed out from all devices", nil)
+}
+
+// SignOutUser godoc
+// @Summary Sign a user out everywhere
+// @Description Revoke every token issued to the user before the given time (defaults to now)
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param signOut body models.SignOutRequest false "Cut-off time"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/users/{id}/sign-out [post]
+func (c *AuthController) SignOutUser(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.SignOutRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+			return
+		}
+	}
+
+	before := time.Now()
+	if req.Before != nil {
+		before = *req.Before
+	}
+
+	if err := c.authService.SignOutEverywhere(uint(id), before, utils.GetAuditActor(ctx)); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "User signed out successfully", nil)
+}
+
 // Existing methods remain the same...
 
 // Login godoc
@@ -180,6 +316,7 @@ func (c *AuthController) Logout(ctx *gin.Context) {
 // @Success 200 {object} utils.Response{data=models.LoginResponse}
 // @Failure 400 {object} utils.Response
 // @Failure 401 {object} utils.Response
+// @Failure 429 {object} utils.Response
 // @Failure 500 {object} utils.Response
 // @Router /auth/login [post]
 func (c *AuthController) Login(ctx *gin.Context) {
@@ -189,7 +326,7 @@ func (c *AuthController) Login(ctx *gin.Context) {
 		return
 	}
 
-	response, err := c.authService.Login(req)
+	response, err := c.authService.Login(req, utils.GetSessionClient(ctx, req.DeviceName))
 	if err != nil {
 		utils.HandleError(ctx, err)
 		return
@@ -200,13 +337,16 @@ func (c *AuthController) Login(ctx *gin.Context) {
 
 // Register godoc
 // @Summary Register new user
-// @Description Create a new user account
+// @Description Create a new user account with any role (requires users.manage)
 // @Tags auth
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param user body models.UserRequest true "User registration data"
 // @Success 201 {object} utils.Response{data=models.UserResponse}
 // @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
 // @Failure 409 {object} utils.Response
 // @Failure 500 {object} utils.Response
 // @Router /auth/register [post]
@@ -217,7 +357,7 @@ func (c *AuthController) Register(ctx *gin.Context) {
 		return
 	}
 
-	response, err := c.authService.Register(req)
+	response, err := c.authService.Register(req, utils.GetAuditActor(ctx))
 	if err != nil {
 		utils.HandleError(ctx, err)
 		return
@@ -262,7 +402,7 @@ func (c *AuthController) GetProfile(ctx *gin.Context) {
 
 // UpdateProfile godoc
 // @Summary Update user profile
-// @Description Update current user's profile information
+// @Description Update current user's profile information: email, username and locale (language of emails, en or id)
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -293,7 +433,7 @@ func (c *AuthController) UpdateProfile(ctx *gin.Context) {
 		return
 	}
 
-	response, err := c.authService.UpdateProfile(uint(userIDUint), req)
+	response, err := c.authService.UpdateProfile(uint(userIDUint), req, utils.GetAuditActor(ctx))
 	if err != nil {
 		utils.HandleError(ctx, err)
 		return
@@ -330,7 +470,7 @@ func (c *AuthController) ChangePassword(ctx *gin.Context) {
 
 	var req struct {
 		CurrentPassword string `json:"current_password" binding:"required"`
-		NewPassword     string `json:"new_password" binding:"required,min=6"`
+		NewPassword     string `json:"new_password" binding:"required"`
 	}
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -338,28 +478,165 @@ func (c *AuthController) ChangePassword(ctx *gin.Context) {
 		return
 	}
 
-	err = c.authService.ChangePassword(uint(userIDUint), req.CurrentPassword, req.NewPassword)
+	err = c.authService.ChangePassword(uint(userIDUint), req.CurrentPassword, req.NewPassword, utils.GetAuditActor(ctx))
 	if err != nil {
 		utils.HandleError(ctx, err)
 		return
 	}
 
+	// A token issued for an expired password has done its job; the user logs in again
+	if claims := utils.GetClaimsFromContext(ctx); claims != nil && claims.Restriction == utils.RestrictionPasswordChange {
+		if err := c.authService.Logout(claims); err != nil {
+			utils.HandleError(ctx, err)
+			return
+		}
+		utils.SuccessJSON(ctx, http.StatusOK, "Password changed successfully. Please log in again.", nil)
+		return
+	}
+
 	utils.SuccessJSON(ctx, http.StatusOK, "Password changed successfully", nil)
 }
 
+// GetJWKS godoc
+// @Summary Get token signing keys
+// @Description Get the public keys access tokens are signed with, as a JSON Web Key Set. Pick the key by the token's kid header.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} utils.JWKS
+// @Failure 500 {object} utils.Response
+// @Router /.well-known/jwks.json [get]
+func (c *AuthController) GetJWKS(ctx *gin.Context) {
+	jwks, err := utils.PublicJWKS()
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	// Verifiers cache the set; a new key is published before it is used for signing
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, jwks)
+}
+
+// GetPasswordPolicy godoc
+// @Summary Get password policy
+// @Description Get the rules new passwords must satisfy, so clients can check them as they are typed
+// @Tags auth
+// @Produce json
+// @Success 200 {object} utils.Response{data=models.PasswordPolicyResponse}
+// @Router /auth/password-policy [get]
+func (c *AuthController) GetPasswordPolicy(ctx *gin.Context) {
+	utils.SuccessJSON(ctx, http.StatusOK, "Password policy retrieved successfully", c.authService.GetPasswordPolicy())
+}
+
 // RefreshToken godoc
 // @Summary Refresh JWT token
-// @Description Refresh expired JWT token
+// @Description Exchange a refresh token for a new access token. The refresh token is rotated and must be replaced by the one returned.
 // @Tags auth
 // @Accept json
 // @Produce json
-// @Security BearerAuth
-// @Success 200 {object} utils.Response{data=map[string]interface{}}
+// @Param refresh body models.RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} utils.Response{data=models.LoginResponse}
+// @Failure 400 {object} utils.Response
 // @Failure 401 {object} utils.Response
 // @Failure 500 {object} utils.Response
 // @Router /auth/refresh [post]
 func (c *AuthController) RefreshToken(ctx *gin.Context) {
-	// This would typically use a refresh token
-	// For now, we'll return an error indicating this feature is not implemented
-	utils.ErrorJSON(ctx, http.StatusNotImplemented, "Refresh token endpoint not implemented")
-}
\ No newline at end of file
+	var req models.RefreshTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	response, err := c.authService.RefreshTokens(req.RefreshToken, utils.GetSessionClient(ctx, ""))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Token refreshed successfully", response)
+}
+
+// GetLockedAccounts godoc
+// @Summary Get locked accounts
+// @Description List accounts temporarily locked after too many failed logins
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]models.LockedUserResponse}
+// @Failure 500 {object} utils.Response
+// @Router /admin/users/locked [get]
+func (c *AuthController) GetLockedAccounts(ctx *gin.Context) {
+	users, err := c.throttleService.GetLockedAccounts()
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Locked accounts retrieved successfully", users)
+}
+
+// GetLockoutEvents godoc
+// @Summary Get lockout events
+// @Description List account lockout and unlock events for audit
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param event query string false "Event type (locked, unlocked)" default(locked)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} utils.Response{data=[]models.LoginAttempt}
+// @Failure 400 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/security/lockouts [get]
+func (c *AuthController) GetLockoutEvents(ctx *gin.Context) {
+	event := ctx.DefaultQuery("event", models.LoginEventLocked)
+	if event != models.LoginEventLocked && event != models.LoginEventUnlocked {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid event type")
+		return
+	}
+	page, limit := utils.GetPaginationParams(ctx)
+
+	events, pagination, err := c.throttleService.GetLockoutEvents(event, page, limit)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"events":     events,
+		"pagination": pagination,
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Lockout events retrieved successfully", response)
+}
+
+// UnlockAccount godoc
+// @Summary Unlock account
+// @Description Lift a login lockout and reset the failed login counter
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/users/{id}/unlock [post]
+func (c *AuthController) UnlockAccount(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	adminUsername, _ := ctx.Get("username")
+	if err := c.throttleService.UnlockAccount(uint(id), adminUsername.(string), utils.GetClientIP(ctx)); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Account unlocked successfully", nil)
+}
@@ -39,7 +39,7 @@ func (c *DepartmentController) CreateDepartment(ctx *gin.Context) {
 		return
 	}
 
-	department, err := c.departmentService.CreateDepartment(req)
+	department, err := c.departmentService.CreateDepartment(req, utils.GetAuditActor(ctx))
 	if err != nil {
 		utils.HandleError(ctx, err)
 		return
@@ -158,7 +158,7 @@ func (c *DepartmentController) UpdateDepartment(ctx *gin.Context) {
 		return
 	}
 
-	department, err := c.departmentService.UpdateDepartment(uint(id), req)
+	department, err := c.departmentService.UpdateDepartment(uint(id), req, utils.GetAuditActor(ctx))
 	if err != nil {
 		utils.HandleError(ctx, err)
 		return
@@ -185,7 +185,7 @@ func (c *DepartmentController) DeleteDepartment(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.departmentService.DeleteDepartment(uint(id)); err != nil {
+	if err := c.departmentService.DeleteDepartment(uint(id), utils.GetAuditActor(ctx)); err != nil {
 		utils.HandleError(ctx, err)
 		return
 	}
@@ -215,4 +215,31 @@ func (c *DepartmentController) GetActiveDepartments(ctx *gin.Context) {
 	}
 
 	utils.SuccessJSON(ctx, http.StatusOK, "Active departments retrieved successfully", departmentResponses)
-}
\ No newline at end of file
+}
+
+// GetDepartmentSubtree godoc
+// @Summary Get department subtree
+// @Description Get a department with all of its nested sub-departments
+// @Tags departments
+// @Accept json
+// @Produce json
+// @Param id path int true "Department ID"
+// @Success 200 {object} utils.Response{data=models.DepartmentTreeNode}
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /departments/{id}/subtree [get]
+func (c *DepartmentController) GetDepartmentSubtree(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid department ID")
+		return
+	}
+
+	tree, err := c.departmentService.GetDepartmentSubtree(uint(id))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Department subtree retrieved successfully", tree)
+}
@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"attendance-system/models"
+	"attendance-system/services"
+	"attendance-system/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EmailController struct {
+	outboxService *services.EmailOutboxService
+}
+
+func NewEmailController() *EmailController {
+	return &EmailController{
+		outboxService: services.NewEmailOutboxService(),
+	}
+}
+
+// GetEmails godoc
+// @Summary List outgoing emails
+// @Description List queued and delivered emails with their delivery status, newest first. Email bodies are not returned.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param recipient query string false "Recipient email address"
+// @Param kind query string false "Kind (account_setup, welcome, password_reset, email_verify, attendance_alert, manager_digest)"
+// @Param status query string false "Status (pending, sent, dead)"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} utils.Response{data=[]models.OutboxEmail}
+// @Failure 400 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/emails [get]
+func (c *EmailController) GetEmails(ctx *gin.Context) {
+	var filter models.OutboxEmailFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid query parameters: "+err.Error())
+		return
+	}
+	page, limit := utils.GetPaginationParams(ctx)
+
+	emails, pagination, err := c.outboxService.GetEmails(filter, page, limit)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"emails":     emails,
+		"pagination": pagination,
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Emails retrieved successfully", response)
+}
+
+// GetEmailByID godoc
+// @Summary Get outgoing email
+// @Description Get the delivery status of one email
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Email ID"
+// @Success 200 {object} utils.Response{data=models.OutboxEmail}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/emails/{id} [get]
+func (c *EmailController) GetEmailByID(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid email ID")
+		return
+	}
+
+	email, err := c.outboxService.GetEmailByID(uint(id))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Email retrieved successfully", email)
+}
+
+// ResendEmail godoc
+// @Summary Re-send email
+// @Description Queue an email again with a fresh set of delivery attempts. Links in the email are sent unchanged, so one that has expired or been replaced stays invalid.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Email ID"
+// @Success 200 {object} utils.Response{data=models.OutboxEmail}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/emails/{id}/resend [post]
+func (c *EmailController) ResendEmail(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid email ID")
+		return
+	}
+
+	email, err := c.outboxService.Resend(uint(id), utils.GetAuditActor(ctx))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Email queued for delivery", email)
+}
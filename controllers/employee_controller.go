@@ -40,7 +40,7 @@ func (c *EmployeeController) CreateEmployee(ctx *gin.Context) {
 		return
 	}
 
-	response, err := c.employeeService.CreateEmployee(req)
+	response, err := c.employeeService.CreateEmployee(req, utils.GetAuditActor(ctx))
 	if err != nil {
 		utils.HandleError(ctx, err)
 		return
@@ -85,9 +85,9 @@ func (c *EmployeeController) GetAllEmployees(ctx *gin.Context) {
 	}
 
 	// Convert to response objects
-	var employeeResponses []models.EmployeeResponse
+	var employeeResponses []interface{}
 	for _, employee := range employees {
-		employeeResponses = append(employeeResponses, employee.ToResponse())
+		employeeResponses = append(employeeResponses, visibleEmployee(ctx, &employee))
 	}
 
 	response := map[string]interface{}{
@@ -100,7 +100,7 @@ func (c *EmployeeController) GetAllEmployees(ctx *gin.Context) {
 
 // GetEmployeeByID godoc
 // @Summary Get employee by ID
-// @Description Get employee details by ID
+// @Description Get employee details by ID. Employees get the directory view of colleagues.
 // @Tags employees
 // @Accept json
 // @Produce json
@@ -122,7 +122,7 @@ func (c *EmployeeController) GetEmployeeByID(ctx *gin.Context) {
 		return
 	}
 
-	utils.SuccessJSON(ctx, http.StatusOK, "Employee retrieved successfully", employee.ToResponse())
+	utils.SuccessJSON(ctx, http.StatusOK, "Employee retrieved successfully", visibleEmployee(ctx, employee))
 }
 
 // GetEmployeeWithUser godoc
@@ -133,6 +133,7 @@ func (c *EmployeeController) GetEmployeeByID(ctx *gin.Context) {
 // @Produce json
 // @Param employee_id path string true "Employee ID"
 // @Success 200 {object} utils.Response{data=models.EmployeeWithUserResponse}
+// @Failure 403 {object} utils.Response
 // @Failure 404 {object} utils.Response
 // @Failure 500 {object} utils.Response
 // @Router /employees/{employee_id}/with-user [get]
@@ -178,7 +179,7 @@ func (c *EmployeeController) UpdateEmployee(ctx *gin.Context) {
 		return
 	}
 
-	employee, err := c.employeeService.UpdateEmployee(uint(id), req)
+	employee, err := c.employeeService.UpdateEmployee(uint(id), req, utils.GetAuditActor(ctx))
 	if err != nil {
 		utils.HandleError(ctx, err)
 		return
@@ -205,7 +206,7 @@ func (c *EmployeeController) DeleteEmployee(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.employeeService.DeleteEmployee(uint(id)); err != nil {
+	if err := c.employeeService.DeleteEmployee(uint(id), utils.GetAuditActor(ctx)); err != nil {
 		utils.HandleError(ctx, err)
 		return
 	}
@@ -236,9 +237,9 @@ func (c *EmployeeController) GetEmployeesByDepartment(ctx *gin.Context) {
 		return
 	}
 
-	var employeeResponses []models.EmployeeResponse
+	var employeeResponses []interface{}
 	for _, employee := range employees {
-		employeeResponses = append(employeeResponses, employee.ToResponse())
+		employeeResponses = append(employeeResponses, visibleEmployee(ctx, &employee))
 	}
 
 	utils.SuccessJSON(ctx, http.StatusOK, "Employees retrieved successfully", employeeResponses)
@@ -273,10 +274,83 @@ func (c *EmployeeController) SearchEmployees(ctx *gin.Context) {
 		return
 	}
 
-	var employeeResponses []models.EmployeeResponse
+	var employeeResponses []interface{}
 	for _, employee := range employees {
-		employeeResponses = append(employeeResponses, employee.ToResponse())
+		employeeResponses = append(employeeResponses, visibleEmployee(ctx, &employee))
 	}
 
 	utils.SuccessJSON(ctx, http.StatusOK, "Employees search completed", employeeResponses)
-}
\ No newline at end of file
+}
+
+// GetReportingChain godoc
+// @Summary Get reporting chain
+// @Description Get the employee's managers, starting with the direct manager
+// @Tags employees
+// @Accept json
+// @Produce json
+// @Param id path int true "Employee ID"
+// @Success 200 {object} utils.Response{data=[]models.EmployeeDirectoryResponse}
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /employees/{id}/reporting-chain [get]
+func (c *EmployeeController) GetReportingChain(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid employee ID")
+		return
+	}
+
+	chain, err := c.employeeService.GetReportingChain(uint(id))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	employeeResponses := []models.EmployeeDirectoryResponse{}
+	for _, employee := range chain {
+		employeeResponses = append(employeeResponses, employee.ToDirectoryResponse())
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Reporting chain retrieved successfully", employeeResponses)
+}
+
+// GetDirectReports godoc
+// @Summary Get direct reports
+// @Description Get the employees who report directly to the employee
+// @Tags employees
+// @Accept json
+// @Produce json
+// @Param id path int true "Employee ID"
+// @Success 200 {object} utils.Response{data=[]models.EmployeeDirectoryResponse}
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /employees/{id}/direct-reports [get]
+func (c *EmployeeController) GetDirectReports(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid employee ID")
+		return
+	}
+
+	reports, err := c.employeeService.GetDirectReports(uint(id))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	employeeResponses := []models.EmployeeDirectoryResponse{}
+	for _, employee := range reports {
+		employeeResponses = append(employeeResponses, employee.ToDirectoryResponse())
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Direct reports retrieved successfully", employeeResponses)
+}
+
+// visibleEmployee returns the full profile when the requester may see it,
+// otherwise the reduced directory view of a colleague
+func visibleEmployee(ctx *gin.Context, employee *models.Employee) interface{} {
+	if utils.CanAccessEmployee(ctx, employee.EmployeeID) {
+		return employee.ToResponse()
+	}
+	return employee.ToDirectoryResponse()
+}
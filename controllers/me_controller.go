@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"attendance-system/services"
+	"attendance-system/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MeController struct {
+	attendanceService *services.AttendanceService
+	employeeService   *services.EmployeeService
+}
+
+func NewMeController() *MeController {
+	return &MeController{
+		attendanceService: services.NewAttendanceService(),
+		employeeService:   services.NewEmployeeService(),
+	}
+}
+
+// GetMyProfile godoc
+// @Summary Get my employee profile
+// @Description Get the full employee profile linked to the current user
+// @Tags me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=models.EmployeeResponse}
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /me/profile [get]
+func (c *MeController) GetMyProfile(ctx *gin.Context) {
+	employeeID, ok := currentEmployeeID(ctx)
+	if !ok {
+		return
+	}
+
+	employee, err := c.employeeService.GetEmployeeByEmployeeID(employeeID)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Profile retrieved successfully", employee.ToResponse())
+}
+
+// GetMyTodayStatus godoc
+// @Summary Get my status for today
+// @Description Get whether the current user is clocked in, since when, and when their shift ends
+// @Tags me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=models.TodayStatusResponse}
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /me/today [get]
+func (c *MeController) GetMyTodayStatus(ctx *gin.Context) {
+	employeeID, ok := currentEmployeeID(ctx)
+	if !ok {
+		return
+	}
+
+	status, err := c.attendanceService.GetTodayStatus(employeeID)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Today's status retrieved successfully", status)
+}
+
+// GetMyAttendance godoc
+// @Summary Get my attendance history
+// @Description Get the current user's attendance records with punctuality data
+// @Tags me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Success 200 {object} utils.Response{data=[]models.AttendanceResponse}
+// @Failure 403 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /me/attendance [get]
+func (c *MeController) GetMyAttendance(ctx *gin.Context) {
+	employeeID, ok := currentEmployeeID(ctx)
+	if !ok {
+		return
+	}
+
+	startDate := ctx.Query("start_date")
+	endDate := ctx.Query("end_date")
+
+	attendances, err := c.attendanceService.GetEmployeeAttendanceWithPunctuality(employeeID, startDate, endDate)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Attendance retrieved successfully", attendances)
+}
+
+// GetMyStats godoc
+// @Summary Get my monthly statistics
+// @Description Get the current user's attendance statistics for a month
+// @Tags me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param month query int false "Month (1-12)"
+// @Param year query int false "Year"
+// @Success 200 {object} utils.Response{data=map[string]interface{}}
+// @Failure 403 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /me/stats [get]
+func (c *MeController) GetMyStats(ctx *gin.Context) {
+	employeeID, ok := currentEmployeeID(ctx)
+	if !ok {
+		return
+	}
+
+	month, _ := strconv.Atoi(ctx.Query("month"))
+	year, _ := strconv.Atoi(ctx.Query("year"))
+
+	stats, err := c.attendanceService.GetAttendanceStats(employeeID, month, year)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Attendance statistics retrieved successfully", stats)
+}
+
+// currentEmployeeID resolves the employee linked to the token, writing a 403 when there is none
+func currentEmployeeID(ctx *gin.Context) (string, bool) {
+	employeeID := utils.GetEmployeeIDFromContext(ctx)
+	if employeeID == "" {
+		utils.ErrorJSON(ctx, http.StatusForbidden, "No employee record linked to this account")
+		return "", false
+	}
+	return employeeID, true
+}
@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"attendance-system/models"
+	"attendance-system/services"
+	"attendance-system/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFAController struct {
+	authService *services.AuthService
+	mfaService  *services.MFAService
+}
+
+func NewMFAController() *MFAController {
+	return &MFAController{
+		authService: services.NewAuthService(),
+		mfaService:  services.NewMFAService(),
+	}
+}
+
+// VerifyMFA godoc
+// @Summary Complete login with a second factor
+// @Description Exchange the MFA token returned by login and an authenticator or recovery code for access tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.MFAVerifyRequest true "MFA token and code"
+// @Success 200 {object} utils.Response{data=models.LoginResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 429 {object} utils.Response
+// @Router /auth/mfa/verify [post]
+func (c *MFAController) VerifyMFA(ctx *gin.Context) {
+	var req models.MFAVerifyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	response, err := c.authService.VerifyMFA(req, utils.GetSessionClient(ctx, req.DeviceName))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Login successful", response)
+}
+
+// GetStatus godoc
+// @Summary Get two-factor status
+// @Description Show whether two-factor authentication is enabled or required for the current user
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=models.MFAStatusResponse}
+// @Failure 401 {object} utils.Response
+// @Router /auth/mfa [get]
+func (c *MFAController) GetStatus(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	response, err := c.mfaService.GetStatus(userID)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Two-factor status retrieved successfully", response)
+}
+
+// BeginEnrollment godoc
+// @Summary Start two-factor enrollment
+// @Description Generate a TOTP secret and otpauth URI for an authenticator app
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=models.MFAEnrollmentResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /auth/mfa/enroll [post]
+func (c *MFAController) BeginEnrollment(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	response, err := c.mfaService.BeginEnrollment(userID)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Scan the code with your authenticator app, then confirm with a code", response)
+}
+
+// ConfirmEnrollment godoc
+// @Summary Confirm two-factor enrollment
+// @Description Enable two-factor authentication with a code from the authenticator app and return recovery codes
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.MFACodeRequest true "Authenticator code"
+// @Success 200 {object} utils.Response{data=models.MFARecoveryCodesResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /auth/mfa/confirm [post]
+func (c *MFAController) ConfirmEnrollment(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req models.MFACodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	response, err := c.mfaService.ConfirmEnrollment(userID, req.Code)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	// Tokens issued before enrollment may be restricted, so the user signs in again with 2FA
+	if claims := utils.GetClaimsFromContext(ctx); claims != nil && claims.Restriction != "" {
+		if err := c.authService.Logout(claims); err != nil {
+			utils.HandleError(ctx, err)
+			return
+		}
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Two-factor authentication enabled. Store these recovery codes somewhere safe.", response)
+}
+
+// DisableMFA godoc
+// @Summary Disable two-factor authentication
+// @Description Turn off two-factor authentication after confirming the password and a current code
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.MFADisableRequest true "Password and code"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /auth/mfa/disable [post]
+func (c *MFAController) DisableMFA(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req models.MFADisableRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	if err := c.mfaService.Disable(userID, req); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Two-factor authentication disabled", nil)
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replace all recovery codes after confirming a current code
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.MFACodeRequest true "Authenticator or recovery code"
+// @Success 200 {object} utils.Response{data=models.MFARecoveryCodesResponse}
+// @Failure 400 {object} utils.Response
+// @Router /auth/mfa/recovery-codes [post]
+func (c *MFAController) RegenerateRecoveryCodes(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req models.MFACodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	response, err := c.mfaService.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Recovery codes regenerated", response)
+}
+
+// currentUserID reads the authenticated user's ID, writing an error response if it is missing
+func currentUserID(ctx *gin.Context) (uint, bool) {
+	userID, err := strconv.ParseUint(utils.GetUserIDFromContext(ctx), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusUnauthorized, "User not authenticated")
+		return 0, false
+	}
+	return uint(userID), true
+}
@@ -0,0 +1,310 @@
+package controllers
+
+import (
+	"attendance-system/models"
+	"attendance-system/services"
+	"attendance-system/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationController struct {
+	notificationService *services.NotificationService
+	digestService       *services.DigestService
+}
+
+func NewNotificationController() *NotificationController {
+	return &NotificationController{
+		notificationService: services.NewNotificationService(),
+		digestService:       services.NewDigestService(),
+	}
+}
+
+// GetNotifications godoc
+// @Summary List my notifications
+// @Description List the current user's inbox, unread first and then newest first, with the unread count. Managers can narrow it to items about their team with scope=team, or to their own with scope=personal.
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Param unread_only query bool false "Only unread notifications"
+// @Param category query string false "Category (alert, reminder, approval, correction, announcement)"
+// @Param scope query string false "Scope (personal, team)"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} utils.Response{data=[]models.Notification}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /notifications [get]
+func (c *NotificationController) GetNotifications(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var filter models.NotificationFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid query parameters: "+err.Error())
+		return
+	}
+	page, limit := utils.GetPaginationParams(ctx)
+
+	notifications, pagination, err := c.notificationService.GetNotifications(userID, utils.GetEmployeeIDFromContext(ctx), filter, page, limit)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	unread, err := c.notificationService.GetUnreadCount(userID)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"notifications": notifications,
+		"unread_count":  unread,
+		"pagination":    pagination,
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Notifications retrieved successfully", response)
+}
+
+// GetUnreadCount godoc
+// @Summary Count my unread notifications
+// @Description Get the number of unread notifications in the current user's inbox, for the notification bell
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /notifications/unread-count [get]
+func (c *NotificationController) GetUnreadCount(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	unread, err := c.notificationService.GetUnreadCount(userID)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Unread count retrieved successfully", map[string]interface{}{"unread_count": unread})
+}
+
+// MarkRead godoc
+// @Summary Mark notification read
+// @Description Mark one of the current user's notifications as read
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Notification ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /notifications/{id}/read [put]
+func (c *NotificationController) MarkRead(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid notification ID")
+		return
+	}
+
+	if err := c.notificationService.MarkRead(userID, uint(id)); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Notification marked as read", nil)
+}
+
+// MarkAllRead godoc
+// @Summary Mark all notifications read
+// @Description Mark every unread notification in the current user's inbox as read
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /notifications/read-all [post]
+func (c *NotificationController) MarkAllRead(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	updated, err := c.notificationService.MarkAllRead(userID)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Notifications marked as read", map[string]interface{}{"updated": updated})
+}
+
+// SendTeamAnnouncement godoc
+// @Summary Announce to my team
+// @Description Put an announcement in the inbox of the current user's direct reports
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param announcement body models.TeamAnnouncementRequest true "Announcement"
+// @Success 201 {object} utils.Response{data=models.AnnouncementResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /notifications/team-announcements [post]
+func (c *NotificationController) SendTeamAnnouncement(ctx *gin.Context) {
+	employeeID, ok := currentEmployeeID(ctx)
+	if !ok {
+		return
+	}
+
+	var req models.TeamAnnouncementRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	response, err := c.notificationService.SendTeamAnnouncement(employeeID, req, utils.GetAuditActor(ctx))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusCreated, "Announcement sent successfully", response)
+}
+
+// SendAnnouncement godoc
+// @Summary Send announcement
+// @Description Put an announcement in the inbox of every active user, or of the users in a department and optionally its sub-departments. Announcements are delivered even to users who turned in-app notifications off.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param announcement body models.AnnouncementRequest true "Announcement"
+// @Success 201 {object} utils.Response{data=models.AnnouncementResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/announcements [post]
+func (c *NotificationController) SendAnnouncement(ctx *gin.Context) {
+	var req models.AnnouncementRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	response, err := c.notificationService.SendAnnouncement(req, utils.GetAuditActor(ctx))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusCreated, "Announcement sent successfully", response)
+}
+
+// GetPreferences godoc
+// @Summary Get my notification preferences
+// @Description Get the channels the current user is notified through and their quiet hours
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=models.NotificationPreference}
+// @Failure 401 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /notifications/preferences [get]
+func (c *NotificationController) GetPreferences(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	preference, err := c.notificationService.GetPreferences(userID)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Notification preferences retrieved successfully", preference)
+}
+
+// UpdatePreferences godoc
+// @Summary Update my notification preferences
+// @Description Turn email and in-app notifications on or off and set quiet hours (HH:MM, server time), during which emails are held back. Empty quiet hours turn them off. Managers can opt in to daily and weekly digest emails sent at digest_time.
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param preferences body models.NotificationPreferenceRequest true "Preferences to change"
+// @Success 200 {object} utils.Response{data=models.NotificationPreference}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /notifications/preferences [put]
+func (c *NotificationController) UpdatePreferences(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req models.NotificationPreferenceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	preference, err := c.notificationService.UpdatePreferences(userID, req)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Notification preferences updated successfully", preference)
+}
+
+// PreviewDigest godoc
+// @Summary Preview my manager digest
+// @Description Build the daily or weekly digest the current manager would get now, without sending it
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Param frequency query string false "daily or weekly" default(daily)
+// @Success 200 {object} utils.Response{data=services.ManagerDigest}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /notifications/digest/preview [get]
+func (c *NotificationController) PreviewDigest(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	frequency := ctx.DefaultQuery("frequency", "daily")
+	if frequency != "daily" && frequency != "weekly" {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "frequency must be daily or weekly")
+		return
+	}
+
+	digest, err := c.digestService.Preview(userID, frequency == "weekly")
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Digest preview generated successfully", digest)
+}
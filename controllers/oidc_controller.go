@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"attendance-system/models"
+	"attendance-system/services"
+	"attendance-system/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OIDCController struct {
+	authService *services.AuthService
+	oidcService *services.OIDCService
+}
+
+func NewOIDCController() *OIDCController {
+	return &OIDCController{
+		authService: services.NewAuthService(),
+		oidcService: services.NewOIDCService(),
+	}
+}
+
+// BeginLogin godoc
+// @Summary Start single sign-on
+// @Description Get the identity provider URL for an OpenID Connect login (authorization code with PKCE)
+// @Tags auth
+// @Produce json
+// @Success 200 {object} utils.Response{data=models.OIDCLoginResponse}
+// @Failure 404 {object} utils.Response
+// @Failure 502 {object} utils.Response
+// @Router /auth/oidc/login [get]
+func (c *OIDCController) BeginLogin(ctx *gin.Context) {
+	response, err := c.oidcService.BeginLogin()
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Redirect to the identity provider to sign in", response)
+}
+
+// Callback godoc
+// @Summary Finish single sign-on
+// @Description Exchange the code and state the identity provider returned for access tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.OIDCCallbackRequest true "Authorization code and state"
+// @Success 200 {object} utils.Response{data=models.LoginResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 502 {object} utils.Response
+// @Router /auth/oidc/callback [post]
+func (c *OIDCController) Callback(ctx *gin.Context) {
+	var req models.OIDCCallbackRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	response, err := c.authService.LoginWithOIDC(req, utils.GetSessionClient(ctx, req.DeviceName))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Login successful", response)
+}
@@ -3,13 +3,14 @@ package controllers
 import (
 	"attendance-system/services"
 	"attendance-system/utils"
-	"encoding/csv"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/xuri/excelize/v2"
 )
 
 type ReportController struct {
@@ -24,13 +25,16 @@ func NewReportController() *ReportController {
 
 // GenerateAttendanceReport godoc
 // @Summary Generate attendance report
-// @Description Generate detailed attendance report for a period
+// @Description Generate detailed attendance report for a period, one page at a time
 // @Tags reports
 // @Accept json
 // @Produce json
 // @Param start_date query string true "Start date (YYYY-MM-DD)"
 // @Param end_date query string true "End date (YYYY-MM-DD)"
 // @Param department_id query int false "Filter by department ID"
+// @Param include_sub_departments query bool false "Include all sub-departments of the department"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
 // @Success 200 {object} utils.Response{data=[]services.AttendanceReport}
 // @Failure 400 {object} utils.Response
 // @Failure 500 {object} utils.Response
@@ -45,13 +49,20 @@ func (c *ReportController) GenerateAttendanceReport(ctx *gin.Context) {
 		return
 	}
 
-	reports, err := c.reportService.GenerateAttendanceReport(startDate, endDate, uint(departmentID))
+	page, limit := utils.GetPaginationParams(ctx)
+
+	reports, pagination, err := c.reportService.GenerateAttendanceReport(startDate, endDate, uint(departmentID), includeSubDepartments(ctx), page, limit)
 	if err != nil {
 		utils.HandleError(ctx, err)
 		return
 	}
 
-	utils.SuccessJSON(ctx, http.StatusOK, "Attendance report generated successfully", reports)
+	response := map[string]interface{}{
+		"reports":    reports,
+		"pagination": pagination,
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Attendance report generated successfully", response)
 }
 
 // GenerateSummaryReport godoc
@@ -63,6 +74,7 @@ func (c *ReportController) GenerateAttendanceReport(ctx *gin.Context) {
 // @Param start_date query string true "Start date (YYYY-MM-DD)"
 // @Param end_date query string true "End date (YYYY-MM-DD)"
 // @Param department_id query int false "Filter by department ID"
+// @Param include_sub_departments query bool false "Include all sub-departments of the department"
 // @Success 200 {object} utils.Response{data=services.SummaryReport}
 // @Failure 400 {object} utils.Response
 // @Failure 500 {object} utils.Response
@@ -77,7 +89,7 @@ func (c *ReportController) GenerateSummaryReport(ctx *gin.Context) {
 		return
 	}
 
-	summary, err := c.reportService.GenerateSummaryReport(startDate, endDate, uint(departmentID))
+	summary, err := c.reportService.GenerateSummaryReport(startDate, endDate, uint(departmentID), includeSubDepartments(ctx))
 	if err != nil {
 		utils.HandleError(ctx, err)
 		return
@@ -93,6 +105,7 @@ func (c *ReportController) GenerateSummaryReport(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param department_id path int true "Department ID"
+// @Param include_sub_departments query bool false "Include all sub-departments of the department"
 // @Param month query int false "Month (1-12)"
 // @Param year query int false "Year"
 // @Success 200 {object} utils.Response{data=map[string]interface{}}
@@ -108,7 +121,7 @@ func (c *ReportController) GenerateDepartmentReport(ctx *gin.Context) {
 	month, _ := strconv.Atoi(ctx.Query("month"))
 	year, _ := strconv.Atoi(ctx.Query("year"))
 
-	report, err := c.reportService.GenerateDepartmentReport(uint(departmentID), month, year)
+	report, err := c.reportService.GenerateDepartmentReport(uint(departmentID), month, year, includeSubDepartments(ctx))
 	if err != nil {
 		utils.HandleError(ctx, err)
 		return
@@ -117,16 +130,54 @@ func (c *ReportController) GenerateDepartmentReport(ctx *gin.Context) {
 	utils.SuccessJSON(ctx, http.StatusOK, "Department report generated successfully", report)
 }
 
+// GenerateHierarchyReport godoc
+// @Summary Generate hierarchy summary report
+// @Description Summarise attendance for a division, rolled up through every sub-department
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param department_id path int true "Department ID"
+// @Param start_date query string true "Start date (YYYY-MM-DD)"
+// @Param end_date query string true "End date (YYYY-MM-DD)"
+// @Success 200 {object} utils.Response{data=services.HierarchySummaryReport}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /reports/hierarchy/{department_id} [get]
+func (c *ReportController) GenerateHierarchyReport(ctx *gin.Context) {
+	departmentID, err := strconv.ParseUint(ctx.Param("department_id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid department ID")
+		return
+	}
+
+	startDate := ctx.Query("start_date")
+	endDate := ctx.Query("end_date")
+	if startDate == "" || endDate == "" {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Start date and end date are required")
+		return
+	}
+
+	report, err := c.reportService.GenerateHierarchySummary(uint(departmentID), startDate, endDate)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Hierarchy report generated successfully", report)
+}
+
 // ExportAttendanceReport godoc
 // @Summary Export attendance report
-// @Description Export attendance report in Excel or CSV format
+// @Description Export attendance report in Excel, CSV or PDF format
 // @Tags reports
 // @Accept json
-// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,text/csv,application/pdf
 // @Param start_date query string true "Start date (YYYY-MM-DD)"
 // @Param end_date query string true "End date (YYYY-MM-DD)"
 // @Param department_id query int false "Filter by department ID"
-// @Param format query string false "Export format (excel or csv)" default(excel)
+// @Param include_sub_departments query bool false "Include all sub-departments of the department"
+// @Param format query string false "Export format (excel, csv or pdf)" default(excel)
 // @Success 200 {file} file "Exported file"
 // @Failure 400 {object} utils.Response
 // @Failure 500 {object} utils.Response
@@ -142,37 +193,35 @@ func (c *ReportController) ExportAttendanceReport(ctx *gin.Context) {
 		return
 	}
 
-	reports, err := c.reportService.GenerateAttendanceReport(startDate, endDate, uint(departmentID))
+	info, err := c.exportInfo(ctx, uint(departmentID), fmt.Sprintf("%s to %s", startDate, endDate))
 	if err != nil {
 		utils.HandleError(ctx, err)
 		return
 	}
 
-	filename := fmt.Sprintf("attendance-report-%s-to-%s", startDate, endDate)
-
-	switch format {
-	case "csv":
-		err = c.exportAttendanceCSV(ctx, reports, filename)
-	default:
-		err = c.exportAttendanceExcel(ctx, reports, filename)
+	// Rows are read and written a batch at a time, so the export has no size limit
+	rows := func(write func([]services.AttendanceReport) error) error {
+		return c.reportService.StreamAttendanceReport(startDate, endDate, uint(departmentID), includeSubDepartments(ctx), write)
 	}
 
-	if err != nil {
-		utils.HandleError(ctx, err)
-		return
-	}
+	filename := fmt.Sprintf("attendance-report-%s-to-%s", startDate, endDate)
+
+	writeReportFile(ctx, format, filename, func(w io.Writer) error {
+		return services.WriteAttendanceReport(w, format, rows, info)
+	})
 }
 
 // ExportSummaryReport godoc
 // @Summary Export summary report
-// @Description Export summary report in Excel or CSV format
+// @Description Export summary report in Excel, CSV or PDF format
 // @Tags reports
 // @Accept json
-// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,text/csv,application/pdf
 // @Param start_date query string true "Start date (YYYY-MM-DD)"
 // @Param end_date query string true "End date (YYYY-MM-DD)"
 // @Param department_id query int false "Filter by department ID"
-// @Param format query string false "Export format (excel or csv)" default(excel)
+// @Param include_sub_departments query bool false "Include all sub-departments of the department"
+// @Param format query string false "Export format (excel, csv or pdf)" default(excel)
 // @Success 200 {file} file "Exported file"
 // @Failure 400 {object} utils.Response
 // @Failure 500 {object} utils.Response
@@ -188,37 +237,36 @@ func (c *ReportController) ExportSummaryReport(ctx *gin.Context) {
 		return
 	}
 
-	summary, err := c.reportService.GenerateSummaryReport(startDate, endDate, uint(departmentID))
+	summary, err := c.reportService.GenerateSummaryReport(startDate, endDate, uint(departmentID), includeSubDepartments(ctx))
 	if err != nil {
 		utils.HandleError(ctx, err)
 		return
 	}
 
-	filename := fmt.Sprintf("summary-report-%s-to-%s", startDate, endDate)
-
-	switch format {
-	case "csv":
-		err = c.exportSummaryCSV(ctx, summary, filename)
-	default:
-		err = c.exportSummaryExcel(ctx, summary, filename)
-	}
-
+	info, err := c.exportInfo(ctx, uint(departmentID), summary.Period)
 	if err != nil {
 		utils.HandleError(ctx, err)
 		return
 	}
+
+	filename := fmt.Sprintf("summary-report-%s-to-%s", startDate, endDate)
+
+	writeReportFile(ctx, format, filename, func(w io.Writer) error {
+		return services.WriteSummaryReport(w, format, summary, info)
+	})
 }
 
 // ExportDepartmentReport godoc
 // @Summary Export department report
-// @Description Export department report in Excel or CSV format
+// @Description Export department report in Excel, CSV or PDF format
 // @Tags reports
 // @Accept json
-// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,text/csv,application/pdf
 // @Param department_id path int true "Department ID"
+// @Param include_sub_departments query bool false "Include all sub-departments of the department"
 // @Param month query int false "Month (1-12)"
 // @Param year query int false "Year"
-// @Param format query string false "Export format (excel or csv)" default(excel)
+// @Param format query string false "Export format (excel, csv or pdf)" default(excel)
 // @Success 200 {file} file "Exported file"
 // @Failure 500 {object} utils.Response
 // @Router /reports/export/department/{department_id} [get]
@@ -233,457 +281,67 @@ func (c *ReportController) ExportDepartmentReport(ctx *gin.Context) {
 	year, _ := strconv.Atoi(ctx.Query("year"))
 	format := ctx.DefaultQuery("format", "excel")
 
-	report, err := c.reportService.GenerateDepartmentReport(uint(departmentID), month, year)
+	report, err := c.reportService.GenerateDepartmentReport(uint(departmentID), month, year, includeSubDepartments(ctx))
 	if err != nil {
 		utils.HandleError(ctx, err)
 		return
 	}
 
-	filename := fmt.Sprintf("department-report-%d-%d-%d", departmentID, month, year)
-
-	switch format {
-	case "csv":
-		err = c.exportDepartmentCSV(ctx, report, filename)
-	default:
-		err = c.exportDepartmentExcel(ctx, report, filename)
-	}
-
+	period, _ := report["period"].(string)
+	info, err := c.exportInfo(ctx, uint(departmentID), period)
 	if err != nil {
 		utils.HandleError(ctx, err)
 		return
 	}
-}
-
-// Excel Export Methods
-func (c *ReportController) exportAttendanceExcel(ctx *gin.Context, reports []services.AttendanceReport, filename string) error {
-	f := excelize.NewFile()
-	defer f.Close()
-
-	// Create a new sheet
-	sheetName := "Attendance Report"
-	index, err := f.NewSheet(sheetName)
-	if err != nil {
-		return err
-	}
-
-	// Set headers
-	headers := []string{"Employee ID", "Employee Name", "Department", "Date", "Clock In", "Clock Out", "Work Hours", "Status", "Late Minutes", "Early Minutes"}
-	for i, header := range headers {
-		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
-		f.SetCellValue(sheetName, cell, header)
-	}
 
-	// Set data
-	for i, report := range reports {
-		row := i + 2
-		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), report.EmployeeID)
-		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), report.EmployeeName)
-		f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), report.Department)
-		f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), report.Date)
-		f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), report.ClockIn.Format("2006-01-02 15:04:05"))
-		
-		if !report.ClockOut.IsZero() {
-			f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), report.ClockOut.Format("2006-01-02 15:04:05"))
-		} else {
-			f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), "Not Clocked Out")
-		}
-		
-		f.SetCellValue(sheetName, fmt.Sprintf("G%d", row), fmt.Sprintf("%.2f", report.WorkHours))
-		f.SetCellValue(sheetName, fmt.Sprintf("H%d", row), report.Status)
-		f.SetCellValue(sheetName, fmt.Sprintf("I%d", row), report.LateMinutes)
-		f.SetCellValue(sheetName, fmt.Sprintf("J%d", row), report.EarlyMinutes)
-	}
+	filename := fmt.Sprintf("department-report-%d-%d-%d", departmentID, month, year)
 
-	// Set active sheet and apply styling
-	f.SetActiveSheet(index)
-	
-	// Set column widths
-	f.SetColWidth(sheetName, "A", "A", 15)
-	f.SetColWidth(sheetName, "B", "B", 25)
-	f.SetColWidth(sheetName, "C", "C", 20)
-	f.SetColWidth(sheetName, "D", "F", 18)
-	f.SetColWidth(sheetName, "G", "J", 15)
-
-	// Style headers
-	style, _ := f.NewStyle(&excelize.Style{
-		Font: &excelize.Font{Bold: true, Color: "FFFFFF"},
-		Fill: excelize.Fill{Type: "pattern", Color: []string{"2c5aa0"}, Pattern: 1},
+	writeReportFile(ctx, format, filename, func(w io.Writer) error {
+		return services.WriteDepartmentReport(w, format, report, info)
 	})
-	f.SetCellStyle(sheetName, "A1", "J1", style)
-
-	// Set response headers
-	ctx.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.xlsx", filename))
-	ctx.Header("Content-Transfer-Encoding", "binary")
-	ctx.Header("Expires", "0")
-
-	return f.Write(ctx.Writer)
 }
 
-func (c *ReportController) exportSummaryExcel(ctx *gin.Context, summary *services.SummaryReport, filename string) error {
-	f := excelize.NewFile()
-	defer f.Close()
-
-	sheetName := "Summary Report"
-	index, err := f.NewSheet(sheetName)
-	if err != nil {
-		return err
-	}
-
-	// Set summary data
-	f.SetCellValue(sheetName, "A1", "Summary Report")
-	f.SetCellValue(sheetName, "A2", "Period")
-	f.SetCellValue(sheetName, "B2", summary.Period)
-	
-	f.SetCellValue(sheetName, "A3", "Total Employees")
-	f.SetCellValue(sheetName, "B3", summary.TotalEmployees)
-	
-	f.SetCellValue(sheetName, "A4", "Total Present")
-	f.SetCellValue(sheetName, "B4", summary.TotalPresent)
-	
-	f.SetCellValue(sheetName, "A5", "Total Late")
-	f.SetCellValue(sheetName, "B5", summary.TotalLate)
-	
-	f.SetCellValue(sheetName, "A6", "Total Absent")
-	f.SetCellValue(sheetName, "B6", summary.TotalAbsent)
-	
-	f.SetCellValue(sheetName, "A7", "Total Work Hours")
-	f.SetCellValue(sheetName, "B7", summary.TotalWorkHours)
-	
-	f.SetCellValue(sheetName, "A8", "Average Work Hours")
-	f.SetCellValue(sheetName, "B8", summary.AverageWorkHours)
-
-	// Apply styling
-	f.SetColWidth(sheetName, "A", "A", 20)
-	f.SetColWidth(sheetName, "B", "B", 25)
-
-	// Style headers
-	headerStyle, _ := f.NewStyle(&excelize.Style{
-		Font: &excelize.Font{Bold: true, Size: 16},
-	})
-	f.SetCellStyle(sheetName, "A1", "B1", headerStyle)
-
-	labelStyle, _ := f.NewStyle(&excelize.Style{
-		Font: &excelize.Font{Bold: true},
-	})
-	f.SetCellStyle(sheetName, "A2", "A8", labelStyle)
-
-	f.SetActiveSheet(index)
-
-	ctx.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.xlsx", filename))
-	ctx.Header("Content-Transfer-Encoding", "binary")
-	ctx.Header("Expires", "0")
-
-	return f.Write(ctx.Writer)
+// includeSubDepartments reports whether the department filter should cover the whole subtree
+func includeSubDepartments(ctx *gin.Context) bool {
+	include, _ := strconv.ParseBool(ctx.Query("include_sub_departments"))
+	return include
 }
 
-func (c *ReportController) exportDepartmentExcel(ctx *gin.Context, report map[string]interface{}, filename string) error {
-	f := excelize.NewFile()
-	defer f.Close()
-
-	sheetName := "Department Report"
-	index, err := f.NewSheet(sheetName)
+// exportInfo describes an export for the printed formats
+func (c *ReportController) exportInfo(ctx *gin.Context, departmentID uint, period string) (services.ReportExportInfo, error) {
+	department, err := c.reportService.DepartmentLabel(departmentID, includeSubDepartments(ctx))
 	if err != nil {
-		return err
-	}
-
-	// Safely extract department report data
-	deptReportInterface, exists := report["department_report"]
-	if !exists {
-		return fmt.Errorf("department_report not found in response")
-	}
-
-	// Convert to the actual struct type
-	deptReportMap, ok := deptReportInterface.(map[string]interface{})
-	if !ok {
-		// If it's already a struct, we need to handle it differently
-		// For now, let's use a type assertion with proper error handling
-		return fmt.Errorf("unexpected type for department_report: %T", deptReportInterface)
-	}
-
-	departmentName, _ := deptReportMap["department_name"].(string)
-	totalEmployees, _ := deptReportMap["total_employees"].(float64) // JSON numbers are float64
-	period := report["period"].(string)
-
-	// Set department header
-	f.SetCellValue(sheetName, "A1", "Department Report")
-	f.SetCellValue(sheetName, "A2", "Department")
-	f.SetCellValue(sheetName, "B2", departmentName)
-	f.SetCellValue(sheetName, "A3", "Period")
-	f.SetCellValue(sheetName, "B3", period)
-	f.SetCellValue(sheetName, "A4", "Total Employees")
-	f.SetCellValue(sheetName, "B4", int(totalEmployees))
-
-	// Set summary data
-	summaryInterface, exists := deptReportMap["summary"]
-	if !exists {
-		return fmt.Errorf("summary not found in department_report")
-	}
-
-	summary, ok := summaryInterface.(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("unexpected type for summary: %T", summaryInterface)
-	}
-
-	f.SetCellValue(sheetName, "A6", "Summary Statistics")
-	f.SetCellValue(sheetName, "A7", "Total Present")
-	f.SetCellValue(sheetName, "B7", summary["total_present"])
-	f.SetCellValue(sheetName, "A8", "Total Late")
-	f.SetCellValue(sheetName, "B8", summary["total_late"])
-	f.SetCellValue(sheetName, "A9", "Total Absent")
-	f.SetCellValue(sheetName, "B9", summary["total_absent"])
-	f.SetCellValue(sheetName, "A10", "Attendance Rate")
-	f.SetCellValue(sheetName, "B10", fmt.Sprintf("%.2f%%", summary["attendance_rate"]))
-	f.SetCellValue(sheetName, "A11", "Average Work Hours")
-	f.SetCellValue(sheetName, "B11", fmt.Sprintf("%.2f", summary["average_work_hours"]))
-
-	// Employee statistics
-	f.SetCellValue(sheetName, "A13", "Employee Statistics")
-	employeeStatsInterface, exists := deptReportMap["employee_stats"]
-	if !exists {
-		return fmt.Errorf("employee_stats not found in department_report")
-	}
-
-	employeeStats, ok := employeeStatsInterface.([]interface{})
-	if !ok {
-		return fmt.Errorf("unexpected type for employee_stats: %T", employeeStatsInterface)
-	}
-
-	headers := []string{"Employee ID", "Employee Name", "Present Days", "Late Days", "Absent Days", "Avg Work Hours"}
-	for i, header := range headers {
-		cell, _ := excelize.CoordinatesToCellName(i+1, 14)
-		f.SetCellValue(sheetName, cell, header)
-	}
-
-	for i, empStatInterface := range employeeStats {
-		empStat, ok := empStatInterface.(map[string]interface{})
-		if !ok {
-			continue // Skip invalid entries
-		}
-
-		statsInterface, exists := empStat["stats"]
-		if !exists {
-			continue
-		}
-
-		stats, ok := statsInterface.(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		row := i + 15
-		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), empStat["employee_id"])
-		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), empStat["employee_name"])
-		f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), stats["total_present"])
-		f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), stats["total_late"])
-		f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), stats["total_absent"])
-		f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), stats["avg_work_hours"])
-	}
-
-	// Apply styling
-	f.SetColWidth(sheetName, "A", "A", 20)
-	f.SetColWidth(sheetName, "B", "B", 25)
-	f.SetColWidth(sheetName, "C", "F", 15)
-
-	headerStyle, _ := f.NewStyle(&excelize.Style{
-		Font: &excelize.Font{Bold: true, Size: 16},
-	})
-	f.SetCellStyle(sheetName, "A1", "A1", headerStyle)
-
-	sectionStyle, _ := f.NewStyle(&excelize.Style{
-		Font: &excelize.Font{Bold: true, Size: 14},
-	})
-	f.SetCellStyle(sheetName, "A6", "A6", sectionStyle)
-	f.SetCellStyle(sheetName, "A13", "A13", sectionStyle)
-
-	tableHeaderStyle, _ := f.NewStyle(&excelize.Style{
-		Font: &excelize.Font{Bold: true, Color: "FFFFFF"},
-		Fill: excelize.Fill{Type: "pattern", Color: []string{"2c5aa0"}, Pattern: 1},
-	})
-	f.SetCellStyle(sheetName, "A14", "F14", tableHeaderStyle)
-
-	f.SetActiveSheet(index)
-
-	ctx.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.xlsx", filename))
-	ctx.Header("Content-Transfer-Encoding", "binary")
-	ctx.Header("Expires", "0")
-
-	return f.Write(ctx.Writer)
+		return services.ReportExportInfo{}, err
+	}
+	return services.ReportExportInfo{
+		Department:  department,
+		Period:      period,
+		GeneratedBy: utils.GetAuditActor(ctx).Name,
+		GeneratedAt: time.Now(),
+	}, nil
 }
 
-// CSV Export Methods
-func (c *ReportController) exportAttendanceCSV(ctx *gin.Context, reports []services.AttendanceReport, filename string) error {
-	ctx.Header("Content-Type", "text/csv")
-	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", filename))
+// writeReportFile sends a report export as a file download. Exports are written as
+// they are generated, so once part of the file has gone out an error can only be
+// logged and the download cut short.
+func writeReportFile(ctx *gin.Context, format, filename string, write func(w io.Writer) error) {
+	contentType, extension := services.ReportFileType(format)
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s", filename, extension))
 	ctx.Header("Content-Transfer-Encoding", "binary")
 	ctx.Header("Expires", "0")
 
-	writer := csv.NewWriter(ctx.Writer)
-	defer writer.Flush()
-
-	// Write headers
-	headers := []string{"Employee ID", "Employee Name", "Department", "Date", "Clock In", "Clock Out", "Work Hours", "Status", "Late Minutes", "Early Minutes"}
-	if err := writer.Write(headers); err != nil {
-		return err
-	}
-
-	// Write data
-	for _, report := range reports {
-		clockOut := ""
-		if !report.ClockOut.IsZero() {
-			clockOut = report.ClockOut.Format("2006-01-02 15:04:05")
-		} else {
-			clockOut = "Not Clocked Out"
-		}
-
-		record := []string{
-			report.EmployeeID,
-			report.EmployeeName,
-			report.Department,
-			report.Date,
-			report.ClockIn.Format("2006-01-02 15:04:05"),
-			clockOut,
-			fmt.Sprintf("%.2f", report.WorkHours),
-			report.Status,
-			strconv.Itoa(report.LateMinutes),
-			strconv.Itoa(report.EarlyMinutes),
-		}
-
-		if err := writer.Write(record); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-func (c *ReportController) exportSummaryCSV(ctx *gin.Context, summary *services.SummaryReport, filename string) error {
-	ctx.Header("Content-Type", "text/csv")
-	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", filename))
-	ctx.Header("Content-Transfer-Encoding", "binary")
-	ctx.Header("Expires", "0")
-
-	writer := csv.NewWriter(ctx.Writer)
-	defer writer.Flush()
-
-	// Write headers
-	if err := writer.Write([]string{"Metric", "Value"}); err != nil {
-		return err
+	err := write(ctx.Writer)
+	if err == nil {
+		return
 	}
-
-	// Write data
-	records := [][]string{
-		{"Period", summary.Period},
-		{"Total Employees", strconv.FormatInt(summary.TotalEmployees, 10)},
-		{"Total Present", strconv.FormatInt(summary.TotalPresent, 10)},
-		{"Total Late", strconv.FormatInt(summary.TotalLate, 10)},
-		{"Total Absent", strconv.FormatInt(summary.TotalAbsent, 10)},
-		{"Total Work Hours", summary.TotalWorkHours},
-		{"Average Work Hours", summary.AverageWorkHours},
+	if ctx.Writer.Written() {
+		log.Printf("Report export %s failed part way: %v", filename, err)
+		ctx.Abort()
+		return
 	}
-
-	for _, record := range records {
-		if err := writer.Write(record); err != nil {
-			return err
-		}
+	for _, header := range []string{"Content-Type", "Content-Disposition", "Content-Transfer-Encoding", "Expires"} {
+		ctx.Writer.Header().Del(header)
 	}
-
-	return nil
+	utils.HandleError(ctx, err)
 }
-
-func (c *ReportController) exportDepartmentCSV(ctx *gin.Context, report map[string]interface{}, filename string) error {
-	ctx.Header("Content-Type", "text/csv")
-	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", filename))
-	ctx.Header("Content-Transfer-Encoding", "binary")
-	ctx.Header("Expires", "0")
-
-	writer := csv.NewWriter(ctx.Writer)
-	defer writer.Flush()
-
-	// Safely extract department report data
-	deptReportInterface, exists := report["department_report"]
-	if !exists {
-		return fmt.Errorf("department_report not found in response")
-	}
-
-	deptReport, ok := deptReportInterface.(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("unexpected type for department_report: %T", deptReportInterface)
-	}
-
-	summaryInterface, exists := deptReport["summary"]
-	if !exists {
-		return fmt.Errorf("summary not found in department_report")
-	}
-
-	summary, ok := summaryInterface.(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("unexpected type for summary: %T", summaryInterface)
-	}
-
-	employeeStatsInterface, exists := deptReport["employee_stats"]
-	if !exists {
-		return fmt.Errorf("employee_stats not found in department_report")
-	}
-
-	employeeStats, ok := employeeStatsInterface.([]interface{})
-	if !ok {
-		return fmt.Errorf("unexpected type for employee_stats: %T", employeeStatsInterface)
-	}
-
-	// Write department header
-	if err := writer.Write([]string{"Department Report"}); err != nil {
-		return err
-	}
-	writer.Write([]string{"Department", deptReport["department_name"].(string)})
-	writer.Write([]string{"Period", report["period"].(string)})
-	writer.Write([]string{"Total Employees", fmt.Sprintf("%.0f", deptReport["total_employees"])})
-	writer.Write([]string{}) // Empty line
-
-	// Write summary
-	writer.Write([]string{"Summary Statistics"})
-	writer.Write([]string{"Total Present", fmt.Sprintf("%.0f", summary["total_present"])})
-	writer.Write([]string{"Total Late", fmt.Sprintf("%.0f", summary["total_late"])})
-	writer.Write([]string{"Total Absent", fmt.Sprintf("%.0f", summary["total_absent"])})
-	writer.Write([]string{"Attendance Rate", fmt.Sprintf("%.2f%%", summary["attendance_rate"])})
-	writer.Write([]string{"Average Work Hours", fmt.Sprintf("%.2f", summary["average_work_hours"])})
-	writer.Write([]string{}) // Empty line
-
-	// Write employee statistics header
-	writer.Write([]string{"Employee Statistics"})
-	writer.Write([]string{"Employee ID", "Employee Name", "Present Days", "Late Days", "Absent Days", "Avg Work Hours"})
-
-	// Write employee data
-	for _, empStatInterface := range employeeStats {
-		empStat, ok := empStatInterface.(map[string]interface{})
-		if !ok {
-			continue // Skip invalid entries
-		}
-
-		statsInterface, exists := empStat["stats"]
-		if !exists {
-			continue
-		}
-
-		stats, ok := statsInterface.(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		record := []string{
-			empStat["employee_id"].(string),
-			empStat["employee_name"].(string),
-			fmt.Sprintf("%.0f", stats["total_present"]),
-			fmt.Sprintf("%.0f", stats["total_late"]),
-			fmt.Sprintf("%.0f", stats["total_absent"]),
-			fmt.Sprintf("%.2f", stats["avg_work_hours"]),
-		}
-		if err := writer.Write(record); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
\ No newline at end of file
@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"attendance-system/models"
+	"attendance-system/services"
+	"attendance-system/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReportScheduleController struct {
+	scheduleService *services.ReportScheduleService
+}
+
+func NewReportScheduleController() *ReportScheduleController {
+	return &ReportScheduleController{
+		scheduleService: services.NewReportScheduleService(),
+	}
+}
+
+// GetReportSchedules godoc
+// @Summary List report schedules
+// @Description List the saved report schedules with their next and last runs
+// @Tags reports
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]models.ReportScheduleResponse}
+// @Failure 500 {object} utils.Response
+// @Router /reports/schedules [get]
+func (c *ReportScheduleController) GetReportSchedules(ctx *gin.Context) {
+	schedules, err := c.scheduleService.GetSchedules()
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	response := make([]models.ReportScheduleResponse, len(schedules))
+	for i := range schedules {
+		response[i] = schedules[i].ToResponse()
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Report schedules retrieved successfully", response)
+}
+
+// GetReportScheduleByID godoc
+// @Summary Get report schedule
+// @Description Get one report schedule
+// @Tags reports
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Report schedule ID"
+// @Success 200 {object} utils.Response{data=models.ReportScheduleResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /reports/schedules/{id} [get]
+func (c *ReportScheduleController) GetReportScheduleByID(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid report schedule ID")
+		return
+	}
+
+	schedule, err := c.scheduleService.GetScheduleByID(uint(id))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Report schedule retrieved successfully", schedule.ToResponse())
+}
+
+// CreateReportSchedule godoc
+// @Summary Create report schedule
+// @Description Save a report to be generated on a cron schedule (server time) and emailed to the recipients as an attachment. The period is relative to each run: yesterday, last_7_days, last_30_days, last_week, last_month or month_to_date.
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param schedule body models.ReportScheduleRequest true "Report schedule"
+// @Success 201 {object} utils.Response{data=models.ReportScheduleResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /reports/schedules [post]
+func (c *ReportScheduleController) CreateReportSchedule(ctx *gin.Context) {
+	var req models.ReportScheduleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	schedule, err := c.scheduleService.CreateSchedule(req, utils.GetAuditActor(ctx))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusCreated, "Report schedule created successfully", schedule.ToResponse())
+}
+
+// UpdateReportSchedule godoc
+// @Summary Update report schedule
+// @Description Replace a report schedule's settings. The next run is worked out again from the new recurrence.
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Report schedule ID"
+// @Param schedule body models.ReportScheduleRequest true "Report schedule"
+// @Success 200 {object} utils.Response{data=models.ReportScheduleResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /reports/schedules/{id} [put]
+func (c *ReportScheduleController) UpdateReportSchedule(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid report schedule ID")
+		return
+	}
+
+	var req models.ReportScheduleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	schedule, err := c.scheduleService.UpdateSchedule(uint(id), req, utils.GetAuditActor(ctx))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Report schedule updated successfully", schedule.ToResponse())
+}
+
+// DeleteReportSchedule godoc
+// @Summary Delete report schedule
+// @Description Delete a report schedule with its run history
+// @Tags reports
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Report schedule ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /reports/schedules/{id} [delete]
+func (c *ReportScheduleController) DeleteReportSchedule(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid report schedule ID")
+		return
+	}
+
+	if err := c.scheduleService.DeleteSchedule(uint(id), utils.GetAuditActor(ctx)); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Report schedule deleted successfully", nil)
+}
+
+// RunReportSchedule godoc
+// @Summary Run report schedule now
+// @Description Generate the report for its period as of now and email it to the recipients right away, even when the schedule is inactive. The next scheduled run is unchanged.
+// @Tags reports
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Report schedule ID"
+// @Success 200 {object} utils.Response{data=models.ReportScheduleRun}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /reports/schedules/{id}/run [post]
+func (c *ReportScheduleController) RunReportSchedule(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid report schedule ID")
+		return
+	}
+
+	run, err := c.scheduleService.RunNow(uint(id), utils.GetAuditActor(ctx))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	message := "Report generated and queued for the recipients"
+	if run.Status != models.ReportRunSucceeded {
+		message = "Report run failed: " + run.Error
+	}
+	utils.SuccessJSON(ctx, http.StatusOK, message, run)
+}
+
+// GetReportScheduleRuns godoc
+// @Summary List report schedule runs
+// @Description List the schedule's run history with the period covered, the file sent and any error, newest first
+// @Tags reports
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Report schedule ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} utils.Response{data=[]models.ReportScheduleRun}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /reports/schedules/{id}/runs [get]
+func (c *ReportScheduleController) GetReportScheduleRuns(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid report schedule ID")
+		return
+	}
+	page, limit := utils.GetPaginationParams(ctx)
+
+	runs, pagination, err := c.scheduleService.GetRuns(uint(id), page, limit)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"runs":       runs,
+		"pagination": pagination,
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Report schedule runs retrieved successfully", response)
+}
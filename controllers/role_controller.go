@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"attendance-system/models"
+	"attendance-system/services"
+	"attendance-system/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoleController struct {
+	roleService *services.RoleService
+}
+
+func NewRoleController() *RoleController {
+	return &RoleController{
+		roleService: services.NewRoleService(),
+	}
+}
+
+// GetAllRoles godoc
+// @Summary Get all roles
+// @Description Get every role with the permissions it grants
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Success 200 {object} utils.Response{data=[]models.RoleResponse}
+// @Failure 500 {object} utils.Response
+// @Router /admin/roles [get]
+func (c *RoleController) GetAllRoles(ctx *gin.Context) {
+	roles, err := c.roleService.GetAllRoles()
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	roleResponses := []models.RoleResponse{}
+	for _, role := range roles {
+		roleResponses = append(roleResponses, role.ToResponse())
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Roles retrieved successfully", roleResponses)
+}
+
+// GetRoleByID godoc
+// @Summary Get role by ID
+// @Description Get a role with the permissions it grants
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param id path int true "Role ID"
+// @Success 200 {object} utils.Response{data=models.RoleResponse}
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/roles/{id} [get]
+func (c *RoleController) GetRoleByID(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid role ID")
+		return
+	}
+
+	role, err := c.roleService.GetRoleByID(uint(id))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Role retrieved successfully", role.ToResponse())
+}
+
+// CreateRole godoc
+// @Summary Create a custom role
+// @Description Create a role granting the given permission codes
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param role body models.RoleRequest true "Role data"
+// @Success 201 {object} utils.Response{data=models.RoleResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/roles [post]
+func (c *RoleController) CreateRole(ctx *gin.Context) {
+	var req models.RoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	role, err := c.roleService.CreateRole(req, utils.GetAuditActor(ctx))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusCreated, "Role created successfully", role.ToResponse())
+}
+
+// UpdateRole godoc
+// @Summary Update role
+// @Description Update a role's name, description and permission set
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param id path int true "Role ID"
+// @Param role body models.RoleRequest true "Role data"
+// @Success 200 {object} utils.Response{data=models.RoleResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/roles/{id} [put]
+func (c *RoleController) UpdateRole(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid role ID")
+		return
+	}
+
+	var req models.RoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	role, err := c.roleService.UpdateRole(uint(id), req, utils.GetAuditActor(ctx))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Role updated successfully", role.ToResponse())
+}
+
+// DeleteRole godoc
+// @Summary Delete role
+// @Description Delete a custom role that is not assigned to any user
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param id path int true "Role ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/roles/{id} [delete]
+func (c *RoleController) DeleteRole(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid role ID")
+		return
+	}
+
+	if err := c.roleService.DeleteRole(uint(id), utils.GetAuditActor(ctx)); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Role deleted successfully", nil)
+}
+
+// GetAllPermissions godoc
+// @Summary Get all permissions
+// @Description Get every permission code that can be granted to a role
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Success 200 {object} utils.Response{data=[]models.Permission}
+// @Failure 500 {object} utils.Response
+// @Router /admin/permissions [get]
+func (c *RoleController) GetAllPermissions(ctx *gin.Context) {
+	permissions, err := c.roleService.GetAllPermissions()
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Permissions retrieved successfully", permissions)
+}
+
+// AssignUserRole godoc
+// @Summary Assign role to user
+// @Description Change the role of a user account
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param role body models.AssignRoleRequest true "Role assignment"
+// @Success 200 {object} utils.Response{data=models.UserResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/users/{id}/role [put]
+func (c *RoleController) AssignUserRole(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.AssignRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	user, err := c.roleService.AssignRole(uint(id), req.Role, utils.GetAuditActor(ctx))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Role assigned successfully", user.ToResponse())
+}
@@ -0,0 +1,248 @@
+package controllers
+
+import (
+	"attendance-system/models"
+	"attendance-system/services"
+	"attendance-system/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ServiceAccountController struct {
+	serviceAccountService *services.ServiceAccountService
+}
+
+func NewServiceAccountController() *ServiceAccountController {
+	return &ServiceAccountController{
+		serviceAccountService: services.NewServiceAccountService(),
+	}
+}
+
+// GetAllServiceAccounts godoc
+// @Summary Get all service accounts
+// @Description Get every service account with its API keys
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]models.ServiceAccountResponse}
+// @Failure 500 {object} utils.Response
+// @Router /admin/service-accounts [get]
+func (c *ServiceAccountController) GetAllServiceAccounts(ctx *gin.Context) {
+	accounts, err := c.serviceAccountService.GetAll()
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	responses := []models.ServiceAccountResponse{}
+	for _, account := range accounts {
+		responses = append(responses, account.ToResponse())
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Service accounts retrieved successfully", responses)
+}
+
+// GetScopes godoc
+// @Summary Get API key scopes
+// @Description List the scopes an API key can be granted and the permissions each one carries
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]models.APIScopeResponse}
+// @Router /admin/service-accounts/scopes [get]
+func (c *ServiceAccountController) GetScopes(ctx *gin.Context) {
+	utils.SuccessJSON(ctx, http.StatusOK, "Scopes retrieved successfully", models.AvailableScopes())
+}
+
+// GetServiceAccountByID godoc
+// @Summary Get service account by ID
+// @Description Get a service account with its API keys
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Service account ID"
+// @Success 200 {object} utils.Response{data=models.ServiceAccountResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /admin/service-accounts/{id} [get]
+func (c *ServiceAccountController) GetServiceAccountByID(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid service account ID")
+		return
+	}
+
+	account, err := c.serviceAccountService.GetByID(uint(id))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Service account retrieved successfully", account.ToResponse())
+}
+
+// CreateServiceAccount godoc
+// @Summary Create service account
+// @Description Create a service account for a system integration
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param account body models.ServiceAccountRequest true "Service account data"
+// @Success 201 {object} utils.Response{data=models.ServiceAccountResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /admin/service-accounts [post]
+func (c *ServiceAccountController) CreateServiceAccount(ctx *gin.Context) {
+	var req models.ServiceAccountRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	var createdBy *uint
+	if userID, err := strconv.ParseUint(utils.GetUserIDFromContext(ctx), 10, 32); err == nil {
+		id := uint(userID)
+		createdBy = &id
+	}
+
+	account, err := c.serviceAccountService.Create(req, createdBy)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusCreated, "Service account created successfully", account.ToResponse())
+}
+
+// UpdateServiceAccount godoc
+// @Summary Update service account
+// @Description Rename, describe or disable a service account. Disabling stops all of its keys.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Service account ID"
+// @Param account body models.ServiceAccountRequest true "Service account data"
+// @Success 200 {object} utils.Response{data=models.ServiceAccountResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /admin/service-accounts/{id} [put]
+func (c *ServiceAccountController) UpdateServiceAccount(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid service account ID")
+		return
+	}
+
+	var req models.ServiceAccountRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	account, err := c.serviceAccountService.Update(uint(id), req)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Service account updated successfully", account.ToResponse())
+}
+
+// DeleteServiceAccount godoc
+// @Summary Delete service account
+// @Description Delete a service account and all of its API keys
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Service account ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /admin/service-accounts/{id} [delete]
+func (c *ServiceAccountController) DeleteServiceAccount(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid service account ID")
+		return
+	}
+
+	if err := c.serviceAccountService.Delete(uint(id)); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Service account deleted successfully", nil)
+}
+
+// CreateAPIKey godoc
+// @Summary Create API key
+// @Description Issue a scoped API key for a service account. The key is only returned once.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Service account ID"
+// @Param key body models.APIKeyRequest true "API key data"
+// @Success 201 {object} utils.Response{data=models.CreatedAPIKeyResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /admin/service-accounts/{id}/keys [post]
+func (c *ServiceAccountController) CreateAPIKey(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid service account ID")
+		return
+	}
+
+	var req models.APIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	key, err := c.serviceAccountService.CreateKey(uint(id), req)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusCreated, "API key created. Store it now, it will not be shown again.", key)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke API key
+// @Description Stop an API key from authenticating
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Service account ID"
+// @Param key_id path int true "API key ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /admin/service-accounts/{id}/keys/{key_id} [delete]
+func (c *ServiceAccountController) RevokeAPIKey(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid service account ID")
+		return
+	}
+
+	keyID, err := strconv.ParseUint(ctx.Param("key_id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+
+	if err := c.serviceAccountService.RevokeKey(uint(id), uint(keyID)); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "API key revoked successfully", nil)
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"attendance-system/services"
+	"attendance-system/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionController struct {
+	authService *services.AuthService
+}
+
+func NewSessionController() *SessionController {
+	return &SessionController{
+		authService: services.NewAuthService(),
+	}
+}
+
+// GetMySessions godoc
+// @Summary List my sessions
+// @Description List the devices the current user is signed in on
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]models.SessionResponse}
+// @Failure 401 {object} utils.Response
+// @Router /auth/sessions [get]
+func (c *SessionController) GetMySessions(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	sessions, err := c.authService.GetSessions(userID, currentSessionID(ctx))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Sessions retrieved successfully", sessions)
+}
+
+// RevokeMySession godoc
+// @Summary Revoke one of my sessions
+// @Description Sign the current user out on one device
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Session ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /auth/sessions/{id} [delete]
+func (c *SessionController) RevokeMySession(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	sessionID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	if err := c.authService.RevokeSession(userID, uint(sessionID), utils.GetAuditActor(ctx)); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Session revoked successfully", nil)
+}
+
+// GetUserSessions godoc
+// @Summary List a user's sessions
+// @Description List the devices a user is signed in on
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} utils.Response{data=[]models.SessionResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /admin/users/{id}/sessions [get]
+func (c *SessionController) GetUserSessions(ctx *gin.Context) {
+	userID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	sessions, err := c.authService.GetSessions(uint(userID), currentSessionID(ctx))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Sessions retrieved successfully", sessions)
+}
+
+// RevokeUserSession godoc
+// @Summary Revoke a user's session
+// @Description Sign a user out on one device, e.g. a lost phone
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param session_id path int true "Session ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /admin/users/{id}/sessions/{session_id} [delete]
+func (c *SessionController) RevokeUserSession(ctx *gin.Context) {
+	userID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	sessionID, err := strconv.ParseUint(ctx.Param("session_id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	if err := c.authService.RevokeSession(uint(userID), uint(sessionID), utils.GetAuditActor(ctx)); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Session revoked successfully", nil)
+}
+
+// currentSessionID returns the session the request's access token belongs to
+func currentSessionID(ctx *gin.Context) string {
+	if claims := utils.GetClaimsFromContext(ctx); claims != nil {
+		return claims.SessionID
+	}
+	return ""
+}
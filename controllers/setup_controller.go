@@ -3,6 +3,7 @@ package controllers
 import (
 	"attendance-system/models"
 	"attendance-system/services"
+	"attendance-system/utils"
 	"net/http"
 	"fmt"
 
@@ -27,7 +28,7 @@ func (sc *SetupController) CompleteAccountSetup(c *gin.Context) {
 		return
 	}
 
-	if err := sc.employeeService.CompleteAccountSetup(req.Token, req.NewPassword); err != nil {
+	if err := sc.employeeService.CompleteAccountSetup(req.Token, req.NewPassword, utils.GetAuditActor(c)); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -44,7 +45,7 @@ func (sc *SetupController) CompleteAccountSetup(c *gin.Context) {
 func (sc *SetupController) GetSetupToken(c *gin.Context) {
 	employeeID := c.Param("employeeId")
 	
-	token, err := sc.employeeService.GetEmployeeSetupToken(employeeID)
+	token, err := sc.employeeService.GetEmployeeSetupToken(employeeID, utils.GetAuditActor(c))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
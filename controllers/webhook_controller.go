@@ -0,0 +1,287 @@
+package controllers
+
+import (
+	"attendance-system/models"
+	"attendance-system/services"
+	"attendance-system/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookController struct {
+	webhookService *services.WebhookService
+}
+
+func NewWebhookController() *WebhookController {
+	return &WebhookController{
+		webhookService: services.NewWebhookService(),
+	}
+}
+
+// GetWebhookEvents godoc
+// @Summary Get webhook event types
+// @Description List the event types a webhook can subscribe to
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]string}
+// @Router /admin/webhooks/events [get]
+func (c *WebhookController) GetWebhookEvents(ctx *gin.Context) {
+	utils.SuccessJSON(ctx, http.StatusOK, "Event types retrieved successfully", models.WebhookEventTypes)
+}
+
+// GetWebhooks godoc
+// @Summary List webhooks
+// @Description List the webhook subscriptions. Secrets are not returned.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]models.WebhookResponse}
+// @Failure 500 {object} utils.Response
+// @Router /admin/webhooks [get]
+func (c *WebhookController) GetWebhooks(ctx *gin.Context) {
+	webhooks, err := c.webhookService.GetWebhooks()
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	response := make([]models.WebhookResponse, len(webhooks))
+	for i := range webhooks {
+		response[i] = webhooks[i].ToResponse()
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Webhooks retrieved successfully", response)
+}
+
+// GetWebhookByID godoc
+// @Summary Get webhook
+// @Description Get one webhook subscription
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} utils.Response{data=models.WebhookResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/webhooks/{id} [get]
+func (c *WebhookController) GetWebhookByID(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	webhook, err := c.webhookService.GetWebhookByID(uint(id))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Webhook retrieved successfully", webhook.ToResponse())
+}
+
+// CreateWebhook godoc
+// @Summary Create webhook
+// @Description Subscribe a URL to events. The signing secret is generated unless one is given, and is only returned here.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param webhook body models.WebhookRequest true "Webhook"
+// @Success 201 {object} utils.Response{data=models.WebhookSecretResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/webhooks [post]
+func (c *WebhookController) CreateWebhook(ctx *gin.Context) {
+	var req models.WebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	webhook, err := c.webhookService.CreateWebhook(req, utils.GetAuditActor(ctx))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusCreated, "Webhook created successfully. Store the secret now, it will not be shown again.", webhook)
+}
+
+// UpdateWebhook godoc
+// @Summary Update webhook
+// @Description Replace a webhook's settings. The signing secret is kept unless a new one is given.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Param webhook body models.WebhookRequest true "Webhook"
+// @Success 200 {object} utils.Response{data=models.WebhookResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/webhooks/{id} [put]
+func (c *WebhookController) UpdateWebhook(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	var req models.WebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	webhook, err := c.webhookService.UpdateWebhook(uint(id), req, utils.GetAuditActor(ctx))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Webhook updated successfully", webhook.ToResponse())
+}
+
+// DeleteWebhook godoc
+// @Summary Delete webhook
+// @Description Delete a webhook with its delivery log
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/webhooks/{id} [delete]
+func (c *WebhookController) DeleteWebhook(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	if err := c.webhookService.DeleteWebhook(uint(id), utils.GetAuditActor(ctx)); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Webhook deleted successfully", nil)
+}
+
+// PingWebhook godoc
+// @Summary Ping webhook
+// @Description Send a signed webhook.ping event to the webhook right away, even when it is disabled, and return the delivery with the receiver's response status. Failed pings are not retried.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} utils.Response{data=models.WebhookDelivery}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/webhooks/{id}/ping [post]
+func (c *WebhookController) PingWebhook(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	delivery, err := c.webhookService.Ping(uint(id))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	message := "Ping delivered successfully"
+	if delivery.Status != models.WebhookDeliveryDelivered {
+		message = "Ping failed: " + delivery.LastError
+	}
+	utils.SuccessJSON(ctx, http.StatusOK, message, delivery)
+}
+
+// GetWebhookDeliveries godoc
+// @Summary List webhook deliveries
+// @Description List the webhook's delivery log with payloads and the outcome of the last attempt, newest first
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Param event_type query string false "Event type"
+// @Param status query string false "Status (pending, delivered, dead)"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} utils.Response{data=[]models.WebhookDelivery}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/webhooks/{id}/deliveries [get]
+func (c *WebhookController) GetWebhookDeliveries(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	var filter models.WebhookDeliveryFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid query parameters: "+err.Error())
+		return
+	}
+	page, limit := utils.GetPaginationParams(ctx)
+
+	deliveries, pagination, err := c.webhookService.GetDeliveries(uint(id), filter, page, limit)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"deliveries": deliveries,
+		"pagination": pagination,
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Webhook deliveries retrieved successfully", response)
+}
+
+// RedeliverWebhook godoc
+// @Summary Redeliver webhook event
+// @Description Queue a delivery again with a fresh set of attempts. The payload and event ID are unchanged.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Param delivery_id path int true "Delivery ID"
+// @Success 200 {object} utils.Response{data=models.WebhookDelivery}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+func (c *WebhookController) RedeliverWebhook(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	deliveryID, err := strconv.ParseUint(ctx.Param("delivery_id"), 10, 32)
+	if err != nil {
+		utils.ErrorJSON(ctx, http.StatusBadRequest, "Invalid delivery ID")
+		return
+	}
+
+	delivery, err := c.webhookService.Redeliver(uint(id), uint(deliveryID), utils.GetAuditActor(ctx))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	utils.SuccessJSON(ctx, http.StatusOK, "Webhook delivery queued", delivery)
+}
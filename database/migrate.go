@@ -10,9 +10,67 @@ import (
 	"gorm.io/gorm"
 )
 
+// columnUpgrade adds a column, with any index or key that goes with it, to a table that
+// was created before the column was part of its CREATE TABLE in migration.sql
+type columnUpgrade struct {
+	table  string
+	column string // Checked before altering, the first column when the ALTER adds several
+	alter  string
+}
+
+// columnUpgrades bring tables created by an earlier migration.sql up to date. CREATE TABLE
+// IF NOT EXISTS leaves existing tables alone, so columns added to it since are added
+// here. New databases get them from CREATE TABLE and skip every upgrade.
+var columnUpgrades = []columnUpgrade{
+	{"departments", "parent_id", "ALTER TABLE departments ADD COLUMN parent_id INT NULL COMMENT 'Parent division or department' AFTER id, " +
+		"ADD FOREIGN KEY (parent_id) REFERENCES departments(id) ON DELETE RESTRICT ON UPDATE CASCADE, " +
+		"ADD INDEX idx_department_parent (parent_id)"},
+	{"employees", "manager_id", "ALTER TABLE employees ADD COLUMN manager_id VARCHAR(50) NULL COMMENT 'Employee ID of the direct manager' AFTER department_id, " +
+		"ADD FOREIGN KEY (manager_id) REFERENCES employees(employee_id) ON DELETE SET NULL ON UPDATE CASCADE, " +
+		"ADD INDEX idx_employee_manager (manager_id)"},
+	{"users", "tokens_valid_after", "ALTER TABLE users ADD COLUMN tokens_valid_after TIMESTAMP NULL"},
+	{"users", "email_verified_at", "ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP NULL"},
+	{"users", "failed_login_attempts", "ALTER TABLE users ADD COLUMN failed_login_attempts INT DEFAULT 0, " +
+		"ADD COLUMN last_failed_login_at TIMESTAMP NULL, ADD COLUMN locked_until TIMESTAMP NULL"},
+	{"users", "mfa_enabled", "ALTER TABLE users ADD COLUMN mfa_enabled BOOLEAN DEFAULT FALSE, ADD COLUMN mfa_secret VARCHAR(255) NULL, " +
+		"ADD COLUMN mfa_enabled_at TIMESTAMP NULL, ADD COLUMN mfa_last_used_step BIGINT NULL"},
+	{"roles", "require_mfa", "ALTER TABLE roles ADD COLUMN require_mfa BOOLEAN DEFAULT FALSE"},
+	{"users", "oidc_subject", "ALTER TABLE users ADD COLUMN oidc_subject VARCHAR(255) NULL UNIQUE"},
+	{"users", "password_changed_at", "ALTER TABLE users ADD COLUMN password_changed_at TIMESTAMP NULL"},
+	{"users", "locale", "ALTER TABLE users ADD COLUMN locale VARCHAR(10) NULL"},
+	{"notifications", "category", "ALTER TABLE notifications ADD COLUMN category VARCHAR(20) NOT NULL DEFAULT 'alert' AFTER type, " +
+		"ADD COLUMN employee_id VARCHAR(50) NULL AFTER category, ADD COLUMN sender_name VARCHAR(150) NULL AFTER message"},
+	{"notification_preferences", "digest_daily", "ALTER TABLE notification_preferences ADD COLUMN digest_daily BOOLEAN NOT NULL DEFAULT FALSE, " +
+		"ADD COLUMN digest_weekly BOOLEAN NOT NULL DEFAULT FALSE, ADD COLUMN digest_time VARCHAR(5) NULL, " +
+		"ADD COLUMN daily_digest_sent_on DATE NULL, ADD COLUMN weekly_digest_sent_on DATE NULL"},
+	{"email_outbox", "attachment_name", "ALTER TABLE email_outbox ADD COLUMN attachment_name VARCHAR(255) NULL AFTER text_body, " +
+		"ADD COLUMN attachment_type VARCHAR(100) NULL AFTER attachment_name, ADD COLUMN attachment LONGTEXT NULL AFTER attachment_type"},
+}
+
+// upgradeColumns applies the column upgrades a database is missing. It runs before
+// migration.sql, whose later statements may use the columns.
+func upgradeColumns(db *gorm.DB) error {
+	migrator := db.Migrator()
+	for _, upgrade := range columnUpgrades {
+		if !migrator.HasTable(upgrade.table) || migrator.HasColumn(upgrade.table, upgrade.column) {
+			continue
+		}
+
+		log.Printf("Adding %s.%s...", upgrade.table, upgrade.column)
+		if err := db.Exec(upgrade.alter).Error; err != nil {
+			return fmt.Errorf("failed to add %s.%s: %v", upgrade.table, upgrade.column, err)
+		}
+	}
+	return nil
+}
+
 func RunMigrations(db *gorm.DB) error {
 	log.Println("🔄 Running database migrations...")
 
+	if err := upgradeColumns(db); err != nil {
+		return err
+	}
+
 	// Read migration file
 	migrationSQL, err := os.ReadFile("database/migration.sql")
 	if err != nil {
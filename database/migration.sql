@@ -9,6 +9,7 @@ USE attendance_system;
 -- Departments table
 CREATE TABLE IF NOT EXISTS departments (
     id INT AUTO_INCREMENT PRIMARY KEY,
+    parent_id INT NULL COMMENT 'Parent division or department',
     name VARCHAR(255) NOT NULL UNIQUE,
     description TEXT,
     max_clock_in TIME NOT NULL,
@@ -19,6 +20,8 @@ CREATE TABLE IF NOT EXISTS departments (
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     
+    FOREIGN KEY (parent_id) REFERENCES departments(id) ON DELETE RESTRICT ON UPDATE CASCADE,
+    INDEX idx_department_parent (parent_id),
     INDEX idx_department_status (status),
     INDEX idx_department_created (created_at)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -28,6 +31,7 @@ CREATE TABLE IF NOT EXISTS employees (
     id INT AUTO_INCREMENT PRIMARY KEY,
     employee_id VARCHAR(50) NOT NULL UNIQUE,
     department_id INT NOT NULL,
+    manager_id VARCHAR(50) NULL COMMENT 'Employee ID of the direct manager',
     name VARCHAR(255) NOT NULL,
     phone VARCHAR(20),
     address TEXT NOT NULL,
@@ -38,7 +42,9 @@ CREATE TABLE IF NOT EXISTS employees (
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     
     FOREIGN KEY (department_id) REFERENCES departments(id) ON DELETE RESTRICT ON UPDATE CASCADE,
+    FOREIGN KEY (manager_id) REFERENCES employees(employee_id) ON DELETE SET NULL ON UPDATE CASCADE,
     INDEX idx_employee_department (department_id),
+    INDEX idx_employee_manager (manager_id),
     INDEX idx_employee_status (status),
     INDEX idx_employee_id (employee_id),
     INDEX idx_employee_created (created_at)
@@ -97,9 +103,19 @@ CREATE TABLE IF NOT EXISTS users (
     role VARCHAR(50) DEFAULT 'employee',
     employee_id VARCHAR(50) NULL,
     is_active BOOLEAN DEFAULT FALSE, -- False until account setup complete
-    setup_token VARCHAR(64) NULL UNIQUE, -- Secure token for account setup
-    token_expires TIMESTAMP NULL, -- Token expiration time
     last_login TIMESTAMP NULL,
+    email_verified_at TIMESTAMP NULL, -- Set once the user proves they own the email address
+    failed_login_attempts INT DEFAULT 0, -- Consecutive failed logins, reset on success
+    last_failed_login_at TIMESTAMP NULL,
+    locked_until TIMESTAMP NULL, -- Logins are refused until this time
+    tokens_valid_after TIMESTAMP NULL, -- Access tokens issued at or before this time are rejected
+    password_changed_at TIMESTAMP NULL, -- Compared against PASSWORD_MAX_AGE_DAYS
+    locale VARCHAR(10) NULL, -- Language of the user's emails, EMAIL_DEFAULT_LOCALE when empty
+    mfa_enabled BOOLEAN DEFAULT FALSE,
+    mfa_secret VARCHAR(255) NULL, -- TOTP secret, encrypted with ENCRYPTION_KEY
+    mfa_enabled_at TIMESTAMP NULL,
+    mfa_last_used_step BIGINT NULL, -- Last accepted TOTP time step, stops codes being replayed
+    oidc_subject VARCHAR(255) NULL UNIQUE, -- Identity provider account linked through single sign-on
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     
@@ -108,11 +124,422 @@ CREATE TABLE IF NOT EXISTS users (
     INDEX idx_user_email (email),
     INDEX idx_user_role (role),
     INDEX idx_user_active (is_active),
-    INDEX idx_user_employee (employee_id),
-    INDEX idx_user_setup_token (setup_token),
-    INDEX idx_user_token_expires (token_expires)
+    INDEX idx_user_employee (employee_id)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
+-- One-time tokens for account setup, password reset and email verification
+CREATE TABLE IF NOT EXISTS one_time_tokens (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    purpose VARCHAR(30) NOT NULL, -- account-setup, password-reset, email-verify
+    token_hash VARCHAR(64) NOT NULL UNIQUE, -- SHA-256 of the token, the token itself is never stored
+    single_use BOOLEAN DEFAULT TRUE,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL,
+    request_ip VARCHAR(45) NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_one_time_token_user_purpose (user_id, purpose),
+    INDEX idx_one_time_token_expires (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Refresh tokens, rotated on every use and grouped into families per login
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    family_id VARCHAR(64) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE, -- SHA-256 of the token, the token itself is never stored
+    role VARCHAR(50) NOT NULL, -- Role at issue time
+    expires_at TIMESTAMP NOT NULL,
+    rotated_at TIMESTAMP NULL,
+    revoked_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_refresh_token_user (user_id),
+    INDEX idx_refresh_token_family (family_id),
+    INDEX idx_refresh_token_expires (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Signed-in devices, one per refresh token family
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    session_id VARCHAR(64) NOT NULL UNIQUE, -- Refresh token family ID, the sid claim of access tokens
+    user_id INT NOT NULL,
+    device_name VARCHAR(100) NULL,
+    user_agent VARCHAR(255) NULL,
+    ip_address VARCHAR(45) NULL,
+    last_seen_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_user_session_user (user_id, revoked_at),
+    INDEX idx_user_session_last_seen (last_seen_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Hashes of previous passwords, so recent ones can't be reused
+CREATE TABLE IF NOT EXISTS password_history (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_password_history_user (user_id, created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Who changed what: administrative changes with the values before and after
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    actor_type VARCHAR(20) NOT NULL, -- user, service_account, anonymous
+    actor_id VARCHAR(50) NULL,
+    actor_name VARCHAR(150) NULL,
+    action VARCHAR(50) NOT NULL, -- e.g. employee.delete, user.role_assign
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id VARCHAR(100) NULL,
+    changes JSON NULL, -- {"field": {"old": ..., "new": ...}}
+    ip_address VARCHAR(45) NULL,
+    request_id VARCHAR(64) NULL, -- X-Request-ID of the request that made the change
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX idx_audit_log_actor (actor_id),
+    INDEX idx_audit_log_action (action),
+    INDEX idx_audit_log_entity (entity_type, entity_id),
+    INDEX idx_audit_log_request (request_id),
+    INDEX idx_audit_log_created (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Emails waiting for delivery and their delivery status
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    kind VARCHAR(50) NOT NULL, -- account_setup, welcome, password_reset, email_verify, attendance_alert, manager_digest, scheduled_report
+    recipient VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    html_body MEDIUMTEXT NULL, -- Encrypted with ENCRYPTION_KEY, bodies carry one-time links
+    text_body MEDIUMTEXT NULL, -- Encrypted with ENCRYPTION_KEY
+    attachment_name VARCHAR(255) NULL, -- Optional file sent with the email, such as a scheduled report
+    attachment_type VARCHAR(100) NULL,
+    attachment LONGTEXT NULL, -- Encrypted with ENCRYPTION_KEY
+    status VARCHAR(20) NOT NULL, -- pending, sent, dead
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error VARCHAR(500) NULL,
+    sent_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    INDEX idx_outbox_email_due (status, next_attempt_at),
+    INDEX idx_outbox_email_recipient (recipient),
+    INDEX idx_outbox_email_kind (kind)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- In-app notifications
+CREATE TABLE IF NOT EXISTS notifications (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    type VARCHAR(50) NOT NULL, -- e.g. attendance.late_arrival
+    category VARCHAR(20) NOT NULL, -- alert, reminder, approval, correction or announcement
+    employee_id VARCHAR(50) NULL, -- Employee the notification is about, when not the recipient
+    title VARCHAR(255) NOT NULL,
+    message TEXT NULL,
+    sender_name VARCHAR(150) NULL,
+    read_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_notification_user (user_id, read_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- How each user wants to be notified, users without a row get every channel at any hour
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL UNIQUE,
+    email_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    in_app_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    quiet_hours_start VARCHAR(5) NULL, -- HH:MM server time, emails wait until quiet_hours_end
+    quiet_hours_end VARCHAR(5) NULL,
+    digest_daily BOOLEAN NOT NULL DEFAULT FALSE, -- Manager digest emails
+    digest_weekly BOOLEAN NOT NULL DEFAULT FALSE,
+    digest_time VARCHAR(5) NULL, -- HH:MM server time, DIGEST_DEFAULT_TIME when empty
+    daily_digest_sent_on DATE NULL,
+    weekly_digest_sent_on DATE NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Attendance alert rules
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(30) NOT NULL, -- late_arrival, missing_clock_in, open_session
+    threshold_minutes INT NOT NULL DEFAULT 0,
+    department_id INT NULL, -- NULL for every department
+    recipient VARCHAR(20) NOT NULL, -- employee, manager, both
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (department_id) REFERENCES departments(id) ON DELETE CASCADE,
+    INDEX idx_alert_rule_type (type)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Alerts already sent, each rule fires once per employee per day
+CREATE TABLE IF NOT EXISTS alert_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    rule_id INT NOT NULL,
+    employee_id VARCHAR(50) NOT NULL,
+    event_date DATE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (rule_id) REFERENCES alert_rules(id) ON DELETE CASCADE,
+    UNIQUE INDEX idx_alert_event_once (rule_id, employee_id, event_date),
+    INDEX idx_alert_event_date (event_date)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Outbound webhook subscriptions
+CREATE TABLE IF NOT EXISTS webhooks (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    url VARCHAR(500) NOT NULL,
+    secret VARCHAR(255) NOT NULL, -- Encrypted with ENCRYPTION_KEY
+    events VARCHAR(255) NOT NULL, -- Comma-separated event types
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Webhook events queued for or delivered to each webhook
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    webhook_id INT NOT NULL,
+    event_id VARCHAR(40) NOT NULL, -- Same for every webhook the event went to
+    event_type VARCHAR(50) NOT NULL,
+    payload MEDIUMTEXT NULL,
+    status VARCHAR(20) NOT NULL, -- pending, delivered, dead
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    response_status INT NOT NULL DEFAULT 0,
+    last_error VARCHAR(500) NULL,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    delivered_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE,
+    INDEX idx_webhook_delivery_webhook (webhook_id),
+    INDEX idx_webhook_delivery_due (status, next_attempt_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Reports generated on a schedule and emailed to a recipient list
+CREATE TABLE IF NOT EXISTS report_schedules (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    report_type VARCHAR(20) NOT NULL, -- attendance, summary, department
+    department_id INT NULL, -- NULL for every department
+    include_sub_departments BOOLEAN NOT NULL DEFAULT FALSE,
+    period VARCHAR(20) NOT NULL, -- yesterday, last_7_days, last_30_days, last_week, last_month, month_to_date
+    format VARCHAR(10) NOT NULL, -- xlsx, csv, pdf
+    cron VARCHAR(100) NOT NULL, -- Server time
+    recipients VARCHAR(1000) NOT NULL, -- Comma-separated email addresses
+    locale VARCHAR(5) NULL, -- EMAIL_DEFAULT_LOCALE when empty
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMP NULL, -- NULL while inactive
+    last_run_at TIMESTAMP NULL,
+    last_run_status VARCHAR(20) NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (department_id) REFERENCES departments(id) ON DELETE CASCADE,
+    INDEX idx_report_schedule_due (next_run_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Run history of report schedules
+CREATE TABLE IF NOT EXISTS report_schedule_runs (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    schedule_id INT NOT NULL,
+    triggered_by VARCHAR(20) NOT NULL, -- schedule, manual
+    status VARCHAR(20) NOT NULL, -- running, succeeded, failed
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    filename VARCHAR(255) NULL,
+    file_size BIGINT NOT NULL DEFAULT 0,
+    recipients INT NOT NULL DEFAULT 0, -- Emails queued
+    error VARCHAR(500) NULL,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP NULL,
+
+    FOREIGN KEY (schedule_id) REFERENCES report_schedules(id) ON DELETE CASCADE,
+    INDEX idx_report_run_schedule (schedule_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Single sign-on logins started but not yet finished
+CREATE TABLE IF NOT EXISTS oidc_states (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    state_hash VARCHAR(64) NOT NULL UNIQUE, -- SHA-256 of the state parameter
+    nonce VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL, -- PKCE verifier
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX idx_oidc_state_expires (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Revoked access token IDs, pruned once the token would have expired
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    jti VARCHAR(64) NOT NULL UNIQUE,
+    user_id INT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_revoked_token_user (user_id),
+    INDEX idx_revoked_token_expires (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Failed attempts on throttled endpoints and account lockout events
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    scope VARCHAR(20) NOT NULL, -- login, setup, reset, verify, mfa, forgot
+    event VARCHAR(20) NOT NULL, -- failed, locked, unlocked
+    user_id INT NULL,
+    identifier VARCHAR(255) NULL,
+    ip_address VARCHAR(45) NULL,
+    detail VARCHAR(255) NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_login_attempt_ip (scope, event, ip_address, created_at),
+    INDEX idx_login_attempt_user (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Two-factor recovery codes, each usable once
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    code_hash VARCHAR(64) NOT NULL, -- SHA-256 of the code
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_mfa_recovery_code_user (user_id, code_hash)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Service accounts for system integrations, authenticated with API keys
+CREATE TABLE IF NOT EXISTS service_accounts (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    description VARCHAR(255) NULL,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_by INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- API keys, limited to declared scopes
+CREATE TABLE IF NOT EXISTS api_keys (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    service_account_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(20) NOT NULL UNIQUE, -- Public part of the key used to look it up
+    key_hash VARCHAR(64) NOT NULL, -- SHA-256 of the full key, the key itself is never stored
+    scopes VARCHAR(255) NOT NULL, -- Comma-separated, e.g. attendance:read,reports:export
+    expires_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP NULL,
+    last_used_ip VARCHAR(45) NULL,
+    revoked_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (service_account_id) REFERENCES service_accounts(id) ON DELETE CASCADE,
+    INDEX idx_api_key_account (service_account_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Roles table (users.role references roles.name)
+CREATE TABLE IF NOT EXISTS roles (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(50) NOT NULL UNIQUE,
+    description VARCHAR(255) NULL,
+    is_system BOOLEAN DEFAULT FALSE, -- Built-in roles cannot be renamed or deleted
+    require_mfa BOOLEAN DEFAULT FALSE, -- Members must enroll in two-factor authentication
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Permissions table
+CREATE TABLE IF NOT EXISTS permissions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    code VARCHAR(100) NOT NULL UNIQUE,
+    description VARCHAR(255) NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Permissions granted to each role
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id INT NOT NULL,
+    permission_id INT NOT NULL,
+
+    PRIMARY KEY (role_id, permission_id),
+    FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+    FOREIGN KEY (permission_id) REFERENCES permissions(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Insert built-in roles
+INSERT INTO roles (name, description, is_system) VALUES
+('employee', 'Records own attendance and views the employee directory', TRUE),
+('manager', 'Manages employees, departments and reports', TRUE),
+('admin', 'Full access including role administration', TRUE);
+
+-- Insert permissions
+INSERT INTO permissions (code, description) VALUES
+('employees.view', 'View the employee directory'),
+('employees.view_all', 'View private details and attendance of any employee'),
+('employees.manage', 'Create, update and delete employees'),
+('departments.view', 'View departments'),
+('departments.manage', 'Create, update and delete departments'),
+('attendance.record', 'Clock in, clock out and view own attendance'),
+('attendance.view_all', 'View attendance logs of all employees'),
+('reports.view', 'Generate attendance reports'),
+('reports.export', 'Export reports to files'),
+('report_schedules.manage', 'Schedule reports to be emailed to a recipient list'),
+('users.manage', 'Assign roles to user accounts'),
+('roles.manage', 'Create and edit roles and their permissions'),
+('service_accounts.manage', 'Manage service accounts and their API keys'),
+('audit.view', 'View and export the audit log'),
+('emails.manage', 'View email delivery status and re-send emails'),
+('alerts.manage', 'Configure attendance alert rules'),
+('announcements.manage', 'Send announcements to everyone or a department'),
+('webhooks.manage', 'Manage outbound webhooks and view their deliveries'),
+('dashboard.admin', 'View the admin dashboard'),
+('dashboard.manager', 'View the manager dashboard');
+
+-- Grant permissions to built-in roles
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r, permissions p
+WHERE r.name = 'employee' AND p.code IN ('employees.view', 'attendance.record');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r, permissions p
+WHERE r.name = 'manager' AND p.code IN (
+    'employees.view', 'employees.view_all', 'employees.manage', 'departments.view', 'departments.manage',
+    'attendance.record', 'attendance.view_all', 'reports.view', 'reports.export', 'dashboard.manager'
+);
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r, permissions p
+WHERE r.name = 'admin';
+
+-- Insert default attendance alert rules
+INSERT INTO alert_rules (name, type, threshold_minutes, department_id, recipient, is_active) VALUES
+('Late arrival', 'late_arrival', 0, NULL, 'manager', TRUE),
+('Missing clock-in reminder', 'missing_clock_in', 15, NULL, 'employee', TRUE),
+('Clock-out reminder', 'open_session', 30, NULL, 'employee', TRUE);
+
 -- Insert sample departments
 INSERT INTO departments (name, description, max_clock_in, max_clock_out, late_tolerance, early_leave_penalty) VALUES
 ('IT Department', 'Information Technology Department responsible for software development and infrastructure', '08:30:00', '17:00:00', 15, 30),
@@ -150,16 +577,29 @@ INSERT INTO attendance_histories (employee_id, attendance_id, date_attendance, a
 INSERT INTO users (username, email, password, role, is_active) VALUES
 ('admin', 'admin@company.com', '$2a$10$lr9BGyvFP2VjwICQX10mQuHk5FKyf14nXYpLRCqLz5Xq7qaK4Uh4G', 'admin', TRUE);
 
--- Insert user accounts for employees with setup tokens (NO PASSWORD initially)
-INSERT INTO users (username, email, password, role, employee_id, is_active, setup_token, token_expires) VALUES
-('john.doe', 'john.doe@company.com', NULL, 'employee', 'EMP001', FALSE, 'setup_token_emp001_abc123def456', DATE_ADD(NOW(), INTERVAL 7 DAY)),
-('jane.smith', 'jane.smith@company.com', '$2a$10$nt3gEDP3zJAyVPfXOYRG2OQJoeNyGKjqinn33plGEajuha/bWgqf6', 'manager', 'EMP002', TRUE, NULL, NULL),
-('bob.johnson', 'bob.johnson@company.com', NULL, 'employee', 'EMP003', FALSE, 'setup_token_emp003_ghi789jkl012', DATE_ADD(NOW(), INTERVAL 7 DAY)),
-('alice.brown', 'alice.brown@company.com', '$2a$10$nt3gEDP3zJAyVPfXOYRG2OQJoeNyGKjqinn33plGEajuha/bWgqf6', 'employee', 'EMP004', TRUE, NULL, NULL),
-('charlie.wilson', 'charlie.wilson@company.com', NULL, 'employee', 'EMP005', FALSE, 'setup_token_emp005_mno345pqr678', DATE_ADD(NOW(), INTERVAL 7 DAY)),
-('david.lee', 'david.lee@company.com', NULL, 'employee', 'EMP006', FALSE, 'setup_token_emp006_stu901vwx234', DATE_ADD(NOW(), INTERVAL 7 DAY)),
-('sarah.chen', 'sarah.chen@company.com', NULL, 'employee', 'EMP007', FALSE, 'setup_token_emp007_yza567bcd890', DATE_ADD(NOW(), INTERVAL 7 DAY)),
-('mike.garcia', 'mike.garcia@company.com', NULL, 'employee', 'EMP008', FALSE, 'setup_token_emp008_efg123hij456', DATE_ADD(NOW(), INTERVAL 7 DAY));
+-- Insert user accounts for employees (NO PASSWORD initially until setup)
+INSERT INTO users (username, email, password, role, employee_id, is_active) VALUES
+('john.doe', 'john.doe@company.com', NULL, 'employee', 'EMP001', FALSE),
+('jane.smith', 'jane.smith@company.com', '$2a$10$nt3gEDP3zJAyVPfXOYRG2OQJoeNyGKjqinn33plGEajuha/bWgqf6', 'manager', 'EMP002', TRUE),
+('bob.johnson', 'bob.johnson@company.com', NULL, 'employee', 'EMP003', FALSE),
+('alice.brown', 'alice.brown@company.com', '$2a$10$nt3gEDP3zJAyVPfXOYRG2OQJoeNyGKjqinn33plGEajuha/bWgqf6', 'employee', 'EMP004', TRUE),
+('charlie.wilson', 'charlie.wilson@company.com', NULL, 'employee', 'EMP005', FALSE),
+('david.lee', 'david.lee@company.com', NULL, 'employee', 'EMP006', FALSE),
+('sarah.chen', 'sarah.chen@company.com', NULL, 'employee', 'EMP007', FALSE),
+('mike.garcia', 'mike.garcia@company.com', NULL, 'employee', 'EMP008', FALSE);
+
+-- Insert account setup tokens for the pending users (stored hashed, plain values for local testing)
+INSERT INTO one_time_tokens (user_id, purpose, token_hash, expires_at)
+SELECT u.id, 'account-setup', SHA2(t.token, 256), DATE_ADD(NOW(), INTERVAL 7 DAY)
+FROM users u
+INNER JOIN (
+    SELECT 'EMP001' AS employee_id, 'setup_token_emp001_abc123def456' AS token
+    UNION ALL SELECT 'EMP003', 'setup_token_emp003_ghi789jkl012'
+    UNION ALL SELECT 'EMP005', 'setup_token_emp005_mno345pqr678'
+    UNION ALL SELECT 'EMP006', 'setup_token_emp006_stu901vwx234'
+    UNION ALL SELECT 'EMP007', 'setup_token_emp007_yza567bcd890'
+    UNION ALL SELECT 'EMP008', 'setup_token_emp008_efg123hij456'
+) t ON u.employee_id = t.employee_id;
 
 -- Create views for common queries
 CREATE OR REPLACE VIEW employee_attendance_summary AS
@@ -181,17 +621,18 @@ SELECT
     u.id as user_id,
     u.username,
     u.email,
-    u.setup_token,
-    u.token_expires,
+    t.expires_at as token_expires,
+    t.request_ip,
     e.employee_id,
     e.name as employee_name,
     d.name as department_name
 FROM users u
+INNER JOIN one_time_tokens t ON t.user_id = u.id AND t.purpose = 'account-setup'
 INNER JOIN employees e ON u.employee_id = e.employee_id
 INNER JOIN departments d ON e.department_id = d.id
 WHERE u.is_active = FALSE 
-AND u.setup_token IS NOT NULL 
-AND u.token_expires > NOW();
+AND t.used_at IS NULL 
+AND t.expires_at > NOW();
 
 -- Display success message
 SELECT 'Database migration completed successfully!' as message;
\ No newline at end of file
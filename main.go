@@ -1,11 +1,16 @@
 package main
 
 import (
+	"attendance-system/config"
 	"attendance-system/database"
 	"attendance-system/middleware"
 	"attendance-system/routes"
+	"attendance-system/services"
+	"attendance-system/utils"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -27,6 +32,20 @@ func main() {
 		}
 	}
 
+	// Refuse to start with development keys in production
+	if err := utils.LoadJWTKeys(); err != nil {
+		log.Fatal("❌ Failed to load JWT signing keys: ", err)
+	}
+	if err := utils.CheckEncryptionKey(); err != nil {
+		log.Fatal("❌ ", err)
+	}
+	if _, err := services.NewEmailSender(config.GetConfig()); err != nil {
+		log.Fatal("❌ Invalid email configuration: ", err)
+	}
+	if err := services.LoadEmailTemplates(); err != nil {
+		log.Fatal("❌ Invalid email templates: ", err)
+	}
+
 	// Set Gin mode
 	if os.Getenv("GIN_MODE") == "release" {
 		gin.SetMode(gin.ReleaseMode)
@@ -43,10 +62,65 @@ func main() {
 		}
 	}
 
+	// Prune expired refresh tokens and revocation entries
+	pruneInterval, err := time.ParseDuration(os.Getenv("TOKEN_PRUNE_INTERVAL"))
+	if err != nil || pruneInterval <= 0 {
+		pruneInterval = time.Hour
+	}
+	services.StartTokenPruner(pruneInterval)
+
+	// Deliver queued emails
+	emailWorkerInterval, err := time.ParseDuration(os.Getenv("EMAIL_WORKER_INTERVAL"))
+	if err != nil || emailWorkerInterval <= 0 {
+		emailWorkerInterval = 10 * time.Second
+	}
+	services.StartEmailOutboxWorker(emailWorkerInterval)
+
+	// Evaluate attendance alert rules that depend on time passing
+	alertSweepInterval, err := time.ParseDuration(os.Getenv("ALERT_SWEEP_INTERVAL"))
+	if err != nil || alertSweepInterval <= 0 {
+		alertSweepInterval = time.Minute
+	}
+	services.StartAlertSweeper(alertSweepInterval)
+
+	// Send manager digests once their send time has passed
+	digestInterval, err := time.ParseDuration(os.Getenv("DIGEST_SWEEP_INTERVAL"))
+	if err != nil || digestInterval <= 0 {
+		digestInterval = time.Minute
+	}
+	services.StartDigestScheduler(digestInterval)
+
+	// Email scheduled reports when they are due
+	reportScheduleInterval, err := time.ParseDuration(os.Getenv("REPORT_SCHEDULE_INTERVAL"))
+	if err != nil || reportScheduleInterval <= 0 {
+		reportScheduleInterval = time.Minute
+	}
+	services.StartReportScheduler(reportScheduleInterval)
+
+	// Deliver queued webhook events
+	webhookWorkerInterval, err := time.ParseDuration(os.Getenv("WEBHOOK_WORKER_INTERVAL"))
+	if err != nil || webhookWorkerInterval <= 0 {
+		webhookWorkerInterval = 10 * time.Second
+	}
+	services.StartWebhookDispatcher(webhookWorkerInterval)
+
 	// Create Gin router
 	router := gin.New()
 
+	// Only believe X-Forwarded-For from our own proxies, or any client could pick the IP
+	// that throttling, sessions and the audit log see
+	var trustedProxies []string
+	if proxies := config.GetConfig().TrustedProxies; proxies != "" {
+		for _, proxy := range strings.Split(proxies, ",") {
+			trustedProxies = append(trustedProxies, strings.TrimSpace(proxy))
+		}
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+
 	// Middleware
+	router.Use(middleware.RequestID())
 	router.Use(middleware.Logger())
 	router.Use(middleware.SetupCORS())
 	router.Use(gin.Recovery())
@@ -9,9 +9,54 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// restrictedTokenRoutes are the routes each kind of restricted token may call
+var restrictedTokenRoutes = map[string]map[string]bool{
+	utils.RestrictionMFAEnrollment: {
+		"/api/v1/auth/profile":     true,
+		"/api/v1/auth/logout":      true,
+		"/api/v1/auth/mfa":         true,
+		"/api/v1/auth/mfa/enroll":  true,
+		"/api/v1/auth/mfa/confirm": true,
+	},
+	utils.RestrictionPasswordChange: {
+		"/api/v1/auth/profile":         true,
+		"/api/v1/auth/logout":          true,
+		"/api/v1/auth/change-password": true,
+	},
+}
+
+// restrictedTokenMessages explain what a restricted token's holder has to do first
+var restrictedTokenMessages = map[string]string{
+	utils.RestrictionMFAEnrollment:  "Two-factor authentication must be set up before using this resource",
+	utils.RestrictionPasswordChange: "Your password has expired and must be changed before using this resource",
+}
+
 // AuthMiddleware provides JWT authentication middleware
 func AuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
+	serviceAccountService := services.NewServiceAccountService()
+
 	return func(c *gin.Context) {
+		// Integrations authenticate with an API key instead of a user's JWT
+		if apiKey := apiKeyFromRequest(c); apiKey != "" {
+			key, err := serviceAccountService.Authenticate(apiKey, utils.GetClientIP(c))
+			if err != nil {
+				utils.ErrorJSON(c, http.StatusUnauthorized, "Invalid or expired API key")
+				c.Abort()
+				return
+			}
+
+			// A key only carries the permissions of its scopes and is never tied to a user or employee
+			c.Set("username", "service:"+key.ServiceAccount.Name)
+			c.Set("role", "")
+			c.Set("employee_id", "")
+			c.Set("service_account_id", key.ServiceAccountID)
+			c.Set("api_key_id", key.ID)
+			c.Set("permissions", key.Permissions())
+
+			c.Next()
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			utils.ErrorJSON(c, http.StatusUnauthorized, "Authorization header is required")
@@ -37,15 +82,48 @@ func AuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 			return
 		}
 
+		// A restricted token only reaches the routes needed to finish the pending step
+		if claims.Restriction != "" && !restrictedTokenRoutes[claims.Restriction][c.FullPath()] {
+			utils.ErrorJSON(c, http.StatusForbidden, restrictedTokenMessages[claims.Restriction])
+			c.Abort()
+			return
+		}
+
 		// Set user info in context
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("role", claims.Role)
+		c.Set("employee_id", claims.EmployeeID)
+		c.Set("claims", claims)
+
+		// Permissions are looked up per request so role changes apply to tokens already issued
+		permissions, err := authService.GetPermissions(claims.Role)
+		if err != nil {
+			permissions = []string{}
+		}
+		c.Set("permissions", permissions)
 
 		c.Next()
 	}
 }
 
+// apiKeyFromRequest reads an API key from the X-API-Key header, or from an
+// Authorization header using the ApiKey or Bearer scheme
+func apiKeyFromRequest(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+
+	parts := strings.Split(c.GetHeader("Authorization"), " ")
+	if len(parts) != 2 {
+		return ""
+	}
+	if parts[0] == "ApiKey" || (parts[0] == "Bearer" && services.IsAPIKey(parts[1])) {
+		return parts[1]
+	}
+	return ""
+}
+
 // OptionalAuthMiddleware provides optional authentication
 func OptionalAuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -54,10 +132,14 @@ func OptionalAuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 			parts := strings.Split(authHeader, " ")
 			if len(parts) == 2 && parts[0] == "Bearer" {
 				claims, err := authService.ValidateToken(parts[1])
-				if err == nil {
+				if err == nil && claims.Restriction == "" {
 					c.Set("user_id", claims.UserID)
 					c.Set("username", claims.Username)
 					c.Set("role", claims.Role)
+					c.Set("employee_id", claims.EmployeeID)
+					if permissions, err := authService.GetPermissions(claims.Role); err == nil {
+						c.Set("permissions", permissions)
+					}
 				}
 			}
 		}
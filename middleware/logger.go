@@ -18,6 +18,7 @@ func Logger() gin.HandlerFunc {
 				Str("method", c.Request.Method).
 				Str("ip", c.ClientIP()).
 				Str("user_agent", c.Request.UserAgent()).
+				Str("request_id", c.GetString("request_id")).
 				Logger()
 		}),
 	)
@@ -47,7 +48,7 @@ func CustomLogger() gin.HandlerFunc {
 			Str("user_agent", c.Request.UserAgent()).
 			Int("status", statusCode).
 			Dur("duration", duration).
-			Str("request_id", c.GetHeader("X-Request-ID")).
+			Str("request_id", c.GetString("request_id")).
 			Logger()
 
 		// Log based on status code
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"attendance-system/utils"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestIDPattern limits IDs passed in by a proxy to something safe to log and store
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID tags each request with an ID, reusing X-Request-ID from a proxy when present.
+// The ID is echoed in the response and recorded in logs and the audit log.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader("X-Request-ID")
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = utils.GenerateID("", 32)
+		}
+
+		c.Set("request_id", requestID)
+		c.Header("X-Request-ID", requestID)
+		c.Next()
+	}
+}
@@ -39,6 +39,56 @@ func RoleMiddleware(allowedRoles []string) gin.HandlerFunc {
 	}
 }
 
+// RequirePermission only allows users whose role grants the given permission
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !utils.HasPermission(c, permission) {
+			utils.ErrorJSON(c, http.StatusForbidden,
+				"Insufficient permissions. Required permission: "+permission)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireAnyPermission allows users whose role grants at least one of the given permissions
+func RequireAnyPermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, permission := range permissions {
+			if utils.HasPermission(c, permission) {
+				c.Next()
+				return
+			}
+		}
+
+		utils.ErrorJSON(c, http.StatusForbidden,
+			"Insufficient permissions. Required one of: "+strings.Join(permissions, ", "))
+		c.Abort()
+	}
+}
+
+// SelfOrPermissionMiddleware allows users holding the given permission, or the owner of the
+// employee record referenced by the given route parameter
+func SelfOrPermissionMiddleware(param string, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if utils.HasPermission(c, permission) {
+			c.Next()
+			return
+		}
+
+		ownEmployeeID := utils.GetEmployeeIDFromContext(c)
+		if ownEmployeeID == "" || ownEmployeeID != c.Param(param) {
+			utils.ErrorJSON(c, http.StatusForbidden, "You can only access your own records")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // AdminMiddleware - only allows admin users
 func AdminMiddleware() gin.HandlerFunc {
 	return RoleMiddleware([]string{"admin"})
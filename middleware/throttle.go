@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"attendance-system/models"
+	"attendance-system/services"
+	"attendance-system/utils"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ThrottleMiddleware limits guessing on endpoints that accept passwords or one-time tokens.
+// Requests from an IP that failed too often recently get 429, and every rejected
+// attempt (400, 401 or 404) counts against the IP. In the forgot password scope every
+// request counts, since it answers the same whether or not the email exists.
+func ThrottleMiddleware(scope string) gin.HandlerFunc {
+	throttleService := services.NewLoginThrottleService()
+
+	return func(c *gin.Context) {
+		ip := utils.GetClientIP(c)
+
+		wait, err := throttleService.CheckIP(scope, ip)
+		if err != nil {
+			if wait > 0 {
+				c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			}
+			utils.HandleError(c, err)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+
+		if scope == models.ThrottleScopeForgot {
+			throttleService.RecordIPFailure(scope, ip)
+			return
+		}
+		switch c.Writer.Status() {
+		case http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound:
+			throttleService.RecordIPFailure(scope, ip)
+		}
+	}
+}
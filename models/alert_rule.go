@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+)
+
+// Kinds of attendance alert rule
+const (
+	// Clocked in more than ThresholdMinutes late, counted from MaxClockIn plus the late tolerance
+	AlertRuleLateArrival = "late_arrival"
+	// Not clocked in ThresholdMinutes after MaxClockIn, checked until MaxClockOut
+	AlertRuleMissingClockIn = "missing_clock_in"
+	// Still clocked in ThresholdMinutes after MaxClockOut
+	AlertRuleOpenSession = "open_session"
+)
+
+// Who an alert is sent to
+const (
+	AlertRecipientEmployee = "employee"
+	AlertRecipientManager  = "manager" // The employee's direct manager
+	AlertRecipientBoth     = "both"
+)
+
+// AlertRule turns an attendance condition into notifications. Each rule alerts at most
+// once per employee per day.
+type AlertRule struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	Name             string    `gorm:"size:100;not null" json:"name"`
+	Type             string    `gorm:"size:30;not null;index" json:"type"`
+	ThresholdMinutes int       `gorm:"not null;default:0" json:"threshold_minutes"`
+	DepartmentID     *uint     `gorm:"index" json:"department_id"` // Nil for every department
+	Recipient        string    `gorm:"size:20;not null" json:"recipient"`
+	IsActive         bool      `gorm:"not null" json:"is_active"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+func (AlertRule) TableName() string {
+	return "alert_rules"
+}
+
+type AlertRuleRequest struct {
+	Name             string `json:"name" binding:"required,max=100"`
+	Type             string `json:"type" binding:"required,oneof=late_arrival missing_clock_in open_session"`
+	ThresholdMinutes int    `json:"threshold_minutes" binding:"min=0,max=1440"`
+	DepartmentID     *uint  `json:"department_id"`
+	Recipient        string `json:"recipient" binding:"required,oneof=employee manager both"`
+	IsActive         *bool  `json:"is_active"` // Defaults to true
+}
+
+// AlertEvent records that a rule fired for an employee on a day, so it fires only once
+type AlertEvent struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	RuleID     uint      `gorm:"not null;uniqueIndex:idx_alert_event_once" json:"rule_id"`
+	EmployeeID string    `gorm:"size:50;not null;uniqueIndex:idx_alert_event_once" json:"employee_id"`
+	EventDate  time.Time `gorm:"type:date;not null;uniqueIndex:idx_alert_event_once" json:"event_date"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (AlertEvent) TableName() string {
+	return "alert_events"
+}
@@ -56,6 +56,18 @@ type AttendanceResponse struct {
 	ClockOutFormatted  string `json:"clock_out_formatted,omitempty"`
 }
 
+// TodayStatusResponse describes the employee's attendance state for the current day
+type TodayStatusResponse struct {
+	EmployeeID       string              `json:"employee_id"`
+	Date             string              `json:"date"`
+	ClockedIn        bool                `json:"clocked_in"`
+	ClockedOut       bool                `json:"clocked_out"`
+	ClockInAt        *time.Time          `json:"clock_in_at"`
+	ClockOutAt       *time.Time          `json:"clock_out_at"`
+	ExpectedShiftEnd *time.Time          `json:"expected_shift_end"`
+	Attendance       *AttendanceResponse `json:"attendance,omitempty"`
+}
+
 func (a *Attendance) ToResponse() AttendanceResponse {
 	return AttendanceResponse{
 		ID:           a.ID,
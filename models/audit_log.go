@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+)
+
+// Kinds of actor recorded in the audit log
+const (
+	AuditActorUser           = "user"
+	AuditActorServiceAccount = "service_account"
+	AuditActorAnonymous      = "anonymous" // Token-based flows such as account setup and password reset
+)
+
+// Audited entity types
+const (
+	AuditEntityEmployee       = "employee"
+	AuditEntityDepartment     = "department"
+	AuditEntityUser           = "user"
+	AuditEntityRole           = "role"
+	AuditEntityAttendance     = "attendance"
+	AuditEntitySession        = "session"
+	AuditEntityEmail          = "email"
+	AuditEntityAlertRule      = "alert_rule"
+	AuditEntityAnnouncement   = "announcement"
+	AuditEntityWebhook        = "webhook"
+	AuditEntityReportSchedule = "report_schedule"
+)
+
+// AuditLog records who changed what, with the values before and after the change
+type AuditLog struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	ActorType  string `gorm:"size:20;not null" json:"actor_type"`
+	ActorID    string `gorm:"size:50;index" json:"actor_id"` // User ID or service account ID
+	ActorName  string `gorm:"size:150" json:"actor_name"`
+	Action     string `gorm:"size:50;not null;index" json:"action"` // e.g. employee.delete, user.role_assign
+	EntityType string `gorm:"size:50;not null;index:idx_audit_log_entity" json:"entity_type"`
+	EntityID   string `gorm:"size:100;index:idx_audit_log_entity" json:"entity_id"`
+	// JSON object of changed fields, each {"old": ..., "new": ...}
+	Changes   string    `gorm:"type:json" json:"changes"`
+	IPAddress string    `gorm:"size:45" json:"ip_address"`
+	RequestID string    `gorm:"size:64;index" json:"request_id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// AuditActor identifies who made a change. Controllers build it from the request with
+// utils.GetAuditActor and pass it to the service making the change.
+type AuditActor struct {
+	Type       string
+	ID         string
+	Name       string
+	EmployeeID string // Employee linked to the acting user, if any
+	IPAddress  string
+	RequestID  string
+}
+
+// AuditChange is one field's value before and after a change
+type AuditChange struct {
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+}
+
+// AuditLogFilter narrows an audit log query; empty fields are ignored
+type AuditLogFilter struct {
+	ActorID    string `form:"actor_id"`
+	Action     string `form:"action"`
+	EntityType string `form:"entity_type"`
+	EntityID   string `form:"entity_id"`
+	RequestID  string `form:"request_id"`
+	StartDate  string `form:"start_date"` // YYYY-MM-DD, inclusive
+	EndDate    string `form:"end_date"`   // YYYY-MM-DD, inclusive
+}
+
+type AuditLogResponse struct {
+	ID         uint                   `json:"id"`
+	ActorType  string                 `json:"actor_type"`
+	ActorID    string                 `json:"actor_id"`
+	ActorName  string                 `json:"actor_name"`
+	Action     string                 `json:"action"`
+	EntityType string                 `json:"entity_type"`
+	EntityID   string                 `json:"entity_id"`
+	Changes    map[string]AuditChange `json:"changes"`
+	IPAddress  string                 `json:"ip_address"`
+	RequestID  string                 `json:"request_id"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
@@ -7,6 +7,7 @@ import (
 // In department.go
 type Department struct {
     ID                uint      `gorm:"primaryKey" json:"id"`
+    ParentID          *uint     `gorm:"index" json:"parent_id"`                     // Parent division/department, nil for top level
     Name              string    `gorm:"size:255;not null;uniqueIndex" json:"name"`
     Description       string    `gorm:"type:text" json:"description"`
     MaxClockIn        string    `gorm:"size:8;not null" json:"max_clock_in"`        // Format: HH:MM:SS
@@ -21,6 +22,7 @@ type Department struct {
 }
 
 type DepartmentRequest struct {
+	ParentID          *uint  `json:"parent_id"`
 	Name              string `json:"name" binding:"required"`
 	Description       string `json:"description"`
 	MaxClockIn        string `json:"max_clock_in" binding:"required"`
@@ -32,6 +34,7 @@ type DepartmentRequest struct {
 
 type DepartmentResponse struct {
 	ID                uint      `json:"id"`
+	ParentID          *uint     `json:"parent_id"`
 	Name              string    `json:"name"`
 	Description       string    `json:"description"`
 	MaxClockIn        string    `json:"max_clock_in"`
@@ -47,6 +50,7 @@ type DepartmentResponse struct {
 func (d *Department) ToResponse() DepartmentResponse {
 	return DepartmentResponse{
 		ID:                d.ID,
+		ParentID:          d.ParentID,
 		Name:              d.Name,
 		Description:       d.Description,
 		MaxClockIn:        d.MaxClockIn,
@@ -57,4 +61,10 @@ func (d *Department) ToResponse() DepartmentResponse {
 		CreatedAt:         d.CreatedAt,
 		UpdatedAt:         d.UpdatedAt,
 	}
-}
\ No newline at end of file
+}
+
+// DepartmentTreeNode is a department together with its nested sub-departments
+type DepartmentTreeNode struct {
+	DepartmentResponse
+	Children []DepartmentTreeNode `json:"children"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+)
+
+// Kinds of email sent by the application
+const (
+	EmailKindAccountSetup    = "account_setup"
+	EmailKindWelcome         = "welcome"
+	EmailKindPasswordReset   = "password_reset"
+	EmailKindEmailVerify     = "email_verify"
+	EmailKindAttendanceAlert = "attendance_alert"
+	EmailKindManagerDigest   = "manager_digest"
+	EmailKindScheduledReport = "scheduled_report"
+)
+
+// Delivery states of an outbox email
+const (
+	EmailStatusPending = "pending" // Waiting for its first or next attempt
+	EmailStatusSent    = "sent"
+	EmailStatusDead    = "dead" // Gave up after the maximum number of attempts
+)
+
+// OutboxEmail is an email queued for delivery by the outbox worker. The bodies carry
+// one-time links, so they are stored encrypted and never returned by the API, as is the
+// optional attachment, such as a scheduled report.
+type OutboxEmail struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	Kind           string     `gorm:"size:50;not null;index:idx_outbox_email_kind" json:"kind"`
+	Recipient      string     `gorm:"size:255;not null;index:idx_outbox_email_recipient" json:"recipient"`
+	Subject        string     `gorm:"size:255;not null" json:"subject"`
+	HTMLBody       string     `gorm:"type:mediumtext" json:"-"` // Encrypted
+	TextBody       string     `gorm:"type:mediumtext" json:"-"` // Encrypted
+	AttachmentName string     `gorm:"size:255" json:"attachment_name,omitempty"`
+	AttachmentType string     `gorm:"size:100" json:"-"`
+	Attachment     string     `gorm:"type:longtext" json:"-"` // Encrypted
+	Status         string     `gorm:"size:20;not null;index:idx_outbox_email_due" json:"status"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"not null;index:idx_outbox_email_due" json:"next_attempt_at"`
+	LastError      string     `gorm:"size:500" json:"last_error"`
+	SentAt         *time.Time `json:"sent_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func (OutboxEmail) TableName() string {
+	return "email_outbox"
+}
+
+// OutboxEmailFilter narrows an outbox query; empty fields are ignored
+type OutboxEmailFilter struct {
+	Recipient string `form:"recipient"`
+	Kind      string `form:"kind"`
+	Status    string `form:"status"`
+}
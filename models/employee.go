@@ -8,6 +8,7 @@ type Employee struct {
     ID           uint      `gorm:"primaryKey" json:"id"`
     EmployeeID   string    `gorm:"uniqueIndex;size:50;not null" json:"employee_id"`
     DepartmentID uint      `gorm:"not null;index" json:"department_id"`
+    ManagerID    *string   `gorm:"size:50;index" json:"manager_id"` // Employee ID of the direct manager
     Name         string    `gorm:"size:255;not null" json:"name"`
     Phone        string    `gorm:"size:20" json:"phone"`
     Address      string    `gorm:"type:text;not null" json:"address"`
@@ -29,6 +30,7 @@ type EmployeeWithUserResponse struct {
 type EmployeeRequest struct {
 	EmployeeID   string    `json:"employee_id"`
 	DepartmentID uint      `json:"department_id" binding:"required"`
+	ManagerID    *string   `json:"manager_id"`
 	Name         string    `json:"name" binding:"required"`
 	Email        string    `json:"email"`
 	Phone        string    `json:"phone"`
@@ -36,12 +38,15 @@ type EmployeeRequest struct {
 	Position     string    `json:"position"`
 	Status       string    `json:"status"`
 	JoinDate     time.Time `json:"join_date"`
+	// Language of the new account's emails (en, id); only used when creating
+	Locale string `json:"locale" binding:"omitempty,oneof=en id"`
 }
 
 type EmployeeResponse struct {
 	ID           uint      `json:"id"`
 	EmployeeID   string    `json:"employee_id"`
 	DepartmentID uint      `json:"department_id"`
+	ManagerID    *string   `json:"manager_id"`
 	Name         string    `json:"name"`
 	Email        string    `json:"email"`
 	Phone        string    `json:"phone"`
@@ -59,6 +64,7 @@ func (e *Employee) ToResponse() EmployeeResponse {
 		ID:           e.ID,
 		EmployeeID:   e.EmployeeID,
 		DepartmentID: e.DepartmentID,
+		ManagerID:    e.ManagerID,
 		Name:         e.Name,
 		Phone:        e.Phone,
 		Address:      e.Address,
@@ -71,6 +77,31 @@ func (e *Employee) ToResponse() EmployeeResponse {
 	}
 }
 
+// EmployeeDirectoryResponse is the reduced public view of a colleague
+type EmployeeDirectoryResponse struct {
+	ID             uint   `json:"id"`
+	EmployeeID     string `json:"employee_id"`
+	Name           string `json:"name"`
+	Position       string `json:"position"`
+	DepartmentID   uint   `json:"department_id"`
+	DepartmentName string  `json:"department_name"`
+	ManagerID      *string `json:"manager_id"`
+	Status         string  `json:"status"`
+}
+
+func (e *Employee) ToDirectoryResponse() EmployeeDirectoryResponse {
+	return EmployeeDirectoryResponse{
+		ID:             e.ID,
+		EmployeeID:     e.EmployeeID,
+		Name:           e.Name,
+		Position:       e.Position,
+		DepartmentID:   e.DepartmentID,
+		DepartmentName: e.Department.Name,
+		ManagerID:      e.ManagerID,
+		Status:         e.Status,
+	}
+}
+
 type EmployeeCreateResponse struct {
 	Employee   EmployeeResponse `json:"employee"`
 	SetupToken string           `json:"setup_token,omitempty"`
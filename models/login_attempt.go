@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+)
+
+// Throttle scopes for endpoints that accept guessable secrets
+const (
+	ThrottleScopeLogin  = "login"
+	ThrottleScopeSetup  = "setup"
+	ThrottleScopeReset  = "reset"
+	ThrottleScopeVerify = "verify"
+	ThrottleScopeMFA    = "mfa"
+	ThrottleScopeForgot = "forgot" // Every request counts, as the response never says whether it did anything
+)
+
+// Login attempt events
+const (
+	LoginEventFailed   = "failed"
+	LoginEventLocked   = "locked"
+	LoginEventUnlocked = "unlocked"
+)
+
+// LoginAttempt records failed attempts (counted per IP for throttling) and
+// account lockout events (kept for audit)
+type LoginAttempt struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Scope      string    `gorm:"size:20;not null;index" json:"scope"`
+	Event      string    `gorm:"size:20;not null;index" json:"event"`
+	UserID     *uint     `gorm:"index" json:"user_id"`
+	Identifier string    `gorm:"size:255" json:"identifier"` // Username or email that was tried
+	IPAddress  string    `gorm:"size:45;index" json:"ip_address"`
+	Detail     string    `gorm:"size:255" json:"detail"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+type LockedUserResponse struct {
+	ID                  uint       `json:"id"`
+	Username            string     `json:"username"`
+	Email               string     `json:"email"`
+	FailedLoginAttempts int        `json:"failed_login_attempts"`
+	LockedUntil         *time.Time `json:"locked_until"`
+}
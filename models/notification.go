@@ -0,0 +1,104 @@
+package models
+
+import (
+	"time"
+)
+
+// Notification categories
+const (
+	NotificationCategoryAlert        = "alert"
+	NotificationCategoryReminder     = "reminder"
+	NotificationCategoryApproval     = "approval"
+	NotificationCategoryCorrection   = "correction"
+	NotificationCategoryAnnouncement = "announcement"
+)
+
+// Inbox scopes: items about the user themselves or about their team
+const (
+	NotificationScopePersonal = "personal"
+	NotificationScopeTeam     = "team"
+)
+
+// Notification is a message in a user's in-app inbox
+type Notification struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	UserID   uint   `gorm:"not null;index:idx_notification_user" json:"-"`
+	Type     string `gorm:"size:50;not null" json:"type"` // e.g. attendance.late_arrival, announcement
+	Category string `gorm:"size:20;not null" json:"category"`
+	// Employee the item is about when it isn't the recipient, e.g. a direct report who was late
+	EmployeeID *string    `gorm:"size:50" json:"employee_id"`
+	Title      string     `gorm:"size:255;not null" json:"title"`
+	Message    string     `gorm:"type:text" json:"message"`
+	SenderName string     `gorm:"size:150" json:"sender_name,omitempty"` // Who sent an announcement
+	ReadAt     *time.Time `gorm:"index:idx_notification_user" json:"read_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// NotificationFilter narrows an inbox query; empty fields are ignored
+type NotificationFilter struct {
+	UnreadOnly bool   `form:"unread_only"`
+	Category   string `form:"category"`
+	Scope      string `form:"scope"` // personal or team
+}
+
+// AnnouncementRequest sends an announcement to everyone or to a department
+type AnnouncementRequest struct {
+	Title        string `json:"title" binding:"required,max=255"`
+	Message      string `json:"message" binding:"required,max=5000"`
+	Scope        string `json:"scope" binding:"required,oneof=all department"`
+	DepartmentID *uint  `json:"department_id"` // Required for the department scope
+	// Also send to the department's sub-departments
+	IncludeSubDepartments bool `json:"include_sub_departments"`
+}
+
+// TeamAnnouncementRequest sends an announcement to the sender's direct reports
+type TeamAnnouncementRequest struct {
+	Title   string `json:"title" binding:"required,max=255"`
+	Message string `json:"message" binding:"required,max=5000"`
+}
+
+type AnnouncementResponse struct {
+	Recipients int `json:"recipients"`
+}
+
+// NotificationPreference holds how a user wants to be notified. Users without one get
+// both email and in-app notifications at any hour.
+type NotificationPreference struct {
+	ID           uint `gorm:"primaryKey" json:"-"`
+	UserID       uint `gorm:"not null;uniqueIndex" json:"-"`
+	EmailEnabled bool `gorm:"not null" json:"email_enabled"`
+	InAppEnabled bool `gorm:"not null" json:"in_app_enabled"`
+	// Emails due between start and end (HH:MM, server time) wait until the end. The range
+	// may span midnight, e.g. 22:00 to 07:00. In-app notifications are never held back.
+	QuietHoursStart *string `gorm:"size:5" json:"quiet_hours_start"`
+	QuietHoursEnd   *string `gorm:"size:5" json:"quiet_hours_end"`
+	// Manager digest emails, sent at DigestTime (HH:MM, server time): daily on working
+	// days about the previous one, weekly on Mondays about the week before
+	DigestDaily        bool       `gorm:"not null" json:"digest_daily"`
+	DigestWeekly       bool       `gorm:"not null" json:"digest_weekly"`
+	DigestTime         *string    `gorm:"size:5" json:"digest_time"` // Defaults to DIGEST_DEFAULT_TIME
+	DailyDigestSentOn  *time.Time `gorm:"type:date" json:"-"`
+	WeeklyDigestSentOn *time.Time `gorm:"type:date" json:"-"`
+	CreatedAt          time.Time  `json:"-"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+func (NotificationPreference) TableName() string {
+	return "notification_preferences"
+}
+
+// NotificationPreferenceRequest updates the fields that are set. An empty quiet hours
+// start or end turns quiet hours off.
+type NotificationPreferenceRequest struct {
+	EmailEnabled    *bool   `json:"email_enabled"`
+	InAppEnabled    *bool   `json:"in_app_enabled"`
+	QuietHoursStart *string `json:"quiet_hours_start"` // HH:MM
+	QuietHoursEnd   *string `json:"quiet_hours_end"`   // HH:MM
+	DigestDaily     *bool   `json:"digest_daily"`
+	DigestWeekly    *bool   `json:"digest_weekly"`
+	DigestTime      *string `json:"digest_time"` // HH:MM, empty for the default
+}
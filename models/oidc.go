@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// OIDCState holds what is needed to finish an SSO login started at /auth/oidc/login.
+// It is looked up by the hash of the state parameter and deleted when used.
+type OIDCState struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	StateHash    string    `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Nonce        string    `gorm:"size:64;not null" json:"-"`
+	CodeVerifier string    `gorm:"size:128;not null" json:"-"` // PKCE verifier sent with the code exchange
+	ExpiresAt    time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (OIDCState) TableName() string {
+	return "oidc_states"
+}
+
+type OIDCLoginResponse struct {
+	AuthorizationURL string `json:"authorization_url"` // Send the browser here
+	State            string `json:"state"`
+}
+
+type OIDCCallbackRequest struct {
+	Code       string `json:"code" binding:"required"`
+	State      string `json:"state" binding:"required"`
+	DeviceName string `json:"device_name" binding:"omitempty,max=100"`
+}
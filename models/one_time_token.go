@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// One-time token purposes. A token is only accepted for the purpose it was issued for.
+const (
+	TokenPurposeAccountSetup  = "account-setup"
+	TokenPurposePasswordReset = "password-reset"
+	TokenPurposeEmailVerify   = "email-verify"
+)
+
+// OneTimeToken is an emailed token such as an account setup or password reset link.
+// Only the SHA-256 of the token is stored.
+type OneTimeToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	Purpose   string     `gorm:"size:30;not null;index" json:"purpose"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	SingleUse bool       `gorm:"default:true" json:"single_use"`
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	RequestIP string     `gorm:"size:45" json:"request_ip"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// IsUsable checks if the token has not expired and, for single-use tokens, has not been used
+func (t *OneTimeToken) IsUsable() bool {
+	if t.SingleUse && t.UsedAt != nil {
+		return false
+	}
+	return t.ExpiresAt.After(time.Now())
+}
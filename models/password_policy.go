@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// PasswordHistory keeps the hashes of a user's previous passwords so they can't be reused
+type PasswordHistory struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	UserID       uint      `gorm:"not null;index" json:"user_id"`
+	PasswordHash string    `gorm:"size:255;not null" json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (PasswordHistory) TableName() string {
+	return "password_history"
+}
+
+// PasswordPolicyResponse describes the active rules so clients can check passwords as they are typed
+type PasswordPolicyResponse struct {
+	MinLength        int  `json:"min_length"`
+	MaxLength        int  `json:"max_length"`
+	RequireUppercase bool `json:"require_uppercase"`
+	RequireLowercase bool `json:"require_lowercase"`
+	RequireDigit     bool `json:"require_digit"`
+	RequireSymbol    bool `json:"require_symbol"`
+	HistoryCount     int  `json:"history_count"` // Number of previous passwords that can't be reused
+	MaxAgeDays       int  `json:"max_age_days"`  // 0 when passwords don't expire
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken is one link in a rotation chain. Every refresh marks the presented
+// token as rotated and issues a new one in the same family, so presenting a
+// rotated token again means it was stolen and the whole family is revoked.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	FamilyID  string     `gorm:"size:64;not null;index" json:"family_id"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"` // SHA-256 of the token handed to the client
+	Role      string     `gorm:"size:50;not null" json:"role"`          // Role at issue time, refresh fails if it changed
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
+	RotatedAt *time.Time `json:"rotated_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// IsExpired checks if the refresh token is past its expiry
+func (t *RefreshToken) IsExpired() bool {
+	return t.ExpiresAt.Before(time.Now())
+}
@@ -0,0 +1,154 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Reports a schedule can deliver
+const (
+	ReportTypeAttendance = "attendance"
+	ReportTypeSummary    = "summary"
+	ReportTypeDepartment = "department" // Covers the month the period starts in
+)
+
+// Relative periods a scheduled report covers, counted back from when it runs
+const (
+	ReportPeriodYesterday   = "yesterday"
+	ReportPeriodLast7Days   = "last_7_days" // The seven days up to yesterday
+	ReportPeriodLast30Days  = "last_30_days"
+	ReportPeriodLastWeek    = "last_week" // Monday to Sunday
+	ReportPeriodLastMonth   = "last_month"
+	ReportPeriodMonthToDate = "month_to_date" // The first of the month up to today
+)
+
+// ReportPeriods are the relative periods a schedule can use
+var ReportPeriods = []string{
+	ReportPeriodYesterday,
+	ReportPeriodLast7Days,
+	ReportPeriodLast30Days,
+	ReportPeriodLastWeek,
+	ReportPeriodLastMonth,
+	ReportPeriodMonthToDate,
+}
+
+// How a report schedule run was started
+const (
+	ReportRunTriggerSchedule = "schedule"
+	ReportRunTriggerManual   = "manual"
+)
+
+// States of a report schedule run
+const (
+	ReportRunRunning   = "running"
+	ReportRunSucceeded = "succeeded"
+	ReportRunFailed    = "failed"
+)
+
+// ReportSchedule generates a report on a cron schedule and emails it to a list of
+// recipients as an attachment
+type ReportSchedule struct {
+	ID                    uint       `gorm:"primaryKey" json:"id"`
+	Name                  string     `gorm:"size:100;not null" json:"name"`
+	ReportType            string     `gorm:"size:20;not null" json:"report_type"`
+	DepartmentID          *uint      `json:"department_id"` // Every department when empty, required for department reports
+	IncludeSubDepartments bool       `gorm:"not null" json:"include_sub_departments"`
+	Period                string     `gorm:"size:20;not null" json:"period"`
+	Format                string     `gorm:"size:10;not null" json:"format"`
+	Cron                  string     `gorm:"size:100;not null" json:"cron"` // Server time
+	Recipients            string     `gorm:"size:1000;not null" json:"-"`   // Comma-separated email addresses
+	Locale                string     `gorm:"size:5" json:"locale"`          // Of the email, EMAIL_DEFAULT_LOCALE when empty
+	IsActive              bool       `gorm:"not null" json:"is_active"`
+	NextRunAt             *time.Time `gorm:"index:idx_report_schedule_due" json:"next_run_at"` // Empty while inactive
+	LastRunAt             *time.Time `json:"last_run_at"`
+	LastRunStatus         string     `gorm:"size:20" json:"last_run_status"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+
+	Department *Department `gorm:"foreignKey:DepartmentID" json:"-"`
+}
+
+func (ReportSchedule) TableName() string {
+	return "report_schedules"
+}
+
+// RecipientList splits the stored recipients
+func (s *ReportSchedule) RecipientList() []string {
+	if s.Recipients == "" {
+		return []string{}
+	}
+	return strings.Split(s.Recipients, ",")
+}
+
+func (s *ReportSchedule) ToResponse() ReportScheduleResponse {
+	return ReportScheduleResponse{
+		ID:                    s.ID,
+		Name:                  s.Name,
+		ReportType:            s.ReportType,
+		DepartmentID:          s.DepartmentID,
+		IncludeSubDepartments: s.IncludeSubDepartments,
+		Period:                s.Period,
+		Format:                s.Format,
+		Cron:                  s.Cron,
+		Recipients:            s.RecipientList(),
+		Locale:                s.Locale,
+		IsActive:              s.IsActive,
+		NextRunAt:             s.NextRunAt,
+		LastRunAt:             s.LastRunAt,
+		LastRunStatus:         s.LastRunStatus,
+		CreatedAt:             s.CreatedAt,
+		UpdatedAt:             s.UpdatedAt,
+	}
+}
+
+type ReportScheduleRequest struct {
+	Name                  string   `json:"name" binding:"required,max=100"`
+	ReportType            string   `json:"report_type" binding:"required,oneof=attendance summary department"`
+	DepartmentID          *uint    `json:"department_id"`
+	IncludeSubDepartments bool     `json:"include_sub_departments"`
+	Period                string   `json:"period" binding:"required"`
+	Format                string   `json:"format" binding:"required,oneof=xlsx csv pdf"`
+	Cron                  string   `json:"cron" binding:"required,max=100"` // Five fields or @daily, @weekly, @monthly
+	Recipients            []string `json:"recipients" binding:"required,min=1,dive,email"`
+	Locale                string   `json:"locale" binding:"omitempty,oneof=en id"`
+	IsActive              *bool    `json:"is_active"` // Defaults to true
+}
+
+type ReportScheduleResponse struct {
+	ID                    uint       `json:"id"`
+	Name                  string     `json:"name"`
+	ReportType            string     `json:"report_type"`
+	DepartmentID          *uint      `json:"department_id"`
+	IncludeSubDepartments bool       `json:"include_sub_departments"`
+	Period                string     `json:"period"`
+	Format                string     `json:"format"`
+	Cron                  string     `json:"cron"`
+	Recipients            []string   `json:"recipients"`
+	Locale                string     `json:"locale"`
+	IsActive              bool       `json:"is_active"`
+	NextRunAt             *time.Time `json:"next_run_at"`
+	LastRunAt             *time.Time `json:"last_run_at"`
+	LastRunStatus         string     `json:"last_run_status"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+}
+
+// ReportScheduleRun is one generation of a scheduled report, kept as its run history
+type ReportScheduleRun struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	ScheduleID  uint       `gorm:"not null;index:idx_report_run_schedule" json:"schedule_id"`
+	TriggeredBy string     `gorm:"size:20;not null" json:"triggered_by"`
+	Status      string     `gorm:"size:20;not null" json:"status"`
+	PeriodStart time.Time  `gorm:"type:date;not null" json:"period_start"`
+	PeriodEnd   time.Time  `gorm:"type:date;not null" json:"period_end"`
+	Filename    string     `gorm:"size:255" json:"filename"`
+	FileSize    int64      `json:"file_size"`  // Bytes
+	Recipients  int        `json:"recipients"` // Emails queued
+	Error       string     `gorm:"size:500" json:"error"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+}
+
+func (ReportScheduleRun) TableName() string {
+	return "report_schedule_runs"
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// RevokedToken records an access token ID (jti) that must be rejected until the
+// token would have expired anyway, after which the entry is pruned
+type RevokedToken struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	JTI       string    `gorm:"column:jti;size:64;not null;uniqueIndex" json:"jti"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SignOutRequest struct {
+	Before *time.Time `json:"before"` // Defaults to now
+}
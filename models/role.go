@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+)
+
+// Permission codes checked by the API. Roles are granted any subset of these.
+const (
+	PermissionEmployeesView         = "employees.view"
+	PermissionEmployeesViewAll      = "employees.view_all"
+	PermissionEmployeesManage       = "employees.manage"
+	PermissionDepartmentsView       = "departments.view"
+	PermissionDepartmentsManage     = "departments.manage"
+	PermissionAttendanceRecord      = "attendance.record"
+	PermissionAttendanceViewAll     = "attendance.view_all"
+	PermissionReportsView           = "reports.view"
+	PermissionReportsExport         = "reports.export"
+	PermissionReportSchedulesManage = "report_schedules.manage"
+	PermissionUsersManage           = "users.manage"
+	PermissionRolesManage           = "roles.manage"
+	PermissionServiceAccountsManage = "service_accounts.manage"
+	PermissionAuditView             = "audit.view"
+	PermissionEmailsManage          = "emails.manage"
+	PermissionAlertsManage          = "alerts.manage"
+	PermissionAnnouncementsManage   = "announcements.manage"
+	PermissionWebhooksManage        = "webhooks.manage"
+	PermissionDashboardAdmin        = "dashboard.admin"
+	PermissionDashboardManager      = "dashboard.manager"
+)
+
+type Permission struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Code        string    `gorm:"size:100;not null;uniqueIndex" json:"code"`
+	Description string    `gorm:"size:255" json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Role struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"size:50;not null;uniqueIndex" json:"name"`
+	Description string    `gorm:"size:255" json:"description"`
+	IsSystem    bool      `gorm:"default:false" json:"is_system"`                      // Built-in roles cannot be renamed or deleted
+	RequireMFA  bool      `gorm:"column:require_mfa;default:false" json:"require_mfa"` // Members must enroll in 2FA
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	Permissions []Permission `gorm:"many2many:role_permissions" json:"permissions,omitempty"`
+}
+
+type RoleRequest struct {
+	Name        string   `json:"name" binding:"required,min=2,max=50"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+	RequireMFA  bool     `json:"require_mfa"`
+}
+
+type AssignRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type RoleResponse struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	IsSystem    bool      `json:"is_system"`
+	RequireMFA  bool      `json:"require_mfa"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PermissionCodes returns the codes of the permissions granted to the role
+func (r *Role) PermissionCodes() []string {
+	codes := []string{}
+	for _, permission := range r.Permissions {
+		codes = append(codes, permission.Code)
+	}
+	return codes
+}
+
+func (r *Role) ToResponse() RoleResponse {
+	return RoleResponse{
+		ID:          r.ID,
+		Name:        r.Name,
+		Description: r.Description,
+		IsSystem:    r.IsSystem,
+		RequireMFA:  r.RequireMFA,
+		Permissions: r.PermissionCodes(),
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+	}
+}
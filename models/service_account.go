@@ -0,0 +1,179 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// API key scopes. Each scope grants a fixed set of permissions, so a key can never
+// do more than the routes its scopes were declared for.
+const (
+	ScopeAttendanceRead = "attendance:read"
+	ScopeEmployeesRead  = "employees:read"
+	ScopeReportsRead    = "reports:read"
+	ScopeReportsExport  = "reports:export"
+)
+
+// ScopePermissions maps each API key scope to the permissions it grants
+var ScopePermissions = map[string][]string{
+	// Attendance routes check employees.view_all to read another employee's records
+	ScopeAttendanceRead: {PermissionAttendanceViewAll, PermissionEmployeesViewAll},
+	ScopeEmployeesRead:  {PermissionEmployeesView, PermissionEmployeesViewAll, PermissionDepartmentsView},
+	ScopeReportsRead:    {PermissionReportsView},
+	ScopeReportsExport:  {PermissionReportsView, PermissionReportsExport},
+}
+
+// ServiceAccount is a non-human identity used by system integrations such as payroll
+type ServiceAccount struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"size:100;not null;uniqueIndex" json:"name"`
+	Description string    `gorm:"size:255" json:"description"`
+	IsActive    bool      `gorm:"default:true" json:"is_active"`
+	CreatedBy   *uint     `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	APIKeys []APIKey `gorm:"foreignKey:ServiceAccountID" json:"api_keys,omitempty"`
+}
+
+func (ServiceAccount) TableName() string {
+	return "service_accounts"
+}
+
+// APIKey authenticates a service account. The key is shown once on creation; only
+// its prefix (to find it) and SHA-256 (to check it) are stored.
+type APIKey struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	ServiceAccountID uint       `gorm:"not null;index" json:"service_account_id"`
+	Name             string     `gorm:"size:100;not null" json:"name"`
+	Prefix           string     `gorm:"size:20;not null;uniqueIndex" json:"prefix"`
+	KeyHash          string     `gorm:"size:64;not null" json:"-"`
+	Scopes           string     `gorm:"size:255;not null" json:"-"` // Comma-separated scope list
+	ExpiresAt        time.Time  `gorm:"not null" json:"expires_at"`
+	LastUsedAt       *time.Time `json:"last_used_at"`
+	LastUsedIP       string     `gorm:"size:45" json:"last_used_ip"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+
+	ServiceAccount *ServiceAccount `gorm:"foreignKey:ServiceAccountID" json:"-"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+type ServiceAccountRequest struct {
+	Name        string `json:"name" binding:"required,min=2,max=100"`
+	Description string `json:"description" binding:"max=255"`
+	IsActive    *bool  `json:"is_active"`
+}
+
+type APIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"` // Defaults to one year from now
+}
+
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatedAPIKeyResponse carries the plain key, which is never shown again
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+type ServiceAccountResponse struct {
+	ID          uint             `json:"id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	IsActive    bool             `json:"is_active"`
+	CreatedBy   *uint            `json:"created_by"`
+	APIKeys     []APIKeyResponse `json:"api_keys"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+type APIScopeResponse struct {
+	Scope       string   `json:"scope"`
+	Permissions []string `json:"permissions"`
+}
+
+// ScopeList splits the stored scopes
+func (k *APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+// Permissions resolves the permissions granted by the key's scopes
+func (k *APIKey) Permissions() []string {
+	seen := map[string]bool{}
+	permissions := []string{}
+	for _, scope := range k.ScopeList() {
+		for _, permission := range ScopePermissions[scope] {
+			if !seen[permission] {
+				seen[permission] = true
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+	return permissions
+}
+
+// IsUsable checks the key is not revoked or expired
+func (k *APIKey) IsUsable() bool {
+	return k.RevokedAt == nil && k.ExpiresAt.After(time.Now())
+}
+
+func (k *APIKey) ToResponse() APIKeyResponse {
+	return APIKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.ScopeList(),
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		LastUsedIP: k.LastUsedIP,
+		RevokedAt:  k.RevokedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
+
+func (a *ServiceAccount) ToResponse() ServiceAccountResponse {
+	keys := []APIKeyResponse{}
+	for _, key := range a.APIKeys {
+		keys = append(keys, key.ToResponse())
+	}
+	return ServiceAccountResponse{
+		ID:          a.ID,
+		Name:        a.Name,
+		Description: a.Description,
+		IsActive:    a.IsActive,
+		CreatedBy:   a.CreatedBy,
+		APIKeys:     keys,
+		CreatedAt:   a.CreatedAt,
+		UpdatedAt:   a.UpdatedAt,
+	}
+}
+
+// AvailableScopes lists every scope with the permissions it grants, sorted by name
+func AvailableScopes() []APIScopeResponse {
+	scopes := []APIScopeResponse{}
+	for scope, permissions := range ScopePermissions {
+		scopes = append(scopes, APIScopeResponse{Scope: scope, Permissions: permissions})
+	}
+	sort.Slice(scopes, func(i, j int) bool { return scopes[i].Scope < scopes[j].Scope })
+	return scopes
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+)
+
+// UserSession is one signed-in device. Its SessionID is the refresh token family
+// started at login and is carried in the sid claim of every access token.
+type UserSession struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	SessionID  string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	DeviceName string     `gorm:"size:100" json:"device_name"`
+	UserAgent  string     `gorm:"size:255" json:"user_agent"`
+	IPAddress  string     `gorm:"size:45" json:"ip_address"`
+	LastSeenAt time.Time  `gorm:"not null" json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
+
+// SessionClient describes the device a login or refresh comes from
+type SessionClient struct {
+	DeviceName string
+	UserAgent  string
+	IPAddress  string
+}
+
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	DeviceName string    `json:"device_name"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"` // The session the request was made with
+}
+
+func (s *UserSession) ToResponse(currentSessionID string) SessionResponse {
+	return SessionResponse{
+		ID:         s.ID,
+		DeviceName: s.DeviceName,
+		UserAgent:  s.UserAgent,
+		IPAddress:  s.IPAddress,
+		CreatedAt:  s.CreatedAt,
+		LastSeenAt: s.LastSeenAt,
+		Current:    currentSessionID != "" && s.SessionID == currentSessionID,
+	}
+}
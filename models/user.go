@@ -9,19 +9,54 @@ import (
 	"gorm.io/gorm"
 )
 
+// Languages emails can be sent in
+const (
+	LocaleEnglish    = "en"
+	LocaleIndonesian = "id"
+)
+
+var SupportedLocales = []string{LocaleEnglish, LocaleIndonesian}
+
+// IsSupportedLocale reports whether emails can be sent in the given language
+func IsSupportedLocale(locale string) bool {
+	for _, supported := range SupportedLocales {
+		if locale == supported {
+			return true
+		}
+	}
+	return false
+}
+
 type User struct {
-	ID           uint       `gorm:"primaryKey" json:"id"`
-	Username     string     `gorm:"uniqueIndex;size:100;not null" json:"username"`
-	Email        string     `gorm:"uniqueIndex;size:255;not null" json:"email"`
-	Password     string     `gorm:"size:255" json:"-"` // Allow empty for setup tokens
-	Role         string     `gorm:"size:50;default:employee" json:"role"`
-	EmployeeID   *string    `gorm:"size:50;index" json:"employee_id"`
-	IsActive     bool       `gorm:"default:false" json:"is_active"` // False until setup complete
-	SetupToken   *string    `gorm:"size:64;uniqueIndex" json:"-"`
-	TokenExpires *time.Time `json:"-"`
-	LastLogin    *time.Time `json:"last_login"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Username   string     `gorm:"uniqueIndex;size:100;not null" json:"username"`
+	Email      string     `gorm:"uniqueIndex;size:255;not null" json:"email"`
+	Password   string     `gorm:"size:255" json:"-"` // Allow empty for setup tokens
+	Role       string     `gorm:"size:50;default:employee" json:"role"`
+	EmployeeID *string    `gorm:"size:50;index" json:"employee_id"`
+	IsActive   bool       `gorm:"default:false" json:"is_active"` // False until setup complete
+	LastLogin  *time.Time `json:"last_login"`
+	// Set once the user proves they own the address (setup link or email-verify token)
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	// Brute-force protection: consecutive failed logins and temporary lockout
+	FailedLoginAttempts int        `gorm:"default:0" json:"-"`
+	LastFailedLoginAt   *time.Time `json:"-"`
+	LockedUntil         *time.Time `json:"-"`
+	// TOTP two-factor authentication; the secret is encrypted at rest
+	MFAEnabled      bool       `gorm:"column:mfa_enabled;default:false" json:"mfa_enabled"`
+	MFASecret       string     `gorm:"column:mfa_secret;size:255" json:"-"`
+	MFAEnabledAt    *time.Time `gorm:"column:mfa_enabled_at" json:"-"`
+	MFALastUsedStep *int64     `gorm:"column:mfa_last_used_step" json:"-"` // Stops a code being replayed
+	// Subject of the identity provider account linked through OIDC single sign-on
+	OIDCSubject *string `gorm:"column:oidc_subject;size:255;uniqueIndex" json:"-"`
+	// Compared against PASSWORD_MAX_AGE_DAYS; falls back to CreatedAt when unset
+	PasswordChangedAt *time.Time `json:"password_changed_at"`
+	// Language of the user's emails; EMAIL_DEFAULT_LOCALE when empty
+	Locale string `gorm:"size:10" json:"locale"`
+	// Access tokens issued at or before this time are rejected ("sign out everywhere")
+	TokensValidAfter *time.Time `json:"-"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
 
 	Employee *Employee `gorm:"foreignKey:EmployeeID;references:EmployeeID" json:"employee,omitempty"`
 }
@@ -29,14 +64,14 @@ type User struct {
 type UserRequest struct {
 	Username   string `json:"username" binding:"required,min=3,max=100"`
 	Email      string `json:"email" binding:"required,email"`
-	Password   string `json:"password" binding:"required,min=6"`
-	Role       string `json:"role" binding:"required,oneof=admin manager employee"`
+	Password   string `json:"password" binding:"required"` // Checked against the password policy
+	Role       string `json:"role" binding:"required"`
 	EmployeeID string `json:"employee_id"`
 }
 
 type UserSetupRequest struct {
 	Token       string `json:"token" binding:"required"`
-	NewPassword string `json:"new_password" binding:"required,min=8"`
+	NewPassword string `json:"new_password" binding:"required"` // Checked against the password policy
 }
 
 type UserSetupResponse struct {
@@ -45,29 +80,38 @@ type UserSetupResponse struct {
 }
 
 type LoginRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Username   string `json:"username" binding:"required"`
+	Password   string `json:"password" binding:"required"`
+	DeviceName string `json:"device_name" binding:"omitempty,max=100"` // Shown in the session list, guessed from the user agent if empty
 }
 
 type LoginResponse struct {
-	User         UserResponse `json:"user"`
+	User         UserResponse `json:"user,omitzero"` // Left out until every factor has passed
 	AccessToken  string       `json:"access_token"`
 	RefreshToken string       `json:"refresh_token,omitempty"`
 	TokenType    string       `json:"token_type"`
 	ExpiresIn    int64        `json:"expires_in"`
+	// Set instead of the tokens above when a second factor is needed (POST /auth/mfa/verify)
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+	// Set when the access token may only be used to finish a required step, e.g. 2FA enrollment
+	Restriction string `json:"restriction,omitempty"`
 }
 
 type UserResponse struct {
-	ID         uint       `json:"id"`
-	Username   string     `json:"username"`
-	Email      string     `json:"email"`
-	Role       string     `json:"role"`
-	EmployeeID *string    `json:"employee_id"`
-	IsActive   bool       `json:"is_active"`
-	LastLogin  *time.Time `json:"last_login"`
-	CreatedAt  time.Time  `json:"created_at"`
-	UpdatedAt  time.Time  `json:"updated_at"`
-	Employee   *EmployeeResponse `json:"employee,omitempty"`
+	ID              uint              `json:"id"`
+	Username        string            `json:"username"`
+	Email           string            `json:"email"`
+	Role            string            `json:"role"`
+	EmployeeID      *string           `json:"employee_id"`
+	IsActive        bool              `json:"is_active"`
+	LastLogin       *time.Time        `json:"last_login"`
+	EmailVerifiedAt *time.Time        `json:"email_verified_at"`
+	MFAEnabled      bool              `json:"mfa_enabled"`
+	Locale          string            `json:"locale"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+	Employee        *EmployeeResponse `json:"employee,omitempty"`
 }
 
 type CreateUserResponse struct {
@@ -124,15 +168,18 @@ func (u *User) BeforeUpdate(tx *gorm.DB) error {
 // ToResponse converts User to UserResponse
 func (u *User) ToResponse() UserResponse {
 	userResp := UserResponse{
-		ID:         u.ID,
-		Username:   u.Username,
-		Email:      u.Email,
-		Role:       u.Role,
-		EmployeeID: u.EmployeeID,
-		IsActive:   u.IsActive,
-		LastLogin:  u.LastLogin,
-		CreatedAt:  u.CreatedAt,
-		UpdatedAt:  u.UpdatedAt,
+		ID:              u.ID,
+		Username:        u.Username,
+		Email:           u.Email,
+		Role:            u.Role,
+		EmployeeID:      u.EmployeeID,
+		IsActive:        u.IsActive,
+		LastLogin:       u.LastLogin,
+		EmailVerifiedAt: u.EmailVerifiedAt,
+		MFAEnabled:      u.MFAEnabled,
+		Locale:          u.Locale,
+		CreatedAt:       u.CreatedAt,
+		UpdatedAt:       u.UpdatedAt,
 	}
 
 	if u.Employee != nil {
@@ -143,29 +190,20 @@ func (u *User) ToResponse() UserResponse {
 	return userResp
 }
 
-// IsSetupTokenValid checks if the setup token is valid and not expired
-func (u *User) IsSetupTokenValid() bool {
-	if u.SetupToken == nil || u.TokenExpires == nil {
-		return false
-	}
-	return u.TokenExpires.After(time.Now())
-}
-
-// ActivateUser activates the user account and clears setup token
+// ActivateUser activates the user account
 func (u *User) ActivateUser() {
 	u.IsActive = true
-	u.SetupToken = nil
-	u.TokenExpires = nil
 	u.UpdatedAt = time.Now()
 }
 
-// SetSetupToken sets a new setup token with expiration
-func (u *User) SetSetupToken(token string, expiresIn time.Duration) {
-	u.SetupToken = &token
-	expiry := time.Now().Add(expiresIn)
-	u.TokenExpires = &expiry
-	u.IsActive = false
-	u.UpdatedAt = time.Now()
+// IsLocked checks if the account is temporarily locked after too many failed logins
+func (u *User) IsLocked() bool {
+	return u.LockedUntil != nil && u.LockedUntil.After(time.Now())
+}
+
+// LockExpired checks if the account was locked and the lock has since run out
+func (u *User) LockExpired() bool {
+	return u.LockedUntil != nil && !u.LockedUntil.After(time.Now())
 }
 
 // CanLogin checks if user can login (active and has password)
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Webhook event types
+const (
+	WebhookEventClockIn               = "attendance.clock_in"
+	WebhookEventClockOut              = "attendance.clock_out"
+	WebhookEventEmployeeCreated       = "employee.created"
+	WebhookEventEmployeeStatusChanged = "employee.status_changed"
+	WebhookEventPing                  = "webhook.ping" // Sent on request only, never subscribed to
+)
+
+// WebhookEventTypes are the events a webhook can subscribe to
+var WebhookEventTypes = []string{
+	WebhookEventClockIn,
+	WebhookEventClockOut,
+	WebhookEventEmployeeCreated,
+	WebhookEventEmployeeStatusChanged,
+}
+
+// Delivery states of a webhook delivery
+const (
+	WebhookDeliveryPending   = "pending" // Waiting for its first or next attempt
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryDead      = "dead" // Gave up after the maximum number of attempts
+)
+
+// Webhook is a subscription of an external system to events. The secret signs every
+// delivery; it is stored encrypted and only shown when it is set.
+type Webhook struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"size:100;not null" json:"name"`
+	URL       string    `gorm:"size:500;not null" json:"url"`
+	Secret    string    `gorm:"size:255;not null" json:"-"` // Encrypted
+	Events    string    `gorm:"size:255;not null" json:"-"` // Comma-separated event types
+	IsActive  bool      `gorm:"not null" json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// EventList splits the stored event types
+func (w *Webhook) EventList() []string {
+	if w.Events == "" {
+		return []string{}
+	}
+	return strings.Split(w.Events, ",")
+}
+
+// Subscribes reports whether the webhook receives the event type
+func (w *Webhook) Subscribes(eventType string) bool {
+	for _, event := range w.EventList() {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Webhook) ToResponse() WebhookResponse {
+	return WebhookResponse{
+		ID:        w.ID,
+		Name:      w.Name,
+		URL:       w.URL,
+		Events:    w.EventList(),
+		IsActive:  w.IsActive,
+		CreatedAt: w.CreatedAt,
+		UpdatedAt: w.UpdatedAt,
+	}
+}
+
+type WebhookRequest struct {
+	Name     string   `json:"name" binding:"required,max=100"`
+	URL      string   `json:"url" binding:"required,url,max=500"`
+	Events   []string `json:"events" binding:"required,min=1"`
+	IsActive *bool    `json:"is_active"` // Defaults to true
+	// Replaces the signing secret. One is generated when a webhook is created without it.
+	Secret string `json:"secret" binding:"omitempty,min=16,max=128"`
+}
+
+type WebhookResponse struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookSecretResponse carries the signing secret, which is not shown again
+type WebhookSecretResponse struct {
+	WebhookResponse
+	Secret string `json:"secret"`
+}
+
+// WebhookDelivery is one event queued for, or delivered to, one webhook. The delivery
+// log keeps the payload and the outcome of the last attempt.
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	WebhookID      uint       `gorm:"not null;index:idx_webhook_delivery_webhook" json:"webhook_id"`
+	EventID        string     `gorm:"size:40;not null" json:"event_id"`
+	EventType      string     `gorm:"size:50;not null" json:"event_type"`
+	Payload        string     `gorm:"type:mediumtext" json:"payload"`
+	Status         string     `gorm:"size:20;not null;index:idx_webhook_delivery_due" json:"status"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"not null;index:idx_webhook_delivery_due" json:"next_attempt_at"`
+	ResponseStatus int        `json:"response_status"` // HTTP status of the last attempt, 0 if there was no response
+	LastError      string     `gorm:"size:500" json:"last_error"`
+	DurationMs     int64      `json:"duration_ms"` // How long the last attempt took
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	Webhook *Webhook `gorm:"foreignKey:WebhookID" json:"-"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// WebhookDeliveryFilter narrows a delivery log query; empty fields are ignored
+type WebhookDeliveryFilter struct {
+	EventType string `form:"event_type"`
+	Status    string `form:"status"`
+}
+
+// WebhookEvent is the JSON body of every delivery
+type WebhookEvent struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookAttendanceData is the data of attendance events
+type WebhookAttendanceData struct {
+	AttendanceID string     `json:"attendance_id"`
+	EmployeeID   string     `json:"employee_id"`
+	ClockIn      time.Time  `json:"clock_in"`
+	ClockOut     *time.Time `json:"clock_out"`
+	WorkHours    *float64   `json:"work_hours"`
+	Status       string     `json:"status"`
+}
+
+// WebhookEmployeeData is the data of employee events
+type WebhookEmployeeData struct {
+	EmployeeID     string  `json:"employee_id"`
+	Name           string  `json:"name"`
+	DepartmentID   uint    `json:"department_id"`
+	ManagerID      *string `json:"manager_id"`
+	Position       string  `json:"position"`
+	Status         string  `json:"status"`
+	PreviousStatus string  `json:"previous_status,omitempty"` // For status changes
+}
@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"attendance-system/models"
+	"attendance-system/utils"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AlertRuleRepository struct {
+	BaseRepository
+}
+
+func NewAlertRuleRepository() *AlertRuleRepository {
+	return &AlertRuleRepository{
+		BaseRepository: *NewBaseRepository(),
+	}
+}
+
+func (r *AlertRuleRepository) Create(rule *models.AlertRule) error {
+	if err := r.DB.Create(rule).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+func (r *AlertRuleRepository) FindAll() ([]models.AlertRule, error) {
+	var rules []models.AlertRule
+	if err := r.DB.Order("type, id").Find(&rules).Error; err != nil {
+		return nil, r.HandleError(err)
+	}
+	return rules, nil
+}
+
+func (r *AlertRuleRepository) FindByID(id uint) (*models.AlertRule, error) {
+	var rule models.AlertRule
+	err := r.DB.First(&rule, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("alert rule not found")
+		}
+		return nil, r.HandleError(err)
+	}
+	return &rule, nil
+}
+
+// FindActiveByType returns the active rules of the given type
+func (r *AlertRuleRepository) FindActiveByType(ruleType string) ([]models.AlertRule, error) {
+	var rules []models.AlertRule
+	if err := r.DB.Where("type = ? AND is_active = ?", ruleType, true).Find(&rules).Error; err != nil {
+		return nil, r.HandleError(err)
+	}
+	return rules, nil
+}
+
+func (r *AlertRuleRepository) Update(rule *models.AlertRule) error {
+	if err := r.DB.Save(rule).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+func (r *AlertRuleRepository) Delete(id uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("rule_id = ?", id).Delete(&models.AlertEvent{}).Error; err != nil {
+			return r.HandleError(err)
+		}
+		if err := tx.Delete(&models.AlertRule{}, id).Error; err != nil {
+			return r.HandleError(err)
+		}
+		return nil
+	})
+}
+
+// ClaimEvent records that the rule fired for the employee on the date. It reports false
+// if it already had, so each rule alerts once per employee per day even with several
+// servers sweeping.
+func (r *AlertRuleRepository) ClaimEvent(ruleID uint, employeeID string, date time.Time) (bool, error) {
+	event := &models.AlertEvent{
+		RuleID:     ruleID,
+		EmployeeID: employeeID,
+		EventDate:  date,
+		CreatedAt:  time.Now(),
+	}
+	result := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(event)
+	if result.Error != nil {
+		return false, r.HandleError(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// DeleteEventsBefore removes alert events for days before the cutoff
+func (r *AlertRuleRepository) DeleteEventsBefore(cutoff time.Time) error {
+	if err := r.DB.Where("event_date < ?", cutoff).Delete(&models.AlertEvent{}).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
@@ -3,6 +3,8 @@ package repositories
 import (
 	"attendance-system/models"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type AttendanceRepository struct {
@@ -35,6 +37,31 @@ func (r *AttendanceRepository) FindTodayAttendance(employeeID string) (*models.A
 	return &attendance, nil
 }
 
+// FindOpenOn returns the attendances clocked in on the date that haven't been clocked out
+func (r *AttendanceRepository) FindOpenOn(date string) ([]models.Attendance, error) {
+	var attendances []models.Attendance
+	err := r.DB.Preload("Employee.Department").
+		Where("DATE(clock_in) = ? AND clock_out IS NULL", date).
+		Find(&attendances).Error
+	if err != nil {
+		return nil, r.HandleError(err)
+	}
+	return attendances, nil
+}
+
+// FindEmployeeIDsClockedInOn returns the IDs of employees who clocked in on the date
+func (r *AttendanceRepository) FindEmployeeIDsClockedInOn(date string) ([]string, error) {
+	var employeeIDs []string
+	err := r.DB.Model(&models.Attendance{}).
+		Where("DATE(clock_in) = ?", date).
+		Distinct().
+		Pluck("employee_id", &employeeIDs).Error
+	if err != nil {
+		return nil, r.HandleError(err)
+	}
+	return employeeIDs, nil
+}
+
 func (r *AttendanceRepository) UpdateAttendance(attendance *models.Attendance) error {
 	if err := r.DB.Save(attendance).Error; err != nil {
 		return r.HandleError(err)
@@ -49,34 +76,127 @@ func (r *AttendanceRepository) CreateAttendanceHistory(history *models.Attendanc
 	return nil
 }
 
-func (r *AttendanceRepository) GetAttendanceLogs(startDate, endDate string, departmentID uint, employeeID string, page, limit int) ([]models.Attendance, *Pagination, error) {
+// GetAttendanceLogs lists attendance records, optionally restricted to a set of departments
+func (r *AttendanceRepository) GetAttendanceLogs(startDate, endDate string, departmentIDs []uint, employeeID string, page, limit int) ([]models.Attendance, *Pagination, error) {
 	var attendances []models.Attendance
 	
-	query := r.DB.Preload("Employee.Department")
+	query := r.attendanceLogQuery(startDate, endDate, departmentIDs, employeeID).Preload("Employee.Department")
+	
+	pagination, err := r.Paginate(query.Order("clock_in DESC"), page, limit, &attendances)
+	if err != nil {
+		return nil, nil, r.HandleError(err)
+	}
 	
+	return attendances, pagination, nil
+}
+
+// EachAttendanceLog walks every attendance record GetAttendanceLogs would list, newest
+// first, handing them to fn a batch at a time. Batches continue from the last record
+// seen rather than an offset, so later batches cost no more than the first.
+func (r *AttendanceRepository) EachAttendanceLog(startDate, endDate string, departmentIDs []uint, batchSize int, fn func([]models.Attendance) error) error {
+	var lastClockIn time.Time
+	var lastID uint
+	for {
+		query := r.attendanceLogQuery(startDate, endDate, departmentIDs, "").Preload("Employee.Department")
+		if lastID != 0 {
+			query = query.Where("(attendances.clock_in < ? OR (attendances.clock_in = ? AND attendances.id < ?))", lastClockIn, lastClockIn, lastID)
+		}
+
+		var attendances []models.Attendance
+		err := query.Order("attendances.clock_in DESC, attendances.id DESC").
+			Limit(batchSize).
+			Find(&attendances).Error
+		if err != nil {
+			return r.HandleError(err)
+		}
+		if len(attendances) == 0 {
+			return nil
+		}
+		if err := fn(attendances); err != nil {
+			return err
+		}
+		if len(attendances) < batchSize {
+			return nil
+		}
+
+		last := attendances[len(attendances)-1]
+		lastClockIn, lastID = last.ClockIn, last.ID
+	}
+}
+
+// attendanceLogQuery filters attendance records by clock-in date, department and employee
+func (r *AttendanceRepository) attendanceLogQuery(startDate, endDate string, departmentIDs []uint, employeeID string) *gorm.DB {
+	query := r.DB.Model(&models.Attendance{})
+
 	if startDate != "" && endDate != "" {
-		query = query.Where("DATE(clock_in) BETWEEN ? AND ?", startDate, endDate)
+		query = query.Where("DATE(attendances.clock_in) BETWEEN ? AND ?", startDate, endDate)
 	} else if startDate != "" {
-		query = query.Where("DATE(clock_in) >= ?", startDate)
+		query = query.Where("DATE(attendances.clock_in) >= ?", startDate)
 	} else if endDate != "" {
-		query = query.Where("DATE(clock_in) <= ?", endDate)
+		query = query.Where("DATE(attendances.clock_in) <= ?", endDate)
 	}
-	
-	if departmentID > 0 {
+
+	if len(departmentIDs) > 0 {
 		query = query.Joins("JOIN employees ON attendances.employee_id = employees.employee_id").
-			Where("employees.department_id = ?", departmentID)
+			Where("employees.department_id IN ?", departmentIDs)
 	}
-	
+
 	if employeeID != "" {
 		query = query.Where("attendances.employee_id = ?", employeeID)
 	}
-	
-	pagination, err := r.Paginate(query.Order("clock_in DESC"), page, limit, &attendances)
+	return query
+}
+
+// AttendanceTotals holds attendance aggregates for a period
+type AttendanceTotals struct {
+	TotalPresent   int64
+	TotalLate      int64
+	TotalWorkHours float64
+}
+
+// GetAttendanceTotals aggregates the attendance records GetAttendanceLogs would list
+// for a period, optionally restricted to a set of departments
+func (r *AttendanceRepository) GetAttendanceTotals(startDate, endDate string, departmentIDs []uint) (*AttendanceTotals, error) {
+	var totals AttendanceTotals
+	err := r.attendanceLogQuery(startDate, endDate, departmentIDs, "").
+		Select("COUNT(*) as total_present, " +
+			"COALESCE(SUM(CASE WHEN attendances.status = 'late' THEN 1 ELSE 0 END), 0) as total_late, " +
+			"COALESCE(SUM(attendances.work_hours), 0) as total_work_hours").
+		Scan(&totals).Error
 	if err != nil {
-		return nil, nil, r.HandleError(err)
+		return nil, r.HandleError(err)
 	}
-	
-	return attendances, pagination, nil
+	return &totals, nil
+}
+
+// DepartmentAttendanceTotals holds attendance aggregates for a single department
+type DepartmentAttendanceTotals struct {
+	DepartmentID   uint
+	TotalPresent   int64
+	TotalLate      int64
+	TotalWorkHours float64
+}
+
+// GetDepartmentAttendanceTotals aggregates attendance per department for a period
+func (r *AttendanceRepository) GetDepartmentAttendanceTotals(startDate, endDate string) (map[uint]DepartmentAttendanceTotals, error) {
+	var rows []DepartmentAttendanceTotals
+	err := r.DB.Table("attendances").
+		Select("employees.department_id, COUNT(*) as total_present, " +
+			"SUM(CASE WHEN attendances.status = 'late' THEN 1 ELSE 0 END) as total_late, " +
+			"COALESCE(SUM(attendances.work_hours), 0) as total_work_hours").
+		Joins("JOIN employees ON attendances.employee_id = employees.employee_id").
+		Where("DATE(attendances.clock_in) BETWEEN ? AND ?", startDate, endDate).
+		Group("employees.department_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, r.HandleError(err)
+	}
+
+	totals := make(map[uint]DepartmentAttendanceTotals, len(rows))
+	for _, row := range rows {
+		totals[row.DepartmentID] = row
+	}
+	return totals, nil
 }
 
 func (r *AttendanceRepository) GetEmployeeAttendance(employeeID string, startDate, endDate string) ([]models.Attendance, error) {
@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"attendance-system/models"
+
+	"gorm.io/gorm"
+)
+
+type AuditLogRepository struct {
+	BaseRepository
+}
+
+func NewAuditLogRepository() *AuditLogRepository {
+	return &AuditLogRepository{
+		BaseRepository: *NewBaseRepository(),
+	}
+}
+
+func (r *AuditLogRepository) Create(entry *models.AuditLog) error {
+	if err := r.DB.Create(entry).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// FindAll lists entries matching the filter, newest first
+func (r *AuditLogRepository) FindAll(filter models.AuditLogFilter, page, limit int) ([]models.AuditLog, *Pagination, error) {
+	var entries []models.AuditLog
+
+	pagination, err := r.Paginate(r.filtered(filter), page, limit, &entries)
+	if err != nil {
+		return nil, nil, r.HandleError(err)
+	}
+
+	return entries, pagination, nil
+}
+
+// FindForExport returns up to max entries matching the filter, newest first
+func (r *AuditLogRepository) FindForExport(filter models.AuditLogFilter, max int) ([]models.AuditLog, error) {
+	var entries []models.AuditLog
+	if err := r.filtered(filter).Limit(max).Find(&entries).Error; err != nil {
+		return nil, r.HandleError(err)
+	}
+	return entries, nil
+}
+
+func (r *AuditLogRepository) filtered(filter models.AuditLogFilter) *gorm.DB {
+	query := r.DB.Model(&models.AuditLog{})
+
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.StartDate != "" {
+		query = query.Where("DATE(created_at) >= ?", filter.StartDate)
+	}
+	if filter.EndDate != "" {
+		query = query.Where("DATE(created_at) <= ?", filter.EndDate)
+	}
+
+	return query.Order("created_at DESC, id DESC")
+}
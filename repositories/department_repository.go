@@ -67,6 +67,13 @@ func (r *DepartmentRepository) Delete(id uint) error {
 		return utils.NewConflictError("cannot delete department with existing employees")
 	}
 
+	// Check if department has sub-departments
+	var childCount int64
+	r.DB.Model(&models.Department{}).Where("parent_id = ?", id).Count(&childCount)
+	if childCount > 0 {
+		return utils.NewConflictError("cannot delete department with sub-departments")
+	}
+
 	result := r.DB.Delete(&models.Department{}, id)
 	if result.Error != nil {
 		return r.HandleError(result.Error)
@@ -96,4 +103,40 @@ func (r *DepartmentRepository) GetActiveDepartments() ([]models.Department, erro
 		return nil, r.HandleError(err)
 	}
 	return departments, nil
-}
\ No newline at end of file
+}
+
+// FindAllDepartments returns every department, used to build the hierarchy in memory
+func (r *DepartmentRepository) FindAllDepartments() ([]models.Department, error) {
+	var departments []models.Department
+	err := r.DB.Order("name").Find(&departments).Error
+	if err != nil {
+		return nil, r.HandleError(err)
+	}
+	return departments, nil
+}
+
+// GetDescendantIDs returns the given department ID followed by the IDs of all its sub-departments
+func (r *DepartmentRepository) GetDescendantIDs(id uint) ([]uint, error) {
+	ids := []uint{id}
+	seen := map[uint]bool{id: true}
+	frontier := []uint{id}
+
+	for len(frontier) > 0 {
+		var children []uint
+		err := r.DB.Model(&models.Department{}).Where("parent_id IN ?", frontier).Pluck("id", &children).Error
+		if err != nil {
+			return nil, r.HandleError(err)
+		}
+
+		frontier = frontier[:0]
+		for _, childID := range children {
+			if !seen[childID] {
+				seen[childID] = true
+				ids = append(ids, childID)
+				frontier = append(frontier, childID)
+			}
+		}
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"attendance-system/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type EmailOutboxRepository struct {
+	BaseRepository
+}
+
+func NewEmailOutboxRepository() *EmailOutboxRepository {
+	return &EmailOutboxRepository{
+		BaseRepository: *NewBaseRepository(),
+	}
+}
+
+func (r *EmailOutboxRepository) Create(email *models.OutboxEmail) error {
+	if err := r.DB.Create(email).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+func (r *EmailOutboxRepository) FindByID(id uint) (*models.OutboxEmail, error) {
+	var email models.OutboxEmail
+	if err := r.DB.First(&email, id).Error; err != nil {
+		return nil, r.HandleError(err)
+	}
+	return &email, nil
+}
+
+// FindAll lists emails matching the filter, newest first
+func (r *EmailOutboxRepository) FindAll(filter models.OutboxEmailFilter, page, limit int) ([]models.OutboxEmail, *Pagination, error) {
+	var emails []models.OutboxEmail
+
+	// Attachments can be large and are never returned, leave them out of listings
+	query := r.DB.Model(&models.OutboxEmail{}).Omit("attachment")
+	if filter.Recipient != "" {
+		query = query.Where("recipient = ?", filter.Recipient)
+	}
+	if filter.Kind != "" {
+		query = query.Where("kind = ?", filter.Kind)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	pagination, err := r.Paginate(query.Order("created_at DESC, id DESC"), page, limit, &emails)
+	if err != nil {
+		return nil, nil, r.HandleError(err)
+	}
+
+	return emails, pagination, nil
+}
+
+// FindDueIDs returns up to limit pending emails whose next attempt is due, oldest first
+func (r *EmailOutboxRepository) FindDueIDs(now time.Time, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.DB.Model(&models.OutboxEmail{}).
+		Where("status = ? AND next_attempt_at <= ?", models.EmailStatusPending, now).
+		Order("next_attempt_at, id").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, r.HandleError(err)
+	}
+	return ids, nil
+}
+
+// Claim takes a due email for an attempt by counting the attempt and pushing its next
+// attempt out to leaseUntil. It reports false if another worker claimed it first. A
+// worker that dies mid-send leaves the email to be retried once the lease runs out.
+func (r *EmailOutboxRepository) Claim(id uint, now, leaseUntil time.Time) (bool, error) {
+	result := r.DB.Model(&models.OutboxEmail{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", id, models.EmailStatusPending, now).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": leaseUntil,
+		})
+	if result.Error != nil {
+		return false, r.HandleError(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *EmailOutboxRepository) MarkSent(id uint, sentAt time.Time) error {
+	err := r.DB.Model(&models.OutboxEmail{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     models.EmailStatusSent,
+		"sent_at":    sentAt,
+		"last_error": "",
+	}).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// MarkFailed records a failed attempt, either scheduling the next one or marking the email dead
+func (r *EmailOutboxRepository) MarkFailed(id uint, status string, nextAttemptAt time.Time, lastError string) error {
+	err := r.DB.Model(&models.OutboxEmail{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          status,
+		"next_attempt_at": nextAttemptAt,
+		"last_error":      lastError,
+	}).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// Requeue puts an email back in the queue with a fresh set of attempts
+func (r *EmailOutboxRepository) Requeue(id uint, now time.Time) error {
+	err := r.DB.Model(&models.OutboxEmail{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          models.EmailStatusPending,
+		"attempts":        0,
+		"next_attempt_at": now,
+		"last_error":      "",
+		"sent_at":         nil,
+	}).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// DeleteFinishedBefore removes sent and dead emails last updated before the cutoff
+func (r *EmailOutboxRepository) DeleteFinishedBefore(cutoff time.Time) error {
+	err := r.DB.Where("status IN ? AND updated_at < ?", []string{models.EmailStatusSent, models.EmailStatusDead}, cutoff).
+		Delete(&models.OutboxEmail{}).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
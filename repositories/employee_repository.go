@@ -82,6 +82,36 @@ func (r *EmployeeRepository) FindByDepartment(departmentID uint) ([]models.Emplo
 	return employees, nil
 }
 
+// FindByDepartments returns employees in any of the given departments
+func (r *EmployeeRepository) FindByDepartments(departmentIDs []uint) ([]models.Employee, error) {
+	var employees []models.Employee
+	err := r.DB.Preload("Department").Where("department_id IN ?", departmentIDs).Order("name").Find(&employees).Error
+	if err != nil {
+		return nil, r.HandleError(err)
+	}
+	return employees, nil
+}
+
+// FindDirectReports returns employees whose direct manager is the given employee
+func (r *EmployeeRepository) FindDirectReports(managerID string) ([]models.Employee, error) {
+	var employees []models.Employee
+	err := r.DB.Preload("Department").Where("manager_id = ?", managerID).Order("name").Find(&employees).Error
+	if err != nil {
+		return nil, r.HandleError(err)
+	}
+	return employees, nil
+}
+
+// FindActive returns all active employees with their departments
+func (r *EmployeeRepository) FindActive() ([]models.Employee, error) {
+	var employees []models.Employee
+	err := r.DB.Preload("Department").Where("status = ?", "active").Find(&employees).Error
+	if err != nil {
+		return nil, r.HandleError(err)
+	}
+	return employees, nil
+}
+
 func (r *EmployeeRepository) Update(employee *models.Employee) error {
 	if err := r.DB.Save(employee).Error; err != nil {
 		return r.HandleError(err)
@@ -109,6 +139,40 @@ func (r *EmployeeRepository) GetActiveEmployeesCount() (int64, error) {
 	return count, nil
 }
 
+// CountActiveByDepartments counts active employees in any of the given departments
+func (r *EmployeeRepository) CountActiveByDepartments(departmentIDs []uint) (int64, error) {
+	var count int64
+	err := r.DB.Model(&models.Employee{}).
+		Where("status = ? AND department_id IN ?", "active", departmentIDs).
+		Count(&count).Error
+	if err != nil {
+		return 0, r.HandleError(err)
+	}
+	return count, nil
+}
+
+// CountActiveGroupedByDepartment returns the number of active employees per department
+func (r *EmployeeRepository) CountActiveGroupedByDepartment() (map[uint]int64, error) {
+	var rows []struct {
+		DepartmentID uint
+		Count        int64
+	}
+	err := r.DB.Model(&models.Employee{}).
+		Select("department_id, COUNT(*) as count").
+		Where("status = ?", "active").
+		Group("department_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, r.HandleError(err)
+	}
+
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.DepartmentID] = row.Count
+	}
+	return counts, nil
+}
+
 func (r *EmployeeRepository) SearchEmployees(query string, limit int) ([]models.Employee, error) {
 	var employees []models.Employee
 	searchPattern := "%" + query + "%"
@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"attendance-system/models"
+	"time"
+)
+
+type LoginAttemptRepository struct {
+	BaseRepository
+}
+
+func NewLoginAttemptRepository() *LoginAttemptRepository {
+	return &LoginAttemptRepository{
+		BaseRepository: *NewBaseRepository(),
+	}
+}
+
+func (r *LoginAttemptRepository) Create(attempt *models.LoginAttempt) error {
+	if err := r.DB.Create(attempt).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// CountFailuresSince counts failed attempts from an IP in a scope and returns the time of the latest one
+func (r *LoginAttemptRepository) CountFailuresSince(scope, ipAddress string, since time.Time) (int64, *time.Time, error) {
+	var result struct {
+		Count  int64
+		Latest *time.Time
+	}
+	err := r.DB.Model(&models.LoginAttempt{}).
+		Select("COUNT(*) AS count, MAX(created_at) AS latest").
+		Where("scope = ? AND event = ? AND ip_address = ? AND created_at >= ?", scope, models.LoginEventFailed, ipAddress, since).
+		Scan(&result).Error
+	if err != nil {
+		return 0, nil, r.HandleError(err)
+	}
+	return result.Count, result.Latest, nil
+}
+
+// FindEvents lists attempts of the given event, newest first
+func (r *LoginAttemptRepository) FindEvents(event string, page, limit int) ([]models.LoginAttempt, *Pagination, error) {
+	var attempts []models.LoginAttempt
+
+	query := r.DB.Model(&models.LoginAttempt{}).Where("event = ?", event).Order("created_at DESC")
+
+	pagination, err := r.Paginate(query, page, limit, &attempts)
+	if err != nil {
+		return nil, nil, r.HandleError(err)
+	}
+
+	return attempts, pagination, nil
+}
+
+// DeleteBefore removes attempts older than the given time, keeping lockout events for audit
+func (r *LoginAttemptRepository) DeleteBefore(before time.Time) (int64, error) {
+	result := r.DB.Where("event = ? AND created_at < ?", models.LoginEventFailed, before).Delete(&models.LoginAttempt{})
+	if result.Error != nil {
+		return 0, r.HandleError(result.Error)
+	}
+	return result.RowsAffected, nil
+}
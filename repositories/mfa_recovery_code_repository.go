@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"attendance-system/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type MFARecoveryCodeRepository struct {
+	BaseRepository
+}
+
+func NewMFARecoveryCodeRepository() *MFARecoveryCodeRepository {
+	return &MFARecoveryCodeRepository{
+		BaseRepository: *NewBaseRepository(),
+	}
+}
+
+// ReplaceForUser deletes the user's existing codes and stores the new set
+func (r *MFARecoveryCodeRepository) ReplaceForUser(userID uint, codes []models.MFARecoveryCode) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return r.HandleError(err)
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		if err := tx.Create(&codes).Error; err != nil {
+			return r.HandleError(err)
+		}
+		return nil
+	})
+}
+
+// Use marks an unused code as used and reports whether one matched
+func (r *MFARecoveryCodeRepository) Use(userID uint, codeHash string) (bool, error) {
+	result := r.DB.Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Limit(1).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, r.HandleError(result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *MFARecoveryCodeRepository) CountUnused(userID uint) (int64, error) {
+	var count int64
+	err := r.DB.Model(&models.MFARecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	if err != nil {
+		return 0, r.HandleError(err)
+	}
+	return count, nil
+}
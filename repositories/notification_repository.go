@@ -0,0 +1,165 @@
+package repositories
+
+import (
+	"attendance-system/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type NotificationRepository struct {
+	BaseRepository
+}
+
+func NewNotificationRepository() *NotificationRepository {
+	return &NotificationRepository{
+		BaseRepository: *NewBaseRepository(),
+	}
+}
+
+func (r *NotificationRepository) Create(notification *models.Notification) error {
+	if err := r.DB.Create(notification).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// CreateBatch stores notifications for many users at once, e.g. an announcement
+func (r *NotificationRepository) CreateBatch(notifications []models.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	if err := r.DB.CreateInBatches(notifications, 500).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// FindForUser lists the user's notifications matching the filter, unread first and then
+// newest first. employeeID is the user's own employee, which separates personal items from
+// items about their team.
+func (r *NotificationRepository) FindForUser(userID uint, employeeID string, filter models.NotificationFilter, page, limit int) ([]models.Notification, *Pagination, error) {
+	var notifications []models.Notification
+
+	query := r.DB.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if filter.UnreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	switch filter.Scope {
+	case models.NotificationScopePersonal:
+		query = query.Where("employee_id IS NULL OR employee_id = ?", employeeID)
+	case models.NotificationScopeTeam:
+		query = query.Where("employee_id IS NOT NULL AND employee_id <> ?", employeeID)
+	}
+
+	pagination, err := r.Paginate(query.Order("read_at IS NULL DESC, created_at DESC, id DESC"), page, limit, &notifications)
+	if err != nil {
+		return nil, nil, r.HandleError(err)
+	}
+
+	return notifications, pagination, nil
+}
+
+// CountUnread returns how many of the user's notifications haven't been read
+func (r *NotificationRepository) CountUnread(userID uint) (int64, error) {
+	var count int64
+	err := r.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	if err != nil {
+		return 0, r.HandleError(err)
+	}
+	return count, nil
+}
+
+// CountUnreadInCategories returns how many of the user's notifications in the categories haven't been read
+func (r *NotificationRepository) CountUnreadInCategories(userID uint, categories []string) (int64, error) {
+	var count int64
+	err := r.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL AND category IN ?", userID, categories).
+		Count(&count).Error
+	if err != nil {
+		return 0, r.HandleError(err)
+	}
+	return count, nil
+}
+
+// MarkRead marks one of the user's notifications read. It reports false if the user has
+// no such notification.
+func (r *NotificationRepository) MarkRead(userID, id uint, readAt time.Time) (bool, error) {
+	var notification models.Notification
+	err := r.DB.Where("id = ? AND user_id = ?", id, userID).First(&notification).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, r.HandleError(err)
+	}
+	if notification.ReadAt != nil {
+		return true, nil
+	}
+
+	err = r.DB.Model(&models.Notification{}).Where("id = ?", id).Update("read_at", readAt).Error
+	if err != nil {
+		return false, r.HandleError(err)
+	}
+	return true, nil
+}
+
+// MarkAllRead marks every unread notification of the user read and returns how many there were
+func (r *NotificationRepository) MarkAllRead(userID uint, readAt time.Time) (int64, error) {
+	result := r.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", readAt)
+	if result.Error != nil {
+		return 0, r.HandleError(result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// FindPreference returns the user's notification preferences, or nil if they never set any
+func (r *NotificationRepository) FindPreference(userID uint) (*models.NotificationPreference, error) {
+	var preference models.NotificationPreference
+	err := r.DB.Where("user_id = ?", userID).First(&preference).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, r.HandleError(err)
+	}
+	return &preference, nil
+}
+
+func (r *NotificationRepository) SavePreference(preference *models.NotificationPreference) error {
+	if err := r.DB.Save(preference).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// FindDigestSubscribers returns the preferences of users who opted in to a digest
+func (r *NotificationRepository) FindDigestSubscribers() ([]models.NotificationPreference, error) {
+	var preferences []models.NotificationPreference
+	if err := r.DB.Where("digest_daily = ? OR digest_weekly = ?", true, true).Find(&preferences).Error; err != nil {
+		return nil, r.HandleError(err)
+	}
+	return preferences, nil
+}
+
+// ClaimDigest records that the user's daily or weekly digest for the day is being sent.
+// It reports false if it already was, so each digest goes out once across servers.
+func (r *NotificationRepository) ClaimDigest(preferenceID uint, weekly bool, day time.Time) (bool, error) {
+	column := "daily_digest_sent_on"
+	if weekly {
+		column = "weekly_digest_sent_on"
+	}
+	result := r.DB.Model(&models.NotificationPreference{}).
+		Where("id = ? AND ("+column+" IS NULL OR "+column+" < ?)", preferenceID, day).
+		Update(column, day)
+	if result.Error != nil {
+		return false, r.HandleError(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
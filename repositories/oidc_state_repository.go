@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"attendance-system/models"
+	"attendance-system/utils"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type OIDCStateRepository struct {
+	BaseRepository
+}
+
+func NewOIDCStateRepository() *OIDCStateRepository {
+	return &OIDCStateRepository{
+		BaseRepository: *NewBaseRepository(),
+	}
+}
+
+func (r *OIDCStateRepository) Create(state *models.OIDCState) error {
+	if err := r.DB.Create(state).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// Consume finds the state and deletes it, so each state can only finish one login
+func (r *OIDCStateRepository) Consume(stateHash string) (*models.OIDCState, error) {
+	var state models.OIDCState
+	err := r.DB.Where("state_hash = ?", stateHash).First(&state).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("login state not found")
+		}
+		return nil, r.HandleError(err)
+	}
+
+	result := r.DB.Delete(&models.OIDCState{}, state.ID)
+	if result.Error != nil {
+		return nil, r.HandleError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		// Another request used it first
+		return nil, utils.NewNotFoundError("login state not found")
+	}
+	return &state, nil
+}
+
+// DeleteExpired removes logins that were started but never finished
+func (r *OIDCStateRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.DB.Where("expires_at < ?", now).Delete(&models.OIDCState{})
+	if result.Error != nil {
+		return 0, r.HandleError(result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"attendance-system/models"
+	"attendance-system/utils"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type OneTimeTokenRepository struct {
+	BaseRepository
+}
+
+func NewOneTimeTokenRepository() *OneTimeTokenRepository {
+	return &OneTimeTokenRepository{
+		BaseRepository: *NewBaseRepository(),
+	}
+}
+
+func (r *OneTimeTokenRepository) Create(token *models.OneTimeToken) error {
+	if err := r.DB.Create(token).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// FindByHash finds a token issued for the given purpose, with its user loaded
+func (r *OneTimeTokenRepository) FindByHash(tokenHash, purpose string) (*models.OneTimeToken, error) {
+	var token models.OneTimeToken
+	err := r.DB.Preload("User.Employee.Department").
+		Where("token_hash = ? AND purpose = ?", tokenHash, purpose).
+		First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("token not found")
+		}
+		return nil, r.HandleError(err)
+	}
+	return &token, nil
+}
+
+// MarkUsed flags the token as used. It returns false when the token was already used.
+func (r *OneTimeTokenRepository) MarkUsed(id uint) (bool, error) {
+	result := r.DB.Model(&models.OneTimeToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, r.HandleError(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// InvalidateForUser marks every outstanding token of the purpose as used
+func (r *OneTimeTokenRepository) InvalidateForUser(userID uint, purpose string) error {
+	err := r.DB.Model(&models.OneTimeToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// HasPending checks if the user has an unused, unexpired token of the purpose
+func (r *OneTimeTokenRepository) HasPending(userID uint, purpose string) (bool, error) {
+	var count int64
+	err := r.DB.Model(&models.OneTimeToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", userID, purpose, time.Now()).
+		Count(&count).Error
+	if err != nil {
+		return false, r.HandleError(err)
+	}
+	return count > 0, nil
+}
+
+// DeleteExpired removes tokens past their expiry
+func (r *OneTimeTokenRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.DB.Where("expires_at < ?", now).Delete(&models.OneTimeToken{})
+	if result.Error != nil {
+		return 0, r.HandleError(result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"attendance-system/models"
+)
+
+type PasswordHistoryRepository struct {
+	BaseRepository
+}
+
+func NewPasswordHistoryRepository() *PasswordHistoryRepository {
+	return &PasswordHistoryRepository{
+		BaseRepository: *NewBaseRepository(),
+	}
+}
+
+func (r *PasswordHistoryRepository) Create(entry *models.PasswordHistory) error {
+	if err := r.DB.Create(entry).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// FindRecent returns the user's most recent password hashes, newest first
+func (r *PasswordHistoryRepository) FindRecent(userID uint, limit int) ([]models.PasswordHistory, error) {
+	var entries []models.PasswordHistory
+	err := r.DB.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Limit(limit).Find(&entries).Error
+	if err != nil {
+		return nil, r.HandleError(err)
+	}
+	return entries, nil
+}
+
+// DeleteAllButRecent keeps only the user's most recent entries
+func (r *PasswordHistoryRepository) DeleteAllButRecent(userID uint, keep int) error {
+	recent, err := r.FindRecent(userID, keep)
+	if err != nil {
+		return err
+	}
+	if len(recent) < keep {
+		return nil
+	}
+
+	oldest := recent[len(recent)-1]
+	err = r.DB.Where("user_id = ? AND id < ?", userID, oldest.ID).Delete(&models.PasswordHistory{}).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
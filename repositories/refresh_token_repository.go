@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"attendance-system/models"
+	"attendance-system/utils"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository struct {
+	BaseRepository
+}
+
+func NewRefreshTokenRepository() *RefreshTokenRepository {
+	return &RefreshTokenRepository{
+		BaseRepository: *NewBaseRepository(),
+	}
+}
+
+func (r *RefreshTokenRepository) Create(token *models.RefreshToken) error {
+	if err := r.DB.Create(token).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+func (r *RefreshTokenRepository) FindByHash(tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.DB.Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("refresh token not found")
+		}
+		return nil, r.HandleError(err)
+	}
+	return &token, nil
+}
+
+// MarkRotated flags the token as used. It returns false when another request
+// already rotated it, which callers must treat as reuse.
+func (r *RefreshTokenRepository) MarkRotated(id uint) (bool, error) {
+	result := r.DB.Model(&models.RefreshToken{}).
+		Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", id).
+		Update("rotated_at", time.Now())
+	if result.Error != nil {
+		return false, r.HandleError(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeFamily revokes every token descended from the same login
+func (r *RefreshTokenRepository) RevokeFamily(familyID string) error {
+	err := r.DB.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// RevokeUserTokensBefore revokes every refresh token issued to the user before the given time
+func (r *RefreshTokenRepository) RevokeUserTokensBefore(userID uint, before time.Time) error {
+	err := r.DB.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND created_at < ? AND revoked_at IS NULL", userID, before).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// DeleteExpired removes refresh tokens past their expiry
+func (r *RefreshTokenRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.DB.Where("expires_at < ?", now).Delete(&models.RefreshToken{})
+	if result.Error != nil {
+		return 0, r.HandleError(result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"attendance-system/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ReportScheduleRepository struct {
+	BaseRepository
+}
+
+func NewReportScheduleRepository() *ReportScheduleRepository {
+	return &ReportScheduleRepository{
+		BaseRepository: *NewBaseRepository(),
+	}
+}
+
+func (r *ReportScheduleRepository) FindAll() ([]models.ReportSchedule, error) {
+	var schedules []models.ReportSchedule
+	if err := r.DB.Order("name, id").Find(&schedules).Error; err != nil {
+		return nil, r.HandleError(err)
+	}
+	return schedules, nil
+}
+
+func (r *ReportScheduleRepository) FindByID(id uint) (*models.ReportSchedule, error) {
+	var schedule models.ReportSchedule
+	if err := r.DB.First(&schedule, id).Error; err != nil {
+		return nil, r.HandleError(err)
+	}
+	return &schedule, nil
+}
+
+func (r *ReportScheduleRepository) Create(schedule *models.ReportSchedule) error {
+	if err := r.DB.Create(schedule).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+func (r *ReportScheduleRepository) Update(schedule *models.ReportSchedule) error {
+	if err := r.DB.Save(schedule).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// Delete removes the schedule with its run history
+func (r *ReportScheduleRepository) Delete(id uint) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("schedule_id = ?", id).Delete(&models.ReportScheduleRun{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.ReportSchedule{}, id).Error
+	})
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// FindDue returns the active schedules whose next run is due, oldest first
+func (r *ReportScheduleRepository) FindDue(now time.Time) ([]models.ReportSchedule, error) {
+	var schedules []models.ReportSchedule
+	err := r.DB.Where("is_active = ? AND next_run_at <= ?", true, now).
+		Order("next_run_at, id").
+		Find(&schedules).Error
+	if err != nil {
+		return nil, r.HandleError(err)
+	}
+	return schedules, nil
+}
+
+// ClaimRun moves a due schedule on to its next run. It reports false if another server
+// claimed the run first.
+func (r *ReportScheduleRepository) ClaimRun(id uint, dueAt time.Time, nextRunAt *time.Time) (bool, error) {
+	result := r.DB.Model(&models.ReportSchedule{}).
+		Where("id = ? AND is_active = ? AND next_run_at = ?", id, true, dueAt).
+		Update("next_run_at", nextRunAt)
+	if result.Error != nil {
+		return false, r.HandleError(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RecordLastRun stores the outcome of the schedule's latest run
+func (r *ReportScheduleRepository) RecordLastRun(id uint, ranAt time.Time, status string) error {
+	err := r.DB.Model(&models.ReportSchedule{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_run_at":     ranAt,
+		"last_run_status": status,
+	}).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+func (r *ReportScheduleRepository) CreateRun(run *models.ReportScheduleRun) error {
+	if err := r.DB.Create(run).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+func (r *ReportScheduleRepository) UpdateRun(run *models.ReportScheduleRun) error {
+	if err := r.DB.Save(run).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// FindRuns lists the schedule's run history, newest first
+func (r *ReportScheduleRepository) FindRuns(scheduleID uint, page, limit int) ([]models.ReportScheduleRun, *Pagination, error) {
+	var runs []models.ReportScheduleRun
+
+	query := r.DB.Model(&models.ReportScheduleRun{}).Where("schedule_id = ?", scheduleID)
+	pagination, err := r.Paginate(query.Order("started_at DESC, id DESC"), page, limit, &runs)
+	if err != nil {
+		return nil, nil, r.HandleError(err)
+	}
+
+	return runs, pagination, nil
+}
@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"attendance-system/models"
+	"time"
+)
+
+type RevokedTokenRepository struct {
+	BaseRepository
+}
+
+func NewRevokedTokenRepository() *RevokedTokenRepository {
+	return &RevokedTokenRepository{
+		BaseRepository: *NewBaseRepository(),
+	}
+}
+
+func (r *RevokedTokenRepository) Create(token *models.RevokedToken) error {
+	if err := r.DB.Create(token).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// IsRevoked checks if the token ID has been revoked
+func (r *RevokedTokenRepository) IsRevoked(jti string) (bool, error) {
+	var count int64
+	err := r.DB.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+	if err != nil {
+		return false, r.HandleError(err)
+	}
+	return count > 0, nil
+}
+
+// DeleteExpired removes entries for tokens that have expired on their own
+func (r *RevokedTokenRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.DB.Where("expires_at < ?", now).Delete(&models.RevokedToken{})
+	if result.Error != nil {
+		return 0, r.HandleError(result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"attendance-system/models"
+	"attendance-system/utils"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type RoleRepository struct {
+	BaseRepository
+}
+
+func NewRoleRepository() *RoleRepository {
+	return &RoleRepository{
+		BaseRepository: *NewBaseRepository(),
+	}
+}
+
+func (r *RoleRepository) Create(role *models.Role) error {
+	if err := r.DB.Create(role).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+func (r *RoleRepository) FindAll() ([]models.Role, error) {
+	var roles []models.Role
+	err := r.DB.Preload("Permissions").Order("name ASC").Find(&roles).Error
+	if err != nil {
+		return nil, r.HandleError(err)
+	}
+	return roles, nil
+}
+
+func (r *RoleRepository) FindByID(id uint) (*models.Role, error) {
+	var role models.Role
+	err := r.DB.Preload("Permissions").First(&role, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("role not found")
+		}
+		return nil, r.HandleError(err)
+	}
+	return &role, nil
+}
+
+func (r *RoleRepository) FindByName(name string) (*models.Role, error) {
+	var role models.Role
+	err := r.DB.Preload("Permissions").Where("name = ?", name).First(&role).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("role not found")
+		}
+		return nil, r.HandleError(err)
+	}
+	return &role, nil
+}
+
+// Update saves the role and replaces its permission set
+func (r *RoleRepository) Update(role *models.Role) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Permissions").Save(role).Error; err != nil {
+			return r.HandleError(err)
+		}
+		if err := tx.Model(role).Association("Permissions").Replace(role.Permissions); err != nil {
+			return r.HandleError(err)
+		}
+		return nil
+	})
+}
+
+func (r *RoleRepository) Delete(id uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM role_permissions WHERE role_id = ?", id).Error; err != nil {
+			return r.HandleError(err)
+		}
+		result := tx.Delete(&models.Role{}, id)
+		if result.Error != nil {
+			return r.HandleError(result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return utils.NewNotFoundError("role not found")
+		}
+		return nil
+	})
+}
+
+func (r *RoleRepository) FindAllPermissions() ([]models.Permission, error) {
+	var permissions []models.Permission
+	err := r.DB.Order("code ASC").Find(&permissions).Error
+	if err != nil {
+		return nil, r.HandleError(err)
+	}
+	return permissions, nil
+}
+
+func (r *RoleRepository) FindPermissionsByCodes(codes []string) ([]models.Permission, error) {
+	var permissions []models.Permission
+	if len(codes) == 0 {
+		return permissions, nil
+	}
+	err := r.DB.Where("code IN ?", codes).Find(&permissions).Error
+	if err != nil {
+		return nil, r.HandleError(err)
+	}
+	return permissions, nil
+}
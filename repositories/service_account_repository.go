@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"attendance-system/models"
+	"attendance-system/utils"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ServiceAccountRepository struct {
+	BaseRepository
+}
+
+func NewServiceAccountRepository() *ServiceAccountRepository {
+	return &ServiceAccountRepository{
+		BaseRepository: *NewBaseRepository(),
+	}
+}
+
+func (r *ServiceAccountRepository) Create(account *models.ServiceAccount) error {
+	if err := r.DB.Create(account).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+func (r *ServiceAccountRepository) FindAll() ([]models.ServiceAccount, error) {
+	var accounts []models.ServiceAccount
+	err := r.DB.Preload("APIKeys").Order("name ASC").Find(&accounts).Error
+	if err != nil {
+		return nil, r.HandleError(err)
+	}
+	return accounts, nil
+}
+
+func (r *ServiceAccountRepository) FindByID(id uint) (*models.ServiceAccount, error) {
+	var account models.ServiceAccount
+	err := r.DB.Preload("APIKeys").First(&account, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("service account not found")
+		}
+		return nil, r.HandleError(err)
+	}
+	return &account, nil
+}
+
+func (r *ServiceAccountRepository) FindByName(name string) (*models.ServiceAccount, error) {
+	var account models.ServiceAccount
+	err := r.DB.Where("name = ?", name).First(&account).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("service account not found")
+		}
+		return nil, r.HandleError(err)
+	}
+	return &account, nil
+}
+
+func (r *ServiceAccountRepository) Update(account *models.ServiceAccount) error {
+	if err := r.DB.Omit("APIKeys").Save(account).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// Delete removes the service account and all of its keys
+func (r *ServiceAccountRepository) Delete(id uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("service_account_id = ?", id).Delete(&models.APIKey{}).Error; err != nil {
+			return r.HandleError(err)
+		}
+		result := tx.Delete(&models.ServiceAccount{}, id)
+		if result.Error != nil {
+			return r.HandleError(result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return utils.NewNotFoundError("service account not found")
+		}
+		return nil
+	})
+}
+
+func (r *ServiceAccountRepository) CreateKey(key *models.APIKey) error {
+	if err := r.DB.Create(key).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// FindKeyByPrefix finds a key and its service account by the public prefix
+func (r *ServiceAccountRepository) FindKeyByPrefix(prefix string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.DB.Preload("ServiceAccount").Where("prefix = ?", prefix).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("API key not found")
+		}
+		return nil, r.HandleError(err)
+	}
+	return &key, nil
+}
+
+// FindKey finds a key only if it belongs to the given service account
+func (r *ServiceAccountRepository) FindKey(accountID, keyID uint) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.DB.Where("id = ? AND service_account_id = ?", keyID, accountID).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("API key not found")
+		}
+		return nil, r.HandleError(err)
+	}
+	return &key, nil
+}
+
+func (r *ServiceAccountRepository) RevokeKey(keyID uint) error {
+	err := r.DB.Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", keyID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// TouchKey records when and from where the key was last used
+func (r *ServiceAccountRepository) TouchKey(keyID uint, ipAddress string, at time.Time) error {
+	err := r.DB.Model(&models.APIKey{}).Where("id = ?", keyID).Updates(map[string]interface{}{
+		"last_used_at": at,
+		"last_used_ip": ipAddress,
+	}).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"attendance-system/models"
+	"attendance-system/utils"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	BaseRepository
+}
+
+func NewSessionRepository() *SessionRepository {
+	return &SessionRepository{
+		BaseRepository: *NewBaseRepository(),
+	}
+}
+
+func (r *SessionRepository) Create(session *models.UserSession) error {
+	if err := r.DB.Create(session).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+func (r *SessionRepository) FindBySessionID(sessionID string) (*models.UserSession, error) {
+	var session models.UserSession
+	err := r.DB.Where("session_id = ?", sessionID).First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("session not found")
+		}
+		return nil, r.HandleError(err)
+	}
+	return &session, nil
+}
+
+// FindByIDForUser finds a session only if it belongs to the given user
+func (r *SessionRepository) FindByIDForUser(id, userID uint) (*models.UserSession, error) {
+	var session models.UserSession
+	err := r.DB.Where("id = ? AND user_id = ?", id, userID).First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("session not found")
+		}
+		return nil, r.HandleError(err)
+	}
+	return &session, nil
+}
+
+// FindActiveByUser lists the user's sessions that are not revoked and were seen after the given time
+func (r *SessionRepository) FindActiveByUser(userID uint, seenAfter time.Time) ([]models.UserSession, error) {
+	var sessions []models.UserSession
+	err := r.DB.Where("user_id = ? AND revoked_at IS NULL AND last_seen_at > ?", userID, seenAfter).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, r.HandleError(err)
+	}
+	return sessions, nil
+}
+
+// Touch records activity on the session, and the IP it came from when known
+func (r *SessionRepository) Touch(sessionID, ipAddress string, at time.Time) error {
+	updates := map[string]interface{}{"last_seen_at": at}
+	if ipAddress != "" {
+		updates["ip_address"] = ipAddress
+	}
+	err := r.DB.Model(&models.UserSession{}).Where("session_id = ?", sessionID).Updates(updates).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+func (r *SessionRepository) Revoke(sessionID string) error {
+	err := r.DB.Model(&models.UserSession{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// RevokeUserSessionsBefore revokes every session the user started before the given time
+func (r *SessionRepository) RevokeUserSessionsBefore(userID uint, before time.Time) error {
+	err := r.DB.Model(&models.UserSession{}).
+		Where("user_id = ? AND created_at < ? AND revoked_at IS NULL", userID, before).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// DeleteInactive removes sessions that were revoked or last seen before the given time
+func (r *SessionRepository) DeleteInactive(before time.Time) (int64, error) {
+	result := r.DB.Where("revoked_at < ? OR last_seen_at < ?", before, before).Delete(&models.UserSession{})
+	if result.Error != nil {
+		return 0, r.HandleError(result.Error)
+	}
+	return result.RowsAffected, nil
+}
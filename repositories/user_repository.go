@@ -65,6 +65,19 @@ func (r *UserRepository) FindByUsername(username string) (*models.User, error) {
 	return &user, nil
 }
 
+// FindByOIDCSubject finds the user linked to an identity provider account
+func (r *UserRepository) FindByOIDCSubject(subject string) (*models.User, error) {
+	var user models.User
+	err := r.DB.Preload("Employee.Department").Where("oidc_subject = ?", subject).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("user not found")
+		}
+		return nil, r.HandleError(err)
+	}
+	return &user, nil
+}
+
 func (r *UserRepository) FindByEmail(email string) (*models.User, error) {
 	var user models.User
 	err := r.DB.Preload("Employee.Department").Where("email = ?", email).First(&user).Error
@@ -137,6 +150,98 @@ func (r *UserRepository) UpdateLastLogin(userID uint) error {
 	return r.DB.Model(&models.User{}).Where("id = ?", userID).Update("last_login", &now).Error
 }
 
+// UpdateFields updates selected columns of a user without touching the rest of the record
+func (r *UserRepository) UpdateFields(userID uint, updates map[string]interface{}) error {
+	err := r.DB.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// ClaimMFAStep records the TOTP step as used. It returns false if that step or a
+// later one was already used, so each code is accepted only once.
+func (r *UserRepository) ClaimMFAStep(userID uint, step int64) (bool, error) {
+	result := r.DB.Model(&models.User{}).
+		Where("id = ? AND (mfa_last_used_step IS NULL OR mfa_last_used_step < ?)", userID, step).
+		Update("mfa_last_used_step", step)
+	if result.Error != nil {
+		return false, r.HandleError(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// IncrementFailedLogins bumps the user's failed login counter and returns the new value
+func (r *UserRepository) IncrementFailedLogins(userID uint) (int, error) {
+	err := r.DB.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"failed_login_attempts": gorm.Expr("failed_login_attempts + 1"),
+		"last_failed_login_at":  time.Now(),
+	}).Error
+	if err != nil {
+		return 0, r.HandleError(err)
+	}
+
+	var user models.User
+	if err := r.DB.Select("failed_login_attempts").First(&user, userID).Error; err != nil {
+		return 0, r.HandleError(err)
+	}
+	return user.FailedLoginAttempts, nil
+}
+
+// RestartFailedLogins clears an expired lock and starts the failed login counter again at 1
+func (r *UserRepository) RestartFailedLogins(userID uint) error {
+	err := r.DB.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"failed_login_attempts": 1,
+		"last_failed_login_at":  time.Now(),
+		"locked_until":          nil,
+	}).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// LockUntil locks the account against logins until the given time
+func (r *UserRepository) LockUntil(userID uint, until time.Time) error {
+	err := r.DB.Model(&models.User{}).Where("id = ?", userID).Update("locked_until", until).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// ResetFailedLogins clears the failed login counter and any lock
+func (r *UserRepository) ResetFailedLogins(userID uint) error {
+	err := r.DB.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"last_failed_login_at":  nil,
+		"locked_until":          nil,
+	}).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// FindLocked finds users whose lock has not yet expired
+func (r *UserRepository) FindLocked(now time.Time) ([]models.User, error) {
+	var users []models.User
+	err := r.DB.Where("locked_until > ?", now).Order("locked_until DESC").Find(&users).Error
+	if err != nil {
+		return nil, r.HandleError(err)
+	}
+	return users, nil
+}
+
+// SetTokensValidAfter invalidates every access token issued to the user before the given time
+func (r *UserRepository) SetTokensValidAfter(userID uint, validAfter time.Time) error {
+	err := r.DB.Model(&models.User{}).Where("id = ?", userID).Update("tokens_valid_after", validAfter).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
 func (r *UserRepository) CountByRole(role string) (int64, error) {
 	var count int64
 	err := r.DB.Model(&models.User{}).Where("role = ?", role).Count(&count).Error
@@ -146,6 +251,30 @@ func (r *UserRepository) CountByRole(role string) (int64, error) {
 	return count, nil
 }
 
+// CountActiveWithPermission counts the active users other than excludeUserID whose role grants the permission
+func (r *UserRepository) CountActiveWithPermission(code string, excludeUserID uint) (int64, error) {
+	var count int64
+	err := r.DB.Model(&models.User{}).
+		Joins("JOIN roles ON roles.name = users.role").
+		Joins("JOIN role_permissions ON role_permissions.role_id = roles.id").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Where("permissions.code = ? AND users.is_active = ? AND users.id <> ?", code, true, excludeUserID).
+		Count(&count).Error
+	if err != nil {
+		return 0, r.HandleError(err)
+	}
+	return count, nil
+}
+
+// RenameRole moves every user holding the old role name to the new one
+func (r *UserRepository) RenameRole(oldName, newName string) error {
+	err := r.DB.Model(&models.User{}).Where("role = ?", oldName).Update("role", newName).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
 // FindUsersByEmployeeIDs finds multiple users by their employee IDs
 func (r *UserRepository) FindUsersByEmployeeIDs(employeeIDs []string) ([]models.User, error) {
 	var users []models.User
@@ -156,6 +285,15 @@ func (r *UserRepository) FindUsersByEmployeeIDs(employeeIDs []string) ([]models.
 	return users, nil
 }
 
+// FindActive returns all active user accounts
+func (r *UserRepository) FindActive() ([]models.User, error) {
+	var users []models.User
+	if err := r.DB.Where("is_active = ?", true).Find(&users).Error; err != nil {
+		return nil, r.HandleError(err)
+	}
+	return users, nil
+}
+
 // CheckEmployeeIDExists checks if an employee ID is already assigned to any user
 func (r *UserRepository) CheckEmployeeIDExists(employeeID string) (bool, error) {
 	var count int64
@@ -166,21 +304,6 @@ func (r *UserRepository) CheckEmployeeIDExists(employeeID string) (bool, error)
 	return count > 0, nil
 }
 
-// FindBySetupToken finds a user by their setup token
-func (r *UserRepository) FindBySetupToken(token string) (*models.User, error) {
-	var user models.User
-	err := r.DB.Preload("Employee.Department").
-		Where("setup_token = ?", token).
-		First(&user).Error
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, utils.NewNotFoundError("user not found with this setup token")
-		}
-		return nil, r.HandleError(err)
-	}
-	return &user, nil
-}
-
 // FindByUsernameOrEmail finds a user by username or email
 func (r *UserRepository) FindByUsernameOrEmail(identifier string) (*models.User, error) {
 	var user models.User
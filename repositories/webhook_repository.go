@@ -0,0 +1,206 @@
+package repositories
+
+import (
+	"attendance-system/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WebhookRepository struct {
+	BaseRepository
+}
+
+func NewWebhookRepository() *WebhookRepository {
+	return &WebhookRepository{
+		BaseRepository: *NewBaseRepository(),
+	}
+}
+
+func (r *WebhookRepository) FindAll() ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	if err := r.DB.Order("name, id").Find(&webhooks).Error; err != nil {
+		return nil, r.HandleError(err)
+	}
+	return webhooks, nil
+}
+
+func (r *WebhookRepository) FindByID(id uint) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := r.DB.First(&webhook, id).Error; err != nil {
+		return nil, r.HandleError(err)
+	}
+	return &webhook, nil
+}
+
+func (r *WebhookRepository) FindActive() ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	if err := r.DB.Where("is_active = ?", true).Find(&webhooks).Error; err != nil {
+		return nil, r.HandleError(err)
+	}
+	return webhooks, nil
+}
+
+func (r *WebhookRepository) Create(webhook *models.Webhook) error {
+	if err := r.DB.Create(webhook).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) Update(webhook *models.Webhook) error {
+	if err := r.DB.Save(webhook).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// Delete removes the webhook with its delivery log
+func (r *WebhookRepository) Delete(id uint) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Webhook{}, id).Error
+	})
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) CreateDeliveries(deliveries []models.WebhookDelivery) error {
+	if err := r.DB.Create(&deliveries).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) CreateDelivery(delivery *models.WebhookDelivery) error {
+	if err := r.DB.Create(delivery).Error; err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// FindDeliveryByID returns a delivery with its webhook
+func (r *WebhookRepository) FindDeliveryByID(id uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := r.DB.Preload("Webhook").First(&delivery, id).Error; err != nil {
+		return nil, r.HandleError(err)
+	}
+	return &delivery, nil
+}
+
+// FindDelivery returns one of the webhook's deliveries
+func (r *WebhookRepository) FindDelivery(webhookID, id uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := r.DB.Where("webhook_id = ?", webhookID).First(&delivery, id).Error; err != nil {
+		return nil, r.HandleError(err)
+	}
+	return &delivery, nil
+}
+
+// FindDeliveries lists the webhook's deliveries matching the filter, newest first
+func (r *WebhookRepository) FindDeliveries(webhookID uint, filter models.WebhookDeliveryFilter, page, limit int) ([]models.WebhookDelivery, *Pagination, error) {
+	var deliveries []models.WebhookDelivery
+
+	query := r.DB.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if filter.EventType != "" {
+		query = query.Where("event_type = ?", filter.EventType)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	pagination, err := r.Paginate(query.Order("created_at DESC, id DESC"), page, limit, &deliveries)
+	if err != nil {
+		return nil, nil, r.HandleError(err)
+	}
+
+	return deliveries, pagination, nil
+}
+
+// FindDueDeliveryIDs returns up to limit pending deliveries whose next attempt is due, oldest first
+func (r *WebhookRepository) FindDueDeliveryIDs(now time.Time, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.DB.Model(&models.WebhookDelivery{}).
+		Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+		Order("next_attempt_at, id").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, r.HandleError(err)
+	}
+	return ids, nil
+}
+
+// ClaimDelivery takes a due delivery for an attempt by counting the attempt and pushing
+// its next attempt out to leaseUntil. It reports false if another worker claimed it first.
+func (r *WebhookRepository) ClaimDelivery(id uint, now, leaseUntil time.Time) (bool, error) {
+	result := r.DB.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", id, models.WebhookDeliveryPending, now).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": leaseUntil,
+		})
+	if result.Error != nil {
+		return false, r.HandleError(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *WebhookRepository) MarkDelivered(id uint, deliveredAt time.Time, responseStatus int, durationMs int64) error {
+	err := r.DB.Model(&models.WebhookDelivery{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          models.WebhookDeliveryDelivered,
+		"delivered_at":    deliveredAt,
+		"response_status": responseStatus,
+		"duration_ms":     durationMs,
+		"last_error":      "",
+	}).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// MarkDeliveryFailed records a failed attempt, either scheduling the next one or marking
+// the delivery dead
+func (r *WebhookRepository) MarkDeliveryFailed(id uint, status string, nextAttemptAt time.Time, responseStatus int, durationMs int64, lastError string) error {
+	err := r.DB.Model(&models.WebhookDelivery{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          status,
+		"next_attempt_at": nextAttemptAt,
+		"response_status": responseStatus,
+		"duration_ms":     durationMs,
+		"last_error":      lastError,
+	}).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// RequeueDelivery puts a delivery back in the queue with a fresh set of attempts
+func (r *WebhookRepository) RequeueDelivery(id uint, now time.Time) error {
+	err := r.DB.Model(&models.WebhookDelivery{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          models.WebhookDeliveryPending,
+		"attempts":        0,
+		"next_attempt_at": now,
+		"last_error":      "",
+		"delivered_at":    nil,
+	}).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
+
+// DeleteFinishedDeliveriesBefore removes delivered and dead deliveries last updated before the cutoff
+func (r *WebhookRepository) DeleteFinishedDeliveriesBefore(cutoff time.Time) error {
+	err := r.DB.Where("status IN ? AND updated_at < ?", []string{models.WebhookDeliveryDelivered, models.WebhookDeliveryDead}, cutoff).
+		Delete(&models.WebhookDelivery{}).Error
+	if err != nil {
+		return r.HandleError(err)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"attendance-system/controllers"
 	"attendance-system/middleware"
+	"attendance-system/models"
 	"attendance-system/services"
 	"net/http"
 	"time"
@@ -18,11 +19,29 @@ func SetupRoutes(router *gin.Engine) {
 	// Initialize services and controllers
 	authService := services.NewAuthService()
 	authController := controllers.NewAuthController()
+	mfaController := controllers.NewMFAController()
+	sessionController := controllers.NewSessionController()
+	serviceAccountController := controllers.NewServiceAccountController()
+	oidcController := controllers.NewOIDCController()
+	auditController := controllers.NewAuditController()
+	emailController := controllers.NewEmailController()
+	alertRuleController := controllers.NewAlertRuleController()
+	webhookController := controllers.NewWebhookController()
+	notificationController := controllers.NewNotificationController()
 	employeeController := controllers.NewEmployeeController()
 	departmentController := controllers.NewDepartmentController()
 	attendanceController := controllers.NewAttendanceController()
 	reportController := controllers.NewReportController()
+	reportScheduleController := controllers.NewReportScheduleController()
 	setupController := controllers.NewSetupController()
+	meController := controllers.NewMeController()
+	roleController := controllers.NewRoleController()
+
+	// Employees may only read their own records unless their role can view everyone's
+	selfOrManager := middleware.SelfOrPermissionMiddleware("employee_id", models.PermissionEmployeesViewAll)
+
+	// Public keys for services verifying our access tokens
+	router.GET("/.well-known/jwks.json", authController.GetJWKS)
 
 	// API v1 group
 	api := router.Group("/api/v1")
@@ -33,14 +52,21 @@ func SetupRoutes(router *gin.Engine) {
 		// Public routes (no authentication required)
 		public := api.Group("/auth")
 		{
-			public.POST("/login", authController.Login)
-			public.POST("/register", authController.Register)
+			public.POST("/login", middleware.ThrottleMiddleware(models.ThrottleScopeLogin), authController.Login)
+			public.POST("/refresh", authController.RefreshToken)
+			public.POST("/forgot-password", middleware.ThrottleMiddleware(models.ThrottleScopeForgot), authController.ForgotPassword)
+			public.GET("/password-policy", authController.GetPasswordPolicy)
+			public.POST("/reset-password", middleware.ThrottleMiddleware(models.ThrottleScopeReset), authController.ResetPassword)
+			public.POST("/verify-email", middleware.ThrottleMiddleware(models.ThrottleScopeVerify), authController.VerifyEmail)
+			public.POST("/mfa/verify", middleware.ThrottleMiddleware(models.ThrottleScopeMFA), mfaController.VerifyMFA)
+			public.GET("/oidc/login", oidcController.BeginLogin)
+			public.POST("/oidc/callback", middleware.ThrottleMiddleware(models.ThrottleScopeLogin), oidcController.Callback)
 		}
 
 		// Account setup routes (public)
 		setup := api.Group("/setup")
 		{
-			setup.POST("/complete", setupController.CompleteAccountSetup)
+			setup.POST("/complete", middleware.ThrottleMiddleware(models.ThrottleScopeSetup), setupController.CompleteAccountSetup)
 			// setup.POST("/resend-email", setupController.ResendSetupEmail)
 		}
 
@@ -51,29 +77,63 @@ func SetupRoutes(router *gin.Engine) {
 			// Auth routes
 			auth := protected.Group("/auth")
 			{
+				auth.POST("/register", middleware.RequirePermission(models.PermissionUsersManage), authController.Register)
 				auth.GET("/profile", authController.GetProfile)
 				auth.PUT("/profile", authController.UpdateProfile)
 				auth.PUT("/change-password", authController.ChangePassword)
-				auth.POST("/refresh", authController.RefreshToken)
-				public.POST("/setup-account", authController.SetupAccount)
-				public.GET("/verify-setup-token", authController.VerifySetupToken)
+				auth.POST("/logout", authController.Logout)
+				auth.POST("/verify-email/request", authController.RequestEmailVerification)
+				auth.POST("/logout-all", authController.LogoutAll)
+				auth.GET("/mfa", mfaController.GetStatus)
+				auth.POST("/mfa/enroll", mfaController.BeginEnrollment)
+				auth.POST("/mfa/confirm", mfaController.ConfirmEnrollment)
+				auth.POST("/mfa/disable", mfaController.DisableMFA)
+				auth.POST("/mfa/recovery-codes", mfaController.RegenerateRecoveryCodes)
+				auth.GET("/sessions", sessionController.GetMySessions)
+				auth.DELETE("/sessions/:id", sessionController.RevokeMySession)
+				public.POST("/setup-account", middleware.ThrottleMiddleware(models.ThrottleScopeSetup), authController.SetupAccount)
+				public.GET("/verify-setup-token", middleware.ThrottleMiddleware(models.ThrottleScopeSetup), authController.VerifySetupToken)
+			}
+
+			// Self-service routes for the employee linked to the token
+			me := protected.Group("/me")
+			{
+				me.GET("/profile", meController.GetMyProfile)
+				me.GET("/today", meController.GetMyTodayStatus)
+				me.GET("/attendance", meController.GetMyAttendance)
+				me.GET("/stats", meController.GetMyStats)
+			}
+
+			// Inbox and notification settings of the current user
+			notifications := protected.Group("/notifications")
+			{
+				notifications.GET("", notificationController.GetNotifications)
+				notifications.GET("/unread-count", notificationController.GetUnreadCount)
+				notifications.PUT("/:id/read", notificationController.MarkRead)
+				notifications.POST("/read-all", notificationController.MarkAllRead)
+				notifications.POST("/team-announcements", notificationController.SendTeamAnnouncement)
+				notifications.GET("/preferences", notificationController.GetPreferences)
+				notifications.PUT("/preferences", notificationController.UpdatePreferences)
+				notifications.GET("/digest/preview", notificationController.PreviewDigest)
 			}
 
 			// Employee routes
 			employees := protected.Group("/employees")
-			employees.Use(middleware.RoleMiddleware([]string{"employee", "manager", "admin"}))
+			employees.Use(middleware.RequirePermission(models.PermissionEmployeesView))
 			{
 				employees.GET("", employeeController.GetAllEmployees)
 				employees.GET("/search", employeeController.SearchEmployees)
 				employees.GET("/department/:department_id", employeeController.GetEmployeesByDepartment)
 				employees.GET("/:id", employeeController.GetEmployeeByID)
+				employees.GET("/:id/reporting-chain", employeeController.GetReportingChain)
+				employees.GET("/:id/direct-reports", employeeController.GetDirectReports)
 				
 				// Use a different path structure to avoid conflicts
-				employees.GET("/employee/:employee_id/details", employeeController.GetEmployeeWithUser)
+				employees.GET("/employee/:employee_id/details", selfOrManager, employeeController.GetEmployeeWithUser)
 				
-				// Admin/Manager only routes
+				// Routes that change employee records
 				adminEmployees := employees.Group("")
-				adminEmployees.Use(middleware.RoleMiddleware([]string{"manager", "admin"}))
+				adminEmployees.Use(middleware.RequirePermission(models.PermissionEmployeesManage))
 				{
 					adminEmployees.POST("", employeeController.CreateEmployee)
 					adminEmployees.PUT("/:id", employeeController.UpdateEmployee)
@@ -83,44 +143,135 @@ func SetupRoutes(router *gin.Engine) {
 
 			// Department routes
 			departments := protected.Group("/departments")
-			departments.Use(middleware.RoleMiddleware([]string{"manager", "admin"}))
+			departments.Use(middleware.RequirePermission(models.PermissionDepartmentsView))
 			{
 				departments.GET("", departmentController.GetAllDepartments)
 				departments.GET("/active", departmentController.GetActiveDepartments)
 				departments.GET("/:id", departmentController.GetDepartmentByID)
-				departments.POST("", departmentController.CreateDepartment)
-				departments.PUT("/:id", departmentController.UpdateDepartment)
-				departments.DELETE("/:id", departmentController.DeleteDepartment)
+				departments.GET("/:id/subtree", departmentController.GetDepartmentSubtree)
+
+				manageDepartments := departments.Group("")
+				manageDepartments.Use(middleware.RequirePermission(models.PermissionDepartmentsManage))
+				{
+					manageDepartments.POST("", departmentController.CreateDepartment)
+					manageDepartments.PUT("/:id", departmentController.UpdateDepartment)
+					manageDepartments.DELETE("/:id", departmentController.DeleteDepartment)
+				}
 			}
 
 			// Attendance routes
+			// Reading is also open to read-only integrations, recording is not
 			attendance := protected.Group("/attendance")
-			attendance.Use(middleware.RoleMiddleware([]string{"employee", "manager", "admin"}))
+			attendance.Use(middleware.RequireAnyPermission(models.PermissionAttendanceRecord, models.PermissionAttendanceViewAll))
 			{
-				attendance.POST("/clock-in", attendanceController.ClockIn)
-				attendance.PUT("/clock-out", attendanceController.ClockOut)
+				attendance.POST("/clock-in", middleware.RequirePermission(models.PermissionAttendanceRecord), attendanceController.ClockIn)
+				attendance.PUT("/clock-out", middleware.RequirePermission(models.PermissionAttendanceRecord), attendanceController.ClockOut)
 				attendance.GET("/logs", attendanceController.GetAttendanceLogs)
-				attendance.GET("/employee/:employee_id", attendanceController.GetEmployeeAttendance)
-				attendance.GET("/stats/:employee_id", attendanceController.GetAttendanceStats)
+				attendance.GET("/employee/:employee_id", selfOrManager, attendanceController.GetEmployeeAttendance)
+				attendance.GET("/stats/:employee_id", selfOrManager, attendanceController.GetAttendanceStats)
 			}
 
-			// Report routes (Manager and Admin only)
+			// Report routes
 			reports := protected.Group("/reports")
-			reports.Use(middleware.RoleMiddleware([]string{"manager", "admin"}))
+			reports.Use(middleware.RequirePermission(models.PermissionReportsView))
 			{
 				reports.GET("/attendance", reportController.GenerateAttendanceReport)
 				reports.GET("/summary", reportController.GenerateSummaryReport)
 				reports.GET("/department/:department_id", reportController.GenerateDepartmentReport)
+				reports.GET("/hierarchy/:department_id", reportController.GenerateHierarchyReport)
 				
 				// Export routes
-				reports.GET("/export/attendance", reportController.ExportAttendanceReport)
-				reports.GET("/export/summary", reportController.ExportSummaryReport)
-				reports.GET("/export/department/:department_id", reportController.ExportDepartmentReport)
+				exports := reports.Group("/export")
+				exports.Use(middleware.RequirePermission(models.PermissionReportsExport))
+				{
+					exports.GET("/attendance", reportController.ExportAttendanceReport)
+					exports.GET("/summary", reportController.ExportSummaryReport)
+					exports.GET("/department/:department_id", reportController.ExportDepartmentReport)
+				}
+
+				// Scheduled report delivery
+				schedules := reports.Group("/schedules")
+				schedules.Use(middleware.RequirePermission(models.PermissionReportSchedulesManage))
+				{
+					schedules.GET("", reportScheduleController.GetReportSchedules)
+					schedules.POST("", reportScheduleController.CreateReportSchedule)
+					schedules.GET("/:id", reportScheduleController.GetReportScheduleByID)
+					schedules.PUT("/:id", reportScheduleController.UpdateReportSchedule)
+					schedules.DELETE("/:id", reportScheduleController.DeleteReportSchedule)
+					schedules.POST("/:id/run", reportScheduleController.RunReportSchedule)
+					schedules.GET("/:id/runs", reportScheduleController.GetReportScheduleRuns)
+				}
+			}
+
+			// Role and permission administration
+			admin := protected.Group("/admin")
+			{
+				admin.GET("/permissions", middleware.RequirePermission(models.PermissionRolesManage), roleController.GetAllPermissions)
+				admin.PUT("/users/:id/role", middleware.RequirePermission(models.PermissionUsersManage), roleController.AssignUserRole)
+				admin.POST("/users/:id/sign-out", middleware.RequirePermission(models.PermissionUsersManage), authController.SignOutUser)
+				admin.GET("/users/:id/sessions", middleware.RequirePermission(models.PermissionUsersManage), sessionController.GetUserSessions)
+				admin.DELETE("/users/:id/sessions/:session_id", middleware.RequirePermission(models.PermissionUsersManage), sessionController.RevokeUserSession)
+				admin.GET("/users/locked", middleware.RequirePermission(models.PermissionUsersManage), authController.GetLockedAccounts)
+				admin.POST("/users/:id/unlock", middleware.RequirePermission(models.PermissionUsersManage), authController.UnlockAccount)
+				admin.GET("/security/lockouts", middleware.RequirePermission(models.PermissionUsersManage), authController.GetLockoutEvents)
+				admin.GET("/audit-logs", middleware.RequirePermission(models.PermissionAuditView), auditController.GetAuditLogs)
+				admin.GET("/audit-logs/export", middleware.RequirePermission(models.PermissionAuditView), auditController.ExportAuditLogs)
+				admin.GET("/emails", middleware.RequirePermission(models.PermissionEmailsManage), emailController.GetEmails)
+				admin.GET("/emails/:id", middleware.RequirePermission(models.PermissionEmailsManage), emailController.GetEmailByID)
+				admin.POST("/emails/:id/resend", middleware.RequirePermission(models.PermissionEmailsManage), emailController.ResendEmail)
+
+				admin.POST("/announcements", middleware.RequirePermission(models.PermissionAnnouncementsManage), notificationController.SendAnnouncement)
+
+				alertRules := admin.Group("/alert-rules")
+				alertRules.Use(middleware.RequirePermission(models.PermissionAlertsManage))
+				{
+					alertRules.GET("", alertRuleController.GetAlertRules)
+					alertRules.POST("", alertRuleController.CreateAlertRule)
+					alertRules.PUT("/:id", alertRuleController.UpdateAlertRule)
+					alertRules.DELETE("/:id", alertRuleController.DeleteAlertRule)
+				}
+
+				webhooks := admin.Group("/webhooks")
+				webhooks.Use(middleware.RequirePermission(models.PermissionWebhooksManage))
+				{
+					webhooks.GET("", webhookController.GetWebhooks)
+					webhooks.GET("/events", webhookController.GetWebhookEvents)
+					webhooks.POST("", webhookController.CreateWebhook)
+					webhooks.GET("/:id", webhookController.GetWebhookByID)
+					webhooks.PUT("/:id", webhookController.UpdateWebhook)
+					webhooks.DELETE("/:id", webhookController.DeleteWebhook)
+					webhooks.POST("/:id/ping", webhookController.PingWebhook)
+					webhooks.GET("/:id/deliveries", webhookController.GetWebhookDeliveries)
+					webhooks.POST("/:id/deliveries/:delivery_id/redeliver", webhookController.RedeliverWebhook)
+				}
+
+				serviceAccounts := admin.Group("/service-accounts")
+				serviceAccounts.Use(middleware.RequirePermission(models.PermissionServiceAccountsManage))
+				{
+					serviceAccounts.GET("", serviceAccountController.GetAllServiceAccounts)
+					serviceAccounts.GET("/scopes", serviceAccountController.GetScopes)
+					serviceAccounts.GET("/:id", serviceAccountController.GetServiceAccountByID)
+					serviceAccounts.POST("", serviceAccountController.CreateServiceAccount)
+					serviceAccounts.PUT("/:id", serviceAccountController.UpdateServiceAccount)
+					serviceAccounts.DELETE("/:id", serviceAccountController.DeleteServiceAccount)
+					serviceAccounts.POST("/:id/keys", serviceAccountController.CreateAPIKey)
+					serviceAccounts.DELETE("/:id/keys/:key_id", serviceAccountController.RevokeAPIKey)
+				}
+
+				roles := admin.Group("/roles")
+				roles.Use(middleware.RequirePermission(models.PermissionRolesManage))
+				{
+					roles.GET("", roleController.GetAllRoles)
+					roles.GET("/:id", roleController.GetRoleByID)
+					roles.POST("", roleController.CreateRole)
+					roles.PUT("/:id", roleController.UpdateRole)
+					roles.DELETE("/:id", roleController.DeleteRole)
+				}
 			}
 
 			// Dashboard routes
-			protected.GET("/admin/dashboard", middleware.RoleMiddleware([]string{"admin"}), adminDashboard)
-			protected.GET("/manager/dashboard", middleware.RoleMiddleware([]string{"manager", "admin"}), managerDashboard)
+			protected.GET("/admin/dashboard", middleware.RequirePermission(models.PermissionDashboardAdmin), adminDashboard)
+			protected.GET("/manager/dashboard", middleware.RequirePermission(models.PermissionDashboardManager), managerDashboard)
 		}
 	}
 
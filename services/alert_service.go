@@ -0,0 +1,332 @@
+package services
+
+import (
+	"attendance-system/config"
+	"attendance-system/models"
+	"attendance-system/repositories"
+	"attendance-system/utils"
+	"log"
+	"time"
+)
+
+// alertEventRetention is how long alert events are kept after the day they are for
+const alertEventRetention = 30 * 24 * time.Hour
+
+// AlertService evaluates the attendance alert rules, on clock-in for late arrivals and
+// in a periodic sweep for missing clock-ins and sessions left open
+type AlertService struct {
+	ruleRepo            *repositories.AlertRuleRepository
+	employeeRepo        *repositories.EmployeeRepository
+	attendanceRepo      *repositories.AttendanceRepository
+	userRepo            *repositories.UserRepository
+	departmentRepo      *repositories.DepartmentRepository
+	notificationService *NotificationService
+	auditService        *AuditService
+	frontendURL         string
+}
+
+func NewAlertService() *AlertService {
+	return &AlertService{
+		ruleRepo:            repositories.NewAlertRuleRepository(),
+		employeeRepo:        repositories.NewEmployeeRepository(),
+		attendanceRepo:      repositories.NewAttendanceRepository(),
+		userRepo:            repositories.NewUserRepository(),
+		departmentRepo:      repositories.NewDepartmentRepository(),
+		notificationService: NewNotificationService(),
+		auditService:        NewAuditService(),
+		frontendURL:         config.GetConfig().FrontendURL,
+	}
+}
+
+func (s *AlertService) GetRules() ([]models.AlertRule, error) {
+	return s.ruleRepo.FindAll()
+}
+
+func (s *AlertService) CreateRule(req models.AlertRuleRequest, actor models.AuditActor) (*models.AlertRule, error) {
+	if err := s.validateDepartment(req.DepartmentID); err != nil {
+		return nil, err
+	}
+
+	rule := &models.AlertRule{
+		Name:             req.Name,
+		Type:             req.Type,
+		ThresholdMinutes: req.ThresholdMinutes,
+		DepartmentID:     req.DepartmentID,
+		Recipient:        req.Recipient,
+		IsActive:         req.IsActive == nil || *req.IsActive,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+	if err := s.ruleRepo.Create(rule); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, "alert_rule.create", models.AuditEntityAlertRule, auditID(rule.ID), nil, auditSnapshot(rule))
+
+	return rule, nil
+}
+
+func (s *AlertService) UpdateRule(id uint, req models.AlertRuleRequest, actor models.AuditActor) (*models.AlertRule, error) {
+	rule, err := s.ruleRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateDepartment(req.DepartmentID); err != nil {
+		return nil, err
+	}
+	before := auditSnapshot(rule)
+
+	rule.Name = req.Name
+	rule.Type = req.Type
+	rule.ThresholdMinutes = req.ThresholdMinutes
+	rule.DepartmentID = req.DepartmentID
+	rule.Recipient = req.Recipient
+	if req.IsActive != nil {
+		rule.IsActive = *req.IsActive
+	}
+	rule.UpdatedAt = time.Now()
+
+	if err := s.ruleRepo.Update(rule); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, "alert_rule.update", models.AuditEntityAlertRule, auditID(rule.ID), before, auditSnapshot(rule))
+
+	return rule, nil
+}
+
+func (s *AlertService) DeleteRule(id uint, actor models.AuditActor) error {
+	rule, err := s.ruleRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.ruleRepo.Delete(rule.ID); err != nil {
+		return err
+	}
+	s.auditService.Record(actor, "alert_rule.delete", models.AuditEntityAlertRule, auditID(rule.ID), auditSnapshot(rule), nil)
+
+	return nil
+}
+
+func (s *AlertService) validateDepartment(departmentID *uint) error {
+	if departmentID == nil {
+		return nil
+	}
+	_, err := s.departmentRepo.FindByID(*departmentID)
+	return err
+}
+
+// OnClockIn evaluates the late arrival rules for a clock-in that was lateMinutes late.
+// Failures are logged rather than returned so they never fail the clock-in itself.
+func (s *AlertService) OnClockIn(employee *models.Employee, attendance *models.Attendance, lateMinutes int) {
+	if lateMinutes <= 0 {
+		return
+	}
+
+	rules, err := s.ruleRepo.FindActiveByType(models.AlertRuleLateArrival)
+	if err != nil {
+		log.Printf("Failed to load late arrival alert rules: %v", err)
+		return
+	}
+
+	for _, rule := range rules {
+		if !ruleApplies(rule, employee) || lateMinutes <= rule.ThresholdMinutes {
+			continue
+		}
+		alert := AttendanceAlert{
+			Type:    rule.Type,
+			Minutes: lateMinutes,
+			Time:    attendance.ClockIn.Format("15:04"),
+		}
+		if err := s.fire(rule, employee, attendance.ClockIn, alert); err != nil {
+			log.Printf("Failed to send late arrival alert for %s: %v", employee.EmployeeID, err)
+		}
+	}
+}
+
+// Sweep evaluates the rules that depend on time passing rather than on a clock-in:
+// employees who haven't clocked in and sessions still open after the shift ended
+func (s *AlertService) Sweep(now time.Time) error {
+	today := now.Format("2006-01-02")
+
+	missingRules, err := s.ruleRepo.FindActiveByType(models.AlertRuleMissingClockIn)
+	if err != nil {
+		return err
+	}
+	if len(missingRules) > 0 && !utils.IsWeekend(now) && !utils.IsHoliday(now) {
+		if err := s.sweepMissingClockIns(missingRules, now, today); err != nil {
+			return err
+		}
+	}
+
+	openRules, err := s.ruleRepo.FindActiveByType(models.AlertRuleOpenSession)
+	if err != nil {
+		return err
+	}
+	if len(openRules) > 0 {
+		attendances, err := s.attendanceRepo.FindOpenOn(today)
+		if err != nil {
+			return err
+		}
+		for _, attendance := range attendances {
+			shiftEnd, ok := shiftTime(now, attendance.Employee.Department.MaxClockOut)
+			if !ok {
+				continue
+			}
+			for _, rule := range openRules {
+				if !ruleApplies(rule, &attendance.Employee) || now.Before(shiftEnd.Add(time.Duration(rule.ThresholdMinutes)*time.Minute)) {
+					continue
+				}
+				alert := AttendanceAlert{Type: rule.Type, Time: shiftEnd.Format("15:04")}
+				if err := s.fire(rule, &attendance.Employee, now, alert); err != nil {
+					log.Printf("Failed to send open session alert for %s: %v", attendance.EmployeeID, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// sweepMissingClockIns alerts for active employees who haven't clocked in once the
+// rule's threshold after their shift start has passed, until their shift ends
+func (s *AlertService) sweepMissingClockIns(rules []models.AlertRule, now time.Time, today string) error {
+	employees, err := s.employeeRepo.FindActive()
+	if err != nil {
+		return err
+	}
+	clockedIn, err := s.attendanceRepo.FindEmployeeIDsClockedInOn(today)
+	if err != nil {
+		return err
+	}
+	present := make(map[string]bool, len(clockedIn))
+	for _, employeeID := range clockedIn {
+		present[employeeID] = true
+	}
+
+	for i := range employees {
+		employee := &employees[i]
+		if present[employee.EmployeeID] || employee.JoinDate.After(now) {
+			continue
+		}
+		shiftStart, ok := shiftTime(now, employee.Department.MaxClockIn)
+		if !ok {
+			continue
+		}
+		shiftEnd, ok := shiftTime(now, employee.Department.MaxClockOut)
+		if !ok || !now.Before(shiftEnd) {
+			continue
+		}
+
+		for _, rule := range rules {
+			if !ruleApplies(rule, employee) || now.Before(shiftStart.Add(time.Duration(rule.ThresholdMinutes)*time.Minute)) {
+				continue
+			}
+			alert := AttendanceAlert{Type: rule.Type, Time: shiftStart.Format("15:04")}
+			if err := s.fire(rule, employee, now, alert); err != nil {
+				log.Printf("Failed to send missing clock-in alert for %s: %v", employee.EmployeeID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// fire notifies the rule's recipients about the employee, unless the rule already fired
+// for them that day
+func (s *AlertService) fire(rule models.AlertRule, employee *models.Employee, at time.Time, alert AttendanceAlert) error {
+	date := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+	claimed, err := s.ruleRepo.ClaimEvent(rule.ID, employee.EmployeeID, date)
+	if err != nil || !claimed {
+		return err
+	}
+
+	alert.EmployeeID = employee.EmployeeID
+	alert.EmployeeName = employee.Name
+	notificationType := "attendance." + rule.Type
+
+	if rule.Recipient == models.AlertRecipientEmployee || rule.Recipient == models.AlertRecipientBoth {
+		if user := s.recipientUser(employee.EmployeeID); user != nil {
+			alert.AboutSelf = true
+			notification := models.Notification{Type: notificationType, Category: models.NotificationCategoryAlert}
+			if rule.Type != models.AlertRuleLateArrival {
+				notification.Category = models.NotificationCategoryReminder
+			}
+			data := EmailTemplateData{Name: employee.Name, ActionURL: s.frontendURL, Alert: alert}
+			if err := s.notificationService.Notify(user, notification, models.EmailKindAttendanceAlert, data); err != nil {
+				return err
+			}
+		}
+	}
+
+	if (rule.Recipient == models.AlertRecipientManager || rule.Recipient == models.AlertRecipientBoth) && employee.ManagerID != nil {
+		if user := s.recipientUser(*employee.ManagerID); user != nil {
+			alert.AboutSelf = false
+			name := user.Username
+			if user.Employee != nil {
+				name = user.Employee.Name
+			}
+			notification := models.Notification{
+				Type:       notificationType,
+				Category:   models.NotificationCategoryAlert,
+				EmployeeID: &employee.EmployeeID,
+			}
+			data := EmailTemplateData{Name: name, ActionURL: s.frontendURL, Alert: alert}
+			if err := s.notificationService.Notify(user, notification, models.EmailKindAttendanceAlert, data); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// recipientUser returns the active user account of the employee, or nil if they have none
+func (s *AlertService) recipientUser(employeeID string) *models.User {
+	user, err := s.userRepo.FindByEmployeeID(employeeID)
+	if err != nil || !user.IsActive {
+		return nil
+	}
+	return user
+}
+
+// PruneEvents removes alert events older than the retention period
+func (s *AlertService) PruneEvents() error {
+	return s.ruleRepo.DeleteEventsBefore(time.Now().Add(-alertEventRetention))
+}
+
+// ruleApplies reports whether the rule covers the employee's department
+func ruleApplies(rule models.AlertRule, employee *models.Employee) bool {
+	return rule.DepartmentID == nil || *rule.DepartmentID == employee.DepartmentID
+}
+
+// shiftTime returns the department's HH:MM:SS shift time on the day of now
+func shiftTime(now time.Time, clock string) (time.Time, bool) {
+	parsed, err := time.Parse("15:04:05", clock)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Date(now.Year(), now.Month(), now.Day(), parsed.Hour(), parsed.Minute(), parsed.Second(), 0, now.Location()), true
+}
+
+// StartAlertSweeper evaluates the time-based alert rules in the background every interval
+func StartAlertSweeper(interval time.Duration) {
+	alertService := NewAlertService()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		pruneTicker := time.NewTicker(24 * time.Hour)
+		defer pruneTicker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				if err := alertService.Sweep(now); err != nil {
+					log.Printf("Failed to evaluate attendance alerts: %v", err)
+				}
+			case <-pruneTicker.C:
+				if err := alertService.PruneEvents(); err != nil {
+					log.Printf("Failed to prune alert events: %v", err)
+				}
+			}
+		}
+	}()
+}
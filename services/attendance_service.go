@@ -12,16 +12,24 @@ import (
 type AttendanceService struct {
 	attendanceRepo *repositories.AttendanceRepository
 	employeeRepo   *repositories.EmployeeRepository
+	auditService   *AuditService
+	alertService   *AlertService
+	webhookService *WebhookService
 }
 
 func NewAttendanceService() *AttendanceService {
 	return &AttendanceService{
 		attendanceRepo: repositories.NewAttendanceRepository(),
 		employeeRepo:   repositories.NewEmployeeRepository(),
+		auditService:   NewAuditService(),
+		alertService:   NewAlertService(),
+		webhookService: NewWebhookService(),
 	}
 }
 
-func (s *AttendanceService) ClockIn(req models.AttendanceRequest) (*models.Attendance, error) {
+// ClockIn records the start of the employee's day. Recording it for someone else is an
+// edit of their attendance and goes to the audit log.
+func (s *AttendanceService) ClockIn(req models.AttendanceRequest, actor models.AuditActor) (*models.Attendance, error) {
 	// Check if employee exists
 	employee, err := s.employeeRepo.FindByEmployeeID(req.EmployeeID)
 	if err != nil {
@@ -75,11 +83,17 @@ func (s *AttendanceService) ClockIn(req models.AttendanceRequest) (*models.Atten
 	if err := s.attendanceRepo.CreateAttendance(attendance); err != nil {
 		return nil, err
 	}
+	if actor.EmployeeID != req.EmployeeID {
+		s.auditService.Record(actor, "attendance.clock_in", models.AuditEntityAttendance, auditID(attendance.ID), nil, auditSnapshot(attendance))
+	}
+	s.alertService.OnClockIn(employee, attendance, lateMinutes)
+	s.webhookService.Emit(models.WebhookEventClockIn, webhookAttendance(attendance))
 
 	return attendance, nil
 }
 
-func (s *AttendanceService) ClockOut(req models.ClockOutRequest) (*models.Attendance, error) {
+// ClockOut closes the employee's day, auditing it like ClockIn when done for someone else
+func (s *AttendanceService) ClockOut(req models.ClockOutRequest, actor models.AuditActor) (*models.Attendance, error) {
 	// Find today's attendance
 	attendance, err := s.attendanceRepo.FindTodayAttendance(req.EmployeeID)
 	if err != nil {
@@ -92,6 +106,7 @@ func (s *AttendanceService) ClockOut(req models.ClockOutRequest) (*models.Attend
 	if attendance.ClockOut != nil {
 		return nil, utils.NewConflictError("already clocked out today")
 	}
+	before := auditSnapshot(attendance)
 
 	now := time.Now()
 	attendance.ClockOut = &now
@@ -126,13 +141,22 @@ func (s *AttendanceService) ClockOut(req models.ClockOutRequest) (*models.Attend
 	if err := s.attendanceRepo.UpdateAttendance(attendance); err != nil {
 		return nil, err
 	}
+	if actor.EmployeeID != req.EmployeeID {
+		s.auditService.Record(actor, "attendance.clock_out", models.AuditEntityAttendance, auditID(attendance.ID), before, auditSnapshot(attendance))
+	}
+	s.webhookService.Emit(models.WebhookEventClockOut, webhookAttendance(attendance))
 
 	return attendance, nil
 }
 
 // Enhanced method with punctuality data
 func (s *AttendanceService) GetAttendanceLogs(startDate, endDate string, departmentID uint, employeeID string, page, limit int) ([]models.AttendanceResponse, *repositories.Pagination, error) {
-	attendances, pagination, err := s.attendanceRepo.GetAttendanceLogs(startDate, endDate, departmentID, employeeID, page, limit)
+	var departmentIDs []uint
+	if departmentID > 0 {
+		departmentIDs = []uint{departmentID}
+	}
+
+	attendances, pagination, err := s.attendanceRepo.GetAttendanceLogs(startDate, endDate, departmentIDs, employeeID, page, limit)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -177,6 +201,39 @@ func (s *AttendanceService) GetAttendanceLogs(startDate, endDate string, departm
 	return responses, pagination, nil
 }
 
+// GetTodayStatus returns whether the employee is clocked in today and when their shift ends
+func (s *AttendanceService) GetTodayStatus(employeeID string) (*models.TodayStatusResponse, error) {
+	employee, err := s.employeeRepo.FindByEmployeeID(employeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	status := &models.TodayStatusResponse{
+		EmployeeID: employeeID,
+		Date:       now.Format("2006-01-02"),
+	}
+
+	if maxClockOut, err := time.Parse("15:04:05", employee.Department.MaxClockOut); err == nil {
+		shiftEnd := time.Date(now.Year(), now.Month(), now.Day(),
+			maxClockOut.Hour(), maxClockOut.Minute(), maxClockOut.Second(), 0, now.Location())
+		status.ExpectedShiftEnd = &shiftEnd
+	}
+
+	attendance, _ := s.attendanceRepo.FindTodayAttendance(employeeID)
+	if attendance == nil || attendance.ID == 0 {
+		return status, nil
+	}
+
+	status.ClockedIn = true
+	status.ClockInAt = &attendance.ClockIn
+	status.ClockedOut = attendance.ClockOut != nil
+	status.ClockOutAt = attendance.ClockOut
+	status.Attendance = s.CalculateAttendancePunctuality(attendance)
+
+	return status, nil
+}
+
 func (s *AttendanceService) GetEmployeeAttendance(employeeID string, startDate, endDate string) ([]models.Attendance, error) {
 	return s.attendanceRepo.GetEmployeeAttendance(employeeID, startDate, endDate)
 }
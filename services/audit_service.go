@@ -0,0 +1,198 @@
+package services
+
+import (
+	"attendance-system/models"
+	"attendance-system/repositories"
+	"attendance-system/utils"
+	"encoding/json"
+	"log"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// auditExportLimit caps the rows in one CSV export; narrow the filter for more
+const auditExportLimit = 50000
+
+// auditIgnoredFields change on every update and would only add noise to the diff
+var auditIgnoredFields = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+}
+
+type AuditService struct {
+	auditRepo *repositories.AuditLogRepository
+}
+
+func NewAuditService() *AuditService {
+	return &AuditService{
+		auditRepo: repositories.NewAuditLogRepository(),
+	}
+}
+
+// Record writes an audit entry. before and after are snapshots taken with auditSnapshot;
+// before is nil for creations and after is nil for deletions. A failure is logged and
+// never fails the change being audited.
+func (s *AuditService) Record(actor models.AuditActor, action, entityType, entityID string, before, after map[string]interface{}) {
+	changes := auditDiff(before, after)
+	if before != nil && after != nil && len(changes) == 0 {
+		return // Nothing actually changed
+	}
+
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		log.Printf("Failed to encode audit changes for %s %s: %v", entityType, entityID, err)
+		return
+	}
+
+	if actor.Type == "" {
+		actor.Type = models.AuditActorAnonymous
+	}
+
+	entry := &models.AuditLog{
+		ActorType:  actor.Type,
+		ActorID:    actor.ID,
+		ActorName:  actor.Name,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Changes:    string(encoded),
+		IPAddress:  actor.IPAddress,
+		RequestID:  actor.RequestID,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.auditRepo.Create(entry); err != nil {
+		log.Printf("Failed to record audit entry %s for %s %s: %v", action, entityType, entityID, err)
+	}
+}
+
+// GetLogs lists audit entries matching the filter, newest first
+func (s *AuditService) GetLogs(filter models.AuditLogFilter, page, limit int) ([]models.AuditLogResponse, *repositories.Pagination, error) {
+	if err := validateAuditFilter(filter); err != nil {
+		return nil, nil, err
+	}
+
+	entries, pagination, err := s.auditRepo.FindAll(filter, page, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	responses := make([]models.AuditLogResponse, 0, len(entries))
+	for _, entry := range entries {
+		responses = append(responses, auditLogResponse(entry))
+	}
+	return responses, pagination, nil
+}
+
+// GetLogsForExport returns the entries matching the filter for a CSV export
+func (s *AuditService) GetLogsForExport(filter models.AuditLogFilter) ([]models.AuditLog, error) {
+	if err := validateAuditFilter(filter); err != nil {
+		return nil, err
+	}
+	return s.auditRepo.FindForExport(filter, auditExportLimit)
+}
+
+func validateAuditFilter(filter models.AuditLogFilter) error {
+	for _, date := range []string{filter.StartDate, filter.EndDate} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return utils.NewBadRequestError("dates must use the YYYY-MM-DD format")
+		}
+	}
+	return nil
+}
+
+func auditLogResponse(entry models.AuditLog) models.AuditLogResponse {
+	changes := map[string]models.AuditChange{}
+	if entry.Changes != "" {
+		_ = json.Unmarshal([]byte(entry.Changes), &changes)
+	}
+
+	return models.AuditLogResponse{
+		ID:         entry.ID,
+		ActorType:  entry.ActorType,
+		ActorID:    entry.ActorID,
+		ActorName:  entry.ActorName,
+		Action:     entry.Action,
+		EntityType: entry.EntityType,
+		EntityID:   entry.EntityID,
+		Changes:    changes,
+		IPAddress:  entry.IPAddress,
+		RequestID:  entry.RequestID,
+		CreatedAt:  entry.CreatedAt,
+	}
+}
+
+// auditID formats a primary key as an audit entity ID
+func auditID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// auditActorForUser attributes an anonymous request to the user a one-time token
+// (setup, password reset or email verification link) was issued to
+func auditActorForUser(actor models.AuditActor, user *models.User) models.AuditActor {
+	if actor.Type == models.AuditActorAnonymous || actor.Type == "" {
+		actor.Type = models.AuditActorUser
+		actor.ID = auditID(user.ID)
+		actor.Name = user.Username
+	}
+	return actor
+}
+
+// auditSnapshot captures an entity's fields as they are now, through its JSON form so
+// fields hidden from the API (password hashes, MFA secrets) never reach the audit log.
+// Nested relations are left out; their foreign keys are kept.
+func auditSnapshot(entity interface{}) map[string]interface{} {
+	encoded, err := json.Marshal(entity)
+	if err != nil {
+		return map[string]interface{}{}
+	}
+
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return map[string]interface{}{}
+	}
+
+	for name, value := range fields {
+		if auditIgnoredFields[name] || isNestedValue(value) {
+			delete(fields, name)
+		}
+	}
+	return fields
+}
+
+func isNestedValue(value interface{}) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return true
+	case []interface{}:
+		for _, item := range v {
+			if _, ok := item.(map[string]interface{}); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// auditDiff lists the fields whose values differ between two snapshots
+func auditDiff(before, after map[string]interface{}) map[string]models.AuditChange {
+	changes := map[string]models.AuditChange{}
+
+	for name, oldValue := range before {
+		newValue, exists := after[name]
+		if (after != nil && exists && reflect.DeepEqual(oldValue, newValue)) || (oldValue == nil && newValue == nil) {
+			continue
+		}
+		changes[name] = models.AuditChange{Old: oldValue, New: newValue}
+	}
+	for name, newValue := range after {
+		if _, exists := before[name]; !exists && newValue != nil {
+			changes[name] = models.AuditChange{New: newValue}
+		}
+	}
+
+	return changes
+}
@@ -5,58 +5,87 @@ import (
 	"attendance-system/repositories"
 	"attendance-system/utils"
 	"fmt"
+	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Lifetimes of the tokens handed out while a second factor is pending
+const (
+	mfaChallengeTTL  = 5 * time.Minute
+	mfaEnrollmentTTL = 10 * time.Minute
+	// Lifetime of the token handed out when the password has expired and must be changed
+	passwordChangeTTL = 10 * time.Minute
+)
+
 type AuthService struct {
-	userRepo     *repositories.UserRepository
-	employeeRepo *repositories.EmployeeRepository
-	emailService *ResendEmailService
+	userRepo         *repositories.UserRepository
+	employeeRepo     *repositories.EmployeeRepository
+	refreshTokenRepo *repositories.RefreshTokenRepository
+	revokedTokenRepo *repositories.RevokedTokenRepository
+	tokenService     *OneTimeTokenService
+	throttleService  *LoginThrottleService
+	mfaService       *MFAService
+	oidcService      *OIDCService
+	sessionService   *SessionService
+	emailService     *EmailService
+	roleService      *RoleService
+	passwordPolicy   *PasswordPolicyService
+	auditService     *AuditService
 }
 
 func NewAuthService() *AuthService {
 	return &AuthService{
-		userRepo:     repositories.NewUserRepository(),
-		employeeRepo: repositories.NewEmployeeRepository(),
-		emailService: NewEmailService(),
+		userRepo:         repositories.NewUserRepository(),
+		employeeRepo:     repositories.NewEmployeeRepository(),
+		refreshTokenRepo: repositories.NewRefreshTokenRepository(),
+		revokedTokenRepo: repositories.NewRevokedTokenRepository(),
+		tokenService:     NewOneTimeTokenService(),
+		throttleService:  NewLoginThrottleService(),
+		mfaService:       NewMFAService(),
+		oidcService:      NewOIDCService(),
+		sessionService:   NewSessionService(),
+		emailService:     NewEmailService(),
+		roleService:      NewRoleService(),
+		passwordPolicy:   NewPasswordPolicyService(),
+		auditService:     NewAuditService(),
 	}
 }
 
 // SetupAccount activates user account and sets password using setup token
-func (s *AuthService) SetupAccount(req models.UserSetupRequest) (*models.UserSetupResponse, error) {
-	// Find user by setup token
-	user, err := s.userRepo.FindBySetupToken(req.Token)
+func (s *AuthService) SetupAccount(req models.UserSetupRequest, actor models.AuditActor) (*models.UserSetupResponse, error) {
+	token, err := s.tokenService.Consume(req.Token, models.TokenPurposeAccountSetup)
 	if err != nil {
-		return nil, utils.NewNotFoundError("invalid or expired setup token")
-	}
-
-	// Check if token is still valid
-	if !user.IsSetupTokenValid() {
-		return nil, utils.NewBadRequestError("setup token has expired")
+		return nil, err
 	}
+	user := token.User
 
 	// Check if user is already active
 	if user.IsActive {
 		return nil, utils.NewBadRequestError("account is already active")
 	}
+	before := auditSnapshot(user)
 
 	// Update user with new password and activate account
-	user.Password = req.NewPassword
+	if err := s.passwordPolicy.SetPassword(user, req.NewPassword); err != nil {
+		return nil, err
+	}
 	user.ActivateUser()
-	user.UpdatedAt = time.Now()
 
-	if err := user.HashPassword(); err != nil {
-		return nil, utils.NewInternalServerError("failed to hash password")
-	}
+	// The setup link was delivered to this address, so it counts as verified
+	now := time.Now()
+	user.EmailVerifiedAt = &now
 
 	// Save the updated user
 	if err := s.userRepo.Update(user); err != nil {
 		return nil, utils.NewInternalServerError("failed to setup account")
 	}
+	s.passwordPolicy.RecordChange(user)
+	s.auditService.Record(auditActorForUser(actor, user), "user.account_setup", models.AuditEntityUser, auditID(user.ID), before, auditSnapshot(user))
 
 	// Send welcome email
 	if user.Email != "" {
@@ -64,7 +93,9 @@ func (s *AuthService) SetupAccount(req models.UserSetupRequest) (*models.UserSet
 		if user.Employee != nil {
 			employeeName = user.Employee.Name
 		}
-		go s.emailService.SendWelcomeEmail(user.Email, employeeName)
+		if err := s.emailService.SendWelcomeEmail(user.Email, user.Locale, employeeName); err != nil {
+			log.Printf("Failed to queue welcome email for user %d: %v", user.ID, err)
+		}
 	}
 
 	response := &models.UserSetupResponse{
@@ -76,31 +107,26 @@ func (s *AuthService) SetupAccount(req models.UserSetupRequest) (*models.UserSet
 }
 
 // VerifySetupToken checks if setup token is valid and returns user info
-func (s *AuthService) VerifySetupToken(token string) (*models.User, error) {
-	if token == "" {
+func (s *AuthService) VerifySetupToken(plain string) (*models.OneTimeToken, error) {
+	if plain == "" {
 		return nil, utils.NewBadRequestError("setup token is required")
 	}
 
-	user, err := s.userRepo.FindBySetupToken(token)
+	token, err := s.tokenService.Verify(plain, models.TokenPurposeAccountSetup)
 	if err != nil {
-		return nil, utils.NewNotFoundError("invalid setup token")
-	}
-
-	// Check if token is expired
-	if !user.IsSetupTokenValid() {
-		return nil, utils.NewBadRequestError("setup token has expired")
+		return nil, err
 	}
 
 	// Check if user is already active
-	if user.IsActive {
+	if token.User.IsActive {
 		return nil, utils.NewBadRequestError("account is already active")
 	}
 
-	return user, nil
+	return token, nil
 }
 
 // ForgotPassword initiates password reset process
-func (s *AuthService) ForgotPassword(email string) error {
+func (s *AuthService) ForgotPassword(email, requestIP string) error {
 	// Find user by email
 	user, err := s.userRepo.FindByEmail(email)
 	if err != nil {
@@ -114,59 +140,96 @@ func (s *AuthService) ForgotPassword(email string) error {
 		return nil
 	}
 
-	// Generate reset token
-	resetToken, err := models.GenerateSecureToken(32)
+	// Generate reset token (expires in 1 hour)
+	resetToken, err := s.tokenService.Issue(user.ID, models.TokenPurposePasswordReset, 1*time.Hour, requestIP)
 	if err != nil {
 		return utils.NewInternalServerError("failed to generate reset token")
 	}
 
-	// Set reset token (expires in 1 hour)
-	resetExpiry := 1 * time.Hour
-	user.SetupToken = &resetToken
-	expiry := time.Now().Add(resetExpiry)
-	user.TokenExpires = &expiry
-	user.UpdatedAt = time.Now()
+	// Send password reset email
+	if err := s.emailService.SendPasswordResetEmail(user.Email, user.Locale, resetToken); err != nil {
+		log.Printf("Failed to queue password reset email for user %d: %v", user.ID, err)
+	}
+
+	return nil
+}
+
+// ResetPassword resets user password using reset token
+func (s *AuthService) ResetPassword(plain, newPassword string, actor models.AuditActor) error {
+	token, err := s.tokenService.Consume(plain, models.TokenPurposePasswordReset)
+	if err != nil {
+		return err
+	}
+	user := token.User
+	before := auditSnapshot(user)
 
-	// Save user with reset token
+	// Update password
+	if err := s.passwordPolicy.SetPassword(user, newPassword); err != nil {
+		return err
+	}
+
+	// Save the updated user
 	if err := s.userRepo.Update(user); err != nil {
-		return utils.NewInternalServerError("failed to set reset token")
+		return utils.NewInternalServerError("failed to reset password")
 	}
+	s.passwordPolicy.RecordChange(user)
+	s.auditService.Record(auditActorForUser(actor, user), "user.password_reset", models.AuditEntityUser, auditID(user.ID), before, auditSnapshot(user))
 
-	// Send password reset email
-	go s.emailService.SendPasswordResetEmail(user.Email, resetToken)
+	// Whoever knew the old password should not stay signed in
+	if err := s.signOutBefore(user.ID, time.Now()); err != nil {
+		log.Printf("Failed to revoke tokens after password reset: %v", err)
+	}
 
 	return nil
 }
 
-// ResetPassword resets user password using reset token
-func (s *AuthService) ResetPassword(token, newPassword string) error {
-	// Find user by reset token
-	user, err := s.userRepo.FindBySetupToken(token)
+// RequestEmailVerification emails the user a link confirming they own their address
+func (s *AuthService) RequestEmailVerification(userID uint, requestIP string) error {
+	user, err := s.userRepo.FindByID(userID)
 	if err != nil {
-		return utils.NewNotFoundError("invalid or expired reset token")
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return utils.NewBadRequestError("email is already verified")
 	}
 
-	// Check if token is still valid
-	if user.TokenExpires == nil || user.TokenExpires.Before(time.Now()) {
-		return utils.NewBadRequestError("reset token has expired")
+	verifyToken, err := s.tokenService.Issue(user.ID, models.TokenPurposeEmailVerify, 24*time.Hour, requestIP)
+	if err != nil {
+		return utils.NewInternalServerError("failed to generate verification token")
 	}
 
-	// Update password and clear reset token
-	user.Password = newPassword
-	user.SetupToken = nil
-	user.TokenExpires = nil
-	user.UpdatedAt = time.Now()
+	if err := s.emailService.SendEmailVerificationEmail(user.Email, user.Locale, verifyToken); err != nil {
+		log.Printf("Failed to queue verification email for user %d: %v", user.ID, err)
+		return utils.NewInternalServerError("failed to send verification email")
+	}
+
+	return nil
+}
+
+// VerifyEmail marks the user's email as verified using an email-verify token
+func (s *AuthService) VerifyEmail(plain string, actor models.AuditActor) error {
+	token, err := s.tokenService.Consume(plain, models.TokenPurposeEmailVerify)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user := token.User
+	before := auditSnapshot(user)
+	user.EmailVerifiedAt = &now
+	user.UpdatedAt = now
 
-	// Save the updated user
 	if err := s.userRepo.Update(user); err != nil {
-		return utils.NewInternalServerError("failed to reset password")
+		return utils.NewInternalServerError("failed to verify email")
 	}
+	s.auditService.Record(auditActorForUser(actor, user), "user.email_verify", models.AuditEntityUser, auditID(user.ID), before, auditSnapshot(user))
 
 	return nil
 }
 
-// Enhanced Login method to handle inactive accounts with setup tokens
-func (s *AuthService) Login(req models.LoginRequest) (*models.LoginResponse, error) {
+// Login authenticates by username or email. Every failure returns the same message
+// so the response doesn't reveal whether the account exists, needs setup or is deactivated.
+func (s *AuthService) Login(req models.LoginRequest, client models.SessionClient) (*models.LoginResponse, error) {
 	var user *models.User
 	var err error
 
@@ -180,43 +243,277 @@ func (s *AuthService) Login(req models.LoginRequest) (*models.LoginResponse, err
 		}
 	}
 
-	// Check if user is active
-	if !user.IsActive {
-		// If user has a valid setup token, guide them to setup
-		if user.IsSetupTokenValid() {
-			return nil, utils.NewUnauthorizedError("Account setup required. Please check your email for setup instructions.")
-		}
-		return nil, utils.NewUnauthorizedError("Account Is Deactivated")
+	if err := s.throttleService.CheckAccount(user); err != nil {
+		return nil, err
 	}
 
 	// Check password
 	if !user.CheckPassword(req.Password) {
+		s.throttleService.RecordAccountFailure(user, req.Username, client.IPAddress)
 		return nil, utils.NewUnauthorizedError("Invalid Credentials")
 	}
 
-	// Generate JWT token
-	accessToken, expiresIn, err := s.generateJWTToken(user)
+	// Check if user is active
+	if !user.IsActive {
+		return nil, utils.NewUnauthorizedError("Invalid Credentials")
+	}
+
+	return s.finishFirstFactor(user, client)
+}
+
+// LoginWithOIDC completes single sign-on and signs in the matching user
+func (s *AuthService) LoginWithOIDC(req models.OIDCCallbackRequest, client models.SessionClient) (*models.LoginResponse, error) {
+	user, err := s.oidcService.Authenticate(req.Code, req.State)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.throttleService.CheckAccount(user); err != nil {
+		return nil, err
+	}
+
+	return s.finishFirstFactor(user, client)
+}
+
+// finishFirstFactor continues a login after the password or identity provider accepted the user
+func (s *AuthService) finishFirstFactor(user *models.User, client models.SessionClient) (*models.LoginResponse, error) {
+	// The first factor alone is not enough. The failure counter is left alone until the
+	// second factor passes, so knowing the password doesn't reset the lockout.
+	if user.MFAEnabled {
+		mfaToken, _, err := s.signToken(user, "", utils.TokenUseMFAChallenge, "", mfaChallengeTTL)
+		if err != nil {
+			return nil, utils.NewInternalServerError("failed to generate token")
+		}
+		return &models.LoginResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+		}, nil
+	}
+
+	s.throttleService.RecordAccountSuccess(user)
+
+	// Users whose role requires 2FA get a short-lived token that can only be used to enroll
+	if s.roleService.RoleRequiresMFA(user.Role) {
+		accessToken, expiresIn, err := s.signToken(user, "", utils.TokenUseAccess, utils.RestrictionMFAEnrollment, mfaEnrollmentTTL)
+		if err != nil {
+			return nil, utils.NewInternalServerError("failed to generate token")
+		}
+		return &models.LoginResponse{
+			User:        user.ToResponse(),
+			AccessToken: accessToken,
+			TokenType:   "Bearer",
+			ExpiresIn:   expiresIn,
+			Restriction: utils.RestrictionMFAEnrollment,
+		}, nil
+	}
+
+	return s.completeLogin(user, client)
+}
+
+// VerifyMFA exchanges the challenge token from Login and a second-factor code for access tokens
+func (s *AuthService) VerifyMFA(req models.MFAVerifyRequest, client models.SessionClient) (*models.LoginResponse, error) {
+	claims, err := utils.ValidateJWT(req.MFAToken)
+	if err != nil || claims.TokenUse != utils.TokenUseMFAChallenge || claims.ID == "" {
+		return nil, utils.NewUnauthorizedError("invalid or expired MFA token")
+	}
+
+	revoked, err := s.revokedTokenRepo.IsRevoked(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, utils.NewUnauthorizedError("invalid or expired MFA token")
+	}
+
+	userID, err := strconv.ParseUint(claims.UserID, 10, 32)
+	if err != nil {
+		return nil, utils.NewUnauthorizedError("invalid or expired MFA token")
+	}
+	user, err := s.userRepo.FindByID(uint(userID))
+	if err != nil || !user.IsActive || !user.MFAEnabled {
+		return nil, utils.NewUnauthorizedError("invalid or expired MFA token")
+	}
+
+	if err := s.throttleService.CheckAccount(user); err != nil {
+		return nil, err
+	}
+
+	ok, err := s.mfaService.VerifyCode(user, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		s.throttleService.RecordAccountFailure(user, user.Username, client.IPAddress)
+		return nil, utils.NewUnauthorizedError("invalid authentication code")
+	}
+
+	// The challenge token is single use
+	challenge := &models.RevokedToken{
+		JTI:       claims.ID,
+		UserID:    user.ID,
+		ExpiresAt: claims.ExpiresAt.Time,
+		CreatedAt: time.Now(),
+	}
+	if err := s.revokedTokenRepo.Create(challenge); err != nil {
+		return nil, utils.NewInternalServerError("failed to revoke token")
+	}
+
+	s.throttleService.RecordAccountSuccess(user)
+
+	return s.completeLogin(user, client)
+}
+
+// completeLogin starts a new session for a fully authenticated user
+func (s *AuthService) completeLogin(user *models.User, client models.SessionClient) (*models.LoginResponse, error) {
+	// An expired password gets a short-lived token that can only be used to change it
+	if s.passwordPolicy.IsExpired(user) {
+		accessToken, expiresIn, err := s.signToken(user, "", utils.TokenUseAccess, utils.RestrictionPasswordChange, passwordChangeTTL)
+		if err != nil {
+			return nil, utils.NewInternalServerError("failed to generate token")
+		}
+		return &models.LoginResponse{
+			User:        user.ToResponse(),
+			AccessToken: accessToken,
+			TokenType:   "Bearer",
+			ExpiresIn:   expiresIn,
+			Restriction: utils.RestrictionPasswordChange,
+		}, nil
+	}
+
+	// Start a new refresh token family for this login; its ID doubles as the session ID
+	familyID, err := models.GenerateSecureToken(16)
 	if err != nil {
 		return nil, utils.NewInternalServerError("failed to generate token")
 	}
 
+	if err := s.sessionService.Start(user.ID, familyID, client); err != nil {
+		return nil, utils.NewInternalServerError("failed to start session")
+	}
+
+	response, err := s.issueTokens(user, familyID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Update last login
 	if err := s.userRepo.UpdateLastLogin(user.ID); err != nil {
 		// Log but don't fail the login
 		fmt.Printf("Failed to update last login: %v\n", err)
 	}
 
-	// Create response
+	return response, nil
+}
+
+// RefreshTokens exchanges a refresh token for a new access token and rotates the refresh token
+func (s *AuthService) RefreshTokens(refreshToken string, client models.SessionClient) (*models.LoginResponse, error) {
+	stored, err := s.refreshTokenRepo.FindByHash(utils.HashToken(refreshToken))
+	if err != nil {
+		return nil, utils.NewUnauthorizedError("invalid refresh token")
+	}
+
+	if stored.RevokedAt != nil {
+		return nil, utils.NewUnauthorizedError("refresh token has been revoked")
+	}
+
+	// A token that was already rotated is being replayed, so assume the family is compromised
+	if stored.RotatedAt != nil {
+		s.revokeFamily(stored.FamilyID)
+		return nil, utils.NewUnauthorizedError("refresh token reuse detected, please log in again")
+	}
+
+	if stored.IsExpired() {
+		return nil, utils.NewUnauthorizedError("refresh token has expired")
+	}
+
+	rotated, err := s.refreshTokenRepo.MarkRotated(stored.ID)
+	if err != nil {
+		return nil, utils.NewInternalServerError("failed to rotate refresh token")
+	}
+	if !rotated {
+		// Lost a race with another refresh of the same token
+		s.revokeFamily(stored.FamilyID)
+		return nil, utils.NewUnauthorizedError("refresh token reuse detected, please log in again")
+	}
+
+	user, err := s.userRepo.FindByID(stored.UserID)
+	if err != nil {
+		s.revokeFamily(stored.FamilyID)
+		return nil, utils.NewUnauthorizedError("invalid refresh token")
+	}
+
+	if !user.IsActive {
+		s.revokeFamily(stored.FamilyID)
+		return nil, utils.NewUnauthorizedError("Account Is Deactivated")
+	}
+
+	// Permissions follow the role, so a role change requires a fresh login
+	if user.Role != stored.Role {
+		s.revokeFamily(stored.FamilyID)
+		return nil, utils.NewUnauthorizedError("account role has changed, please log in again")
+	}
+
+	// A role can start requiring 2FA after the session began
+	if !user.MFAEnabled && s.roleService.RoleRequiresMFA(user.Role) {
+		s.revokeFamily(stored.FamilyID)
+		return nil, utils.NewUnauthorizedError("two-factor authentication is required, please log in again")
+	}
+
+	s.sessionService.Touch(stored.FamilyID, client)
+
+	return s.issueTokens(user, stored.FamilyID)
+}
+
+// issueTokens creates an access token and a new refresh token in the given family
+func (s *AuthService) issueTokens(user *models.User, familyID string) (*models.LoginResponse, error) {
+	accessToken, expiresIn, err := s.generateJWTToken(user, familyID)
+	if err != nil {
+		return nil, utils.NewInternalServerError("failed to generate token")
+	}
+
+	refreshToken, err := models.GenerateSecureToken(32)
+	if err != nil {
+		return nil, utils.NewInternalServerError("failed to generate token")
+	}
+
+	stored := &models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: utils.HashToken(refreshToken),
+		Role:      user.Role,
+		ExpiresAt: time.Now().Add(s.getRefreshTokenExpiry()),
+		CreatedAt: time.Now(),
+	}
+	if err := s.refreshTokenRepo.Create(stored); err != nil {
+		return nil, utils.NewInternalServerError("failed to store refresh token")
+	}
+
 	response := &models.LoginResponse{
-		User:        user.ToResponse(),
-		AccessToken: accessToken,
-		TokenType:   "Bearer",
-		ExpiresIn:   expiresIn,
+		User:         user.ToResponse(),
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    expiresIn,
 	}
 
 	return response, nil
 }
 
+// revokeFamily revokes a refresh token family, logging failures since callers are already rejecting the request
+func (s *AuthService) revokeFamily(familyID string) {
+	if err := s.refreshTokenRepo.RevokeFamily(familyID); err != nil {
+		log.Printf("Failed to revoke refresh token family: %v", err)
+	}
+}
+
+// getRefreshTokenExpiry reads REFRESH_TOKEN_EXPIRY (default 30 days)
+func (s *AuthService) getRefreshTokenExpiry() time.Duration {
+	expiry, err := time.ParseDuration(os.Getenv("REFRESH_TOKEN_EXPIRY"))
+	if err != nil || expiry <= 0 {
+		return 30 * 24 * time.Hour
+	}
+	return expiry
+}
+
 // Helper method to get frontend URL
 func (s *AuthService) getFrontendURL() string {
 	frontendURL := os.Getenv("FRONTEND_URL")
@@ -226,7 +523,7 @@ func (s *AuthService) getFrontendURL() string {
 	return frontendURL
 }
 
-func (s *AuthService) Register(req models.UserRequest) (*models.UserResponse, error) {
+func (s *AuthService) Register(req models.UserRequest, actor models.AuditActor) (*models.UserResponse, error) {
 	// Check if username already exists
 	existingUser, _ := s.userRepo.FindByUsername(req.Username)
 	if existingUser != nil {
@@ -239,6 +536,10 @@ func (s *AuthService) Register(req models.UserRequest) (*models.UserResponse, er
 		return nil, utils.NewConflictError("email already exists")
 	}
 
+	if !s.roleService.RoleExists(req.Role) {
+		return nil, utils.NewBadRequestError("role not found")
+	}
+
 	// If employee_id is provided, verify it exists
 	var employeeID *string
 	if req.EmployeeID != "" {
@@ -249,7 +550,12 @@ func (s *AuthService) Register(req models.UserRequest) (*models.UserResponse, er
 		employeeID = &employee.EmployeeID
 	}
 
+	if err := s.passwordPolicy.Validate(req.Password, &models.User{Username: req.Username, Email: req.Email}); err != nil {
+		return nil, err
+	}
+
 	// Create user
+	now := time.Now()
 	user := &models.User{
 		Username:   req.Username,
 		Email:      req.Email,
@@ -257,13 +563,16 @@ func (s *AuthService) Register(req models.UserRequest) (*models.UserResponse, er
 		Role:       req.Role,
 		EmployeeID: employeeID,
 		IsActive:   true,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		CreatedAt:  now,
+		UpdatedAt:  now,
 	}
+	user.PasswordChangedAt = &now
 
 	if err := s.userRepo.Create(user); err != nil {
 		return nil, err
 	}
+	s.passwordPolicy.RecordChange(user)
+	s.auditService.Record(actor, "user.register", models.AuditEntityUser, auditID(user.ID), nil, auditSnapshot(user))
 
 	// Reload user to get relations
 	createdUser, err := s.userRepo.FindByID(user.ID)
@@ -275,17 +584,11 @@ func (s *AuthService) Register(req models.UserRequest) (*models.UserResponse, er
 	return &response, nil
 }
 
-func (s *AuthService) generateJWTToken(user *models.User) (string, int64, error) {
-	// Get JWT secret from environment
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "your-default-secret-key-change-in-production"
-	}
-
-	// Get token expiry from environment (default 24 hours)
+func (s *AuthService) generateJWTToken(user *models.User, sessionID string) (string, int64, error) {
+	// Get token expiry from environment (default 15 minutes, clients renew with the refresh token)
 	tokenExpiryStr := os.Getenv("JWT_EXPIRY")
 	if tokenExpiryStr == "" {
-		tokenExpiryStr = "24h"
+		tokenExpiryStr = "15m"
 	}
 
 	// Parse duration
@@ -294,34 +597,53 @@ func (s *AuthService) generateJWTToken(user *models.User) (string, int64, error)
 	if tokenExpiryStr != "" {
 		expiryDuration, err = time.ParseDuration(tokenExpiryStr)
 		if err != nil {
-			expiryDuration = 24 * time.Hour // Default to 24 hours
+			expiryDuration = 15 * time.Minute // Default to 15 minutes
 		}
 	} else {
-		expiryDuration = 24 * time.Hour
+		expiryDuration = 15 * time.Minute
 	}
 
+	return s.signToken(user, sessionID, utils.TokenUseAccess, "", expiryDuration)
+}
+
+// signToken creates a signed JWT for the user with the given use, restriction and lifetime
+func (s *AuthService) signToken(user *models.User, sessionID, use, restriction string, expiryDuration time.Duration) (string, int64, error) {
 	expiryTime := time.Now().Add(expiryDuration)
 	expiresIn := expiryTime.Unix()
 
+	// Link the token to the user's employee record for ownership checks
+	employeeID := ""
+	if user.EmployeeID != nil {
+		employeeID = *user.EmployeeID
+	}
+
+	// Unique token ID so the token can be revoked before it expires
+	jti, err := models.GenerateSecureToken(16)
+	if err != nil {
+		return "", 0, err
+	}
+
 	// Create claims
 	claims := utils.JWTClaims{
-		UserID:   strconv.FormatUint(uint64(user.ID), 10),
-		Username: user.Username,
-		Role:     user.Role,
+		UserID:      strconv.FormatUint(uint64(user.ID), 10),
+		Username:    user.Username,
+		Role:        user.Role,
+		EmployeeID:  employeeID,
+		SessionID:   sessionID,
+		TokenUse:    use,
+		Restriction: restriction,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
 			ExpiresAt: jwt.NewNumericDate(expiryTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "attendance-system",
+			Issuer:    utils.JWTIssuer,
 			Subject:   strconv.FormatUint(uint64(user.ID), 10),
 		},
 	}
 
-	// Create token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// Sign token
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+	// Sign with the active key; its kid lets verifiers pick the right public key
+	tokenString, err := utils.SignJWT(&claims)
 	if err != nil {
 		return "", 0, err
 	}
@@ -331,13 +653,172 @@ func (s *AuthService) generateJWTToken(user *models.User) (string, int64, error)
 
 func (s *AuthService) ValidateToken(tokenString string) (*utils.JWTClaims, error) {
 	claims, err := utils.ValidateJWT(tokenString)
+	if err != nil || claims.ID == "" || claims.IssuedAt == nil || claims.TokenUse != utils.TokenUseAccess {
+		return nil, utils.NewUnauthorizedError("invalid token")
+	}
+
+	revoked, err := s.revokedTokenRepo.IsRevoked(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, utils.NewUnauthorizedError("token has been revoked")
+	}
+
+	if claims.SessionID != "" {
+		if err := s.sessionService.Validate(claims.SessionID); err != nil {
+			return nil, err
+		}
+	}
+
+	// The token must still describe the account as it is now
+	userID, err := strconv.ParseUint(claims.UserID, 10, 32)
+	if err != nil {
+		return nil, utils.NewUnauthorizedError("invalid token")
+	}
+	user, err := s.userRepo.FindByID(uint(userID))
 	if err != nil {
 		return nil, utils.NewUnauthorizedError("invalid token")
 	}
+	if !user.IsActive {
+		return nil, utils.NewUnauthorizedError("account is deactivated")
+	}
+	if user.Role != claims.Role {
+		return nil, utils.NewUnauthorizedError("account role has changed, please log in again")
+	}
+	if user.TokensValidAfter != nil && !claims.IssuedAt.Time.After(*user.TokensValidAfter) {
+		return nil, utils.NewUnauthorizedError("token has been revoked")
+	}
+
 	return claims, nil
 }
 
-func (s *AuthService) ChangePassword(userID uint, currentPassword, newPassword string) error {
+// Logout revokes the presented access token and the refresh token family it was issued with
+func (s *AuthService) Logout(claims *utils.JWTClaims) error {
+	userID, err := strconv.ParseUint(claims.UserID, 10, 32)
+	if err != nil {
+		return utils.NewBadRequestError("invalid user ID")
+	}
+
+	revoked := &models.RevokedToken{
+		JTI:       claims.ID,
+		UserID:    uint(userID),
+		ExpiresAt: claims.ExpiresAt.Time,
+		CreatedAt: time.Now(),
+	}
+	if err := s.revokedTokenRepo.Create(revoked); err != nil {
+		return utils.NewInternalServerError("failed to revoke token")
+	}
+
+	if claims.SessionID != "" {
+		if err := s.sessionService.Revoke(claims.SessionID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SignOutEverywhere invalidates every access and refresh token issued to the user before the given time
+func (s *AuthService) SignOutEverywhere(userID uint, before time.Time, actor models.AuditActor) error {
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		return err
+	}
+
+	if err := s.signOutBefore(userID, before); err != nil {
+		return err
+	}
+	s.auditService.Record(actor, "user.sign_out_all", models.AuditEntityUser, auditID(userID), nil, nil)
+	return nil
+}
+
+// signOutBefore revokes the user's tokens and sessions issued before the given time
+func (s *AuthService) signOutBefore(userID uint, before time.Time) error {
+	now := time.Now()
+	if before.IsZero() || before.After(now) {
+		before = now
+	}
+	// Token iat has second precision, so compare whole seconds
+	before = before.Truncate(time.Second)
+
+	if err := s.userRepo.SetTokensValidAfter(userID, before); err != nil {
+		return utils.NewInternalServerError("failed to revoke tokens")
+	}
+	if err := s.refreshTokenRepo.RevokeUserTokensBefore(userID, before.Add(time.Second)); err != nil {
+		return utils.NewInternalServerError("failed to revoke refresh tokens")
+	}
+	if err := s.sessionService.RevokeAllBefore(userID, before.Add(time.Second)); err != nil {
+		return utils.NewInternalServerError("failed to revoke sessions")
+	}
+
+	return nil
+}
+
+// PruneExpiredTokens removes revocation entries, refresh tokens, one-time tokens and unfinished
+// SSO logins that have expired on their own, sessions idle for longer than a refresh token
+// lasts, and failed attempts too old to count towards throttling
+func (s *AuthService) PruneExpiredTokens() error {
+	now := time.Now()
+	if _, err := s.revokedTokenRepo.DeleteExpired(now); err != nil {
+		return err
+	}
+	if _, err := s.refreshTokenRepo.DeleteExpired(now); err != nil {
+		return err
+	}
+	if err := s.tokenService.PruneExpired(); err != nil {
+		return err
+	}
+	if err := s.sessionService.PruneInactive(now.Add(-s.getRefreshTokenExpiry())); err != nil {
+		return err
+	}
+	if err := s.oidcService.PruneExpired(); err != nil {
+		return err
+	}
+	return s.throttleService.PruneAttempts()
+}
+
+// StartTokenPruner periodically prunes expired token records in the background
+func StartTokenPruner(interval time.Duration) {
+	authService := NewAuthService()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := authService.PruneExpiredTokens(); err != nil {
+				log.Printf("Failed to prune expired tokens: %v", err)
+			}
+		}
+	}()
+}
+
+// GetSessions lists the user's active sessions, flagging the one with the given ID as current
+func (s *AuthService) GetSessions(userID uint, currentSessionID string) ([]models.SessionResponse, error) {
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		return nil, err
+	}
+	return s.sessionService.GetUserSessions(userID, currentSessionID, s.getRefreshTokenExpiry())
+}
+
+// RevokeSession signs one of the user's devices out
+func (s *AuthService) RevokeSession(userID, sessionID uint, actor models.AuditActor) error {
+	if err := s.sessionService.RevokeUserSession(userID, sessionID); err != nil {
+		return err
+	}
+	s.auditService.Record(actor, "session.revoke", models.AuditEntitySession, auditID(sessionID), nil, nil)
+	return nil
+}
+
+// GetPasswordPolicy returns the rules new passwords must satisfy
+func (s *AuthService) GetPasswordPolicy() models.PasswordPolicyResponse {
+	return s.passwordPolicy.GetPolicy()
+}
+
+// GetPermissions resolves the permission codes granted to a role
+func (s *AuthService) GetPermissions(role string) ([]string, error) {
+	return s.roleService.GetPermissionsForRole(role)
+}
+
+func (s *AuthService) ChangePassword(userID uint, currentPassword, newPassword string, actor models.AuditActor) error {
 	user, err := s.userRepo.FindByID(userID)
 	if err != nil {
 		return utils.NewNotFoundError("user not found")
@@ -347,16 +828,18 @@ func (s *AuthService) ChangePassword(userID uint, currentPassword, newPassword s
 	if !user.CheckPassword(currentPassword) {
 		return utils.NewBadRequestError("current password is incorrect")
 	}
+	before := auditSnapshot(user)
 
 	// Update password
-	user.Password = newPassword
-	if err := user.HashPassword(); err != nil {
-		return utils.NewInternalServerError("failed to hash password")
+	if err := s.passwordPolicy.SetPassword(user, newPassword); err != nil {
+		return err
 	}
 
 	if err := s.userRepo.Update(user); err != nil {
 		return utils.NewInternalServerError("failed to update password")
 	}
+	s.passwordPolicy.RecordChange(user)
+	s.auditService.Record(actor, "user.password_change", models.AuditEntityUser, auditID(user.ID), before, auditSnapshot(user))
 
 	return nil
 }
@@ -370,11 +853,12 @@ func (s *AuthService) GetUserProfile(userID uint) (*models.UserResponse, error)
 	return &response, nil
 }
 
-func (s *AuthService) UpdateProfile(userID uint, req map[string]interface{}) (*models.UserResponse, error) {
+func (s *AuthService) UpdateProfile(userID uint, req map[string]interface{}, actor models.AuditActor) (*models.UserResponse, error) {
 	user, err := s.userRepo.FindByID(userID)
 	if err != nil {
 		return nil, err
 	}
+	before := auditSnapshot(user)
 
 	// Update allowed fields
 	if email, ok := req["email"].(string); ok && email != "" {
@@ -383,7 +867,10 @@ func (s *AuthService) UpdateProfile(userID uint, req map[string]interface{}) (*m
 		if existingUser != nil && existingUser.ID != userID {
 			return nil, utils.NewConflictError("email already taken")
 		}
-		user.Email = email
+		if email != user.Email {
+			user.Email = email
+			user.EmailVerifiedAt = nil
+		}
 	}
 
 	if username, ok := req["username"].(string); ok && username != "" {
@@ -395,11 +882,19 @@ func (s *AuthService) UpdateProfile(userID uint, req map[string]interface{}) (*m
 		user.Username = username
 	}
 
+	if locale, ok := req["locale"].(string); ok {
+		if locale != "" && !models.IsSupportedLocale(locale) {
+			return nil, utils.NewBadRequestError("locale must be one of " + strings.Join(models.SupportedLocales, ", "))
+		}
+		user.Locale = locale
+	}
+
 	user.UpdatedAt = time.Now()
 
 	if err := s.userRepo.Update(user); err != nil {
 		return nil, err
 	}
+	s.auditService.Record(actor, "user.profile_update", models.AuditEntityUser, auditID(user.ID), before, auditSnapshot(user))
 
 	response := user.ToResponse()
 	return &response, nil
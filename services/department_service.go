@@ -3,11 +3,13 @@ package services
 import (
 	"attendance-system/models"
 	"attendance-system/repositories"
+	"attendance-system/utils"
 	"time"
 )
 
 type DepartmentService struct {
 	departmentRepo *repositories.DepartmentRepository
+	auditService   *AuditService
 }
 
 // DepartmentRepo getter untuk akses dari controller
@@ -18,11 +20,17 @@ func (s *DepartmentService) DepartmentRepo() *repositories.DepartmentRepository
 func NewDepartmentService() *DepartmentService {
 	return &DepartmentService{
 		departmentRepo: repositories.NewDepartmentRepository(),
+		auditService:   NewAuditService(),
 	}
 }
 
-func (s *DepartmentService) CreateDepartment(req models.DepartmentRequest) (*models.Department, error) {
+func (s *DepartmentService) CreateDepartment(req models.DepartmentRequest, actor models.AuditActor) (*models.Department, error) {
+	if err := s.validateParent(0, req.ParentID); err != nil {
+		return nil, err
+	}
+
 	department := &models.Department{
+		ParentID:          req.ParentID,
 		Name:              req.Name,
 		Description:       req.Description,
 		MaxClockIn:        req.MaxClockIn,
@@ -47,6 +55,7 @@ func (s *DepartmentService) CreateDepartment(req models.DepartmentRequest) (*mod
 	if err := s.departmentRepo.Create(department); err != nil {
 		return nil, err
 	}
+	s.auditService.Record(actor, "department.create", models.AuditEntityDepartment, auditID(department.ID), nil, auditSnapshot(department))
 
 	return department, nil
 }
@@ -59,12 +68,18 @@ func (s *DepartmentService) GetDepartmentByID(id uint) (*models.Department, erro
 	return s.departmentRepo.FindByID(id)
 }
 
-func (s *DepartmentService) UpdateDepartment(id uint, req models.DepartmentRequest) (*models.Department, error) {
+func (s *DepartmentService) UpdateDepartment(id uint, req models.DepartmentRequest, actor models.AuditActor) (*models.Department, error) {
 	department, err := s.departmentRepo.FindByID(id)
 	if err != nil {
 		return nil, err
 	}
+	before := auditSnapshot(department)
+
+	if err := s.validateParent(id, req.ParentID); err != nil {
+		return nil, err
+	}
 
+	department.ParentID = req.ParentID
 	department.Name = req.Name
 	department.Description = req.Description
 	department.MaxClockIn = req.MaxClockIn
@@ -77,12 +92,22 @@ func (s *DepartmentService) UpdateDepartment(id uint, req models.DepartmentReque
 	if err := s.departmentRepo.Update(department); err != nil {
 		return nil, err
 	}
+	s.auditService.Record(actor, "department.update", models.AuditEntityDepartment, auditID(department.ID), before, auditSnapshot(department))
 
 	return department, nil
 }
 
-func (s *DepartmentService) DeleteDepartment(id uint) error {
-	return s.departmentRepo.Delete(id)
+func (s *DepartmentService) DeleteDepartment(id uint, actor models.AuditActor) error {
+	department, err := s.departmentRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.departmentRepo.Delete(id); err != nil {
+		return err
+	}
+	s.auditService.Record(actor, "department.delete", models.AuditEntityDepartment, auditID(department.ID), auditSnapshot(department), nil)
+	return nil
 }
 
 func (s *DepartmentService) GetDepartmentWithEmployees(id uint) (*models.Department, error) {
@@ -91,4 +116,84 @@ func (s *DepartmentService) GetDepartmentWithEmployees(id uint) (*models.Departm
 
 func (s *DepartmentService) GetActiveDepartments() ([]models.Department, error) {
 	return s.departmentRepo.GetActiveDepartments()
-}
\ No newline at end of file
+}
+
+// validateParent makes sure the parent exists and would not create a cycle
+func (s *DepartmentService) validateParent(id uint, parentID *uint) error {
+	if parentID == nil {
+		return nil
+	}
+	if *parentID == id {
+		return utils.NewBadRequestError("department cannot be its own parent")
+	}
+	if _, err := s.departmentRepo.FindByID(*parentID); err != nil {
+		return utils.NewBadRequestError("parent department not found")
+	}
+	if id == 0 {
+		return nil
+	}
+
+	descendantIDs, err := s.departmentRepo.GetDescendantIDs(id)
+	if err != nil {
+		return err
+	}
+	for _, descendantID := range descendantIDs {
+		if descendantID == *parentID {
+			return utils.NewBadRequestError("parent department cannot be one of its sub-departments")
+		}
+	}
+	return nil
+}
+
+// GetDepartmentSubtree returns the department with all of its nested sub-departments
+func (s *DepartmentService) GetDepartmentSubtree(id uint) (*models.DepartmentTreeNode, error) {
+	departments, err := s.departmentRepo.FindAllDepartments()
+	if err != nil {
+		return nil, err
+	}
+
+	childrenByParent := make(map[uint][]models.Department)
+	var root *models.Department
+	for i := range departments {
+		if departments[i].ID == id {
+			root = &departments[i]
+		}
+		if departments[i].ParentID != nil {
+			parentID := *departments[i].ParentID
+			childrenByParent[parentID] = append(childrenByParent[parentID], departments[i])
+		}
+	}
+	if root == nil {
+		return nil, utils.NewNotFoundError("department not found")
+	}
+
+	var build func(department models.Department, visited map[uint]bool) models.DepartmentTreeNode
+	build = func(department models.Department, visited map[uint]bool) models.DepartmentTreeNode {
+		visited[department.ID] = true
+		node := models.DepartmentTreeNode{
+			DepartmentResponse: department.ToResponse(),
+			Children:           []models.DepartmentTreeNode{},
+		}
+		for _, child := range childrenByParent[department.ID] {
+			if !visited[child.ID] {
+				node.Children = append(node.Children, build(child, visited))
+			}
+		}
+		return node
+	}
+
+	tree := build(*root, make(map[uint]bool))
+	return &tree, nil
+}
+
+// GetDepartmentScope returns the department IDs covered by a department filter,
+// optionally including every sub-department below it
+func (s *DepartmentService) GetDepartmentScope(id uint, includeSubDepartments bool) ([]uint, error) {
+	if id == 0 {
+		return nil, nil
+	}
+	if !includeSubDepartments {
+		return []uint{id}, nil
+	}
+	return s.departmentRepo.GetDescendantIDs(id)
+}
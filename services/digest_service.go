@@ -0,0 +1,380 @@
+package services
+
+import (
+	"attendance-system/config"
+	"attendance-system/models"
+	"attendance-system/repositories"
+	"attendance-system/utils"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+// digestTrendDays is how many days back the daily digest's trend reaches
+const digestTrendDays = 7
+
+// ManagerDigest is what a manager's digest email reports about their department and
+// its sub-departments
+type ManagerDigest struct {
+	Weekly         bool   `json:"weekly"`
+	PeriodStart    string `json:"period_start"` // YYYY-MM-DD
+	PeriodEnd      string `json:"period_end"`   // Same as the start for daily digests
+	DepartmentName string `json:"department_name"`
+	TotalEmployees int    `json:"total_employees"`
+	// Totals over the working days of the period
+	Present          int           `json:"present"`
+	Late             int           `json:"late"`
+	Absent           int           `json:"absent"`
+	LateArrivals     []DigestEntry `json:"late_arrivals"`
+	Absences         []DigestEntry `json:"absences"`
+	OpenSessions     []DigestEntry `json:"open_sessions"` // Clocked in during the period and never clocked out
+	PendingApprovals int64         `json:"pending_approvals"`
+	Trend            []DigestDay   `json:"trend"`
+}
+
+// DigestEntry is an employee listed in a digest. Daily digests set Time and Minutes for
+// each late arrival; weekly digests count the days and add up the minutes.
+type DigestEntry struct {
+	EmployeeID   string `json:"employee_id"`
+	EmployeeName string `json:"employee_name"`
+	Date         string `json:"date,omitempty"`
+	Time         string `json:"time,omitempty"` // HH:MM clock-in
+	Minutes      int    `json:"minutes,omitempty"`
+	Days         int    `json:"days,omitempty"`
+}
+
+// DigestDay is one working day of a digest's trend
+type DigestDay struct {
+	Date    string `json:"date"`
+	Present int    `json:"present"`
+	Late    int    `json:"late"`
+	Absent  int    `json:"absent"`
+	Rate    int    `json:"rate"` // Percentage of employees present
+}
+
+// DigestService builds and sends the daily and weekly digest emails managers opt in to
+// in their notification preferences
+type DigestService struct {
+	notificationRepo  *repositories.NotificationRepository
+	userRepo          *repositories.UserRepository
+	employeeRepo      *repositories.EmployeeRepository
+	departmentService *DepartmentService
+	reportService     *ReportService
+	roleService       *RoleService
+	emailService      *EmailService
+	frontendURL       string
+	defaultTime       string
+}
+
+func NewDigestService() *DigestService {
+	defaultTime := os.Getenv("DIGEST_DEFAULT_TIME")
+	if _, err := time.Parse("15:04", defaultTime); err != nil {
+		defaultTime = "07:00"
+	}
+
+	return &DigestService{
+		notificationRepo:  repositories.NewNotificationRepository(),
+		userRepo:          repositories.NewUserRepository(),
+		employeeRepo:      repositories.NewEmployeeRepository(),
+		departmentService: NewDepartmentService(),
+		reportService:     NewReportService(),
+		roleService:       NewRoleService(),
+		emailService:      NewEmailService(),
+		frontendURL:       config.GetConfig().FrontendURL,
+		defaultTime:       defaultTime,
+	}
+}
+
+// CanReceiveDigest reports whether the user may get digests: an active account linked to
+// an employee, with a role that can view the manager dashboard
+func (s *DigestService) CanReceiveDigest(user *models.User) (bool, error) {
+	if !user.IsActive || user.EmployeeID == nil {
+		return false, nil
+	}
+	permissions, err := s.roleService.GetPermissionsForRole(user.Role)
+	if err != nil {
+		return false, err
+	}
+	for _, permission := range permissions {
+		if permission == models.PermissionDashboardManager {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Preview builds the digest the user would get now, without sending it
+func (s *DigestService) Preview(userID uint, weekly bool) (*ManagerDigest, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	allowed, err := s.CanReceiveDigest(user)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, utils.NewForbiddenError("digests are only available to managers linked to an employee")
+	}
+	return s.Build(user, weekly, time.Now())
+}
+
+// SendDue sends the digests whose send time has passed today and that haven't gone out yet
+func (s *DigestService) SendDue(now time.Time) error {
+	preferences, err := s.notificationRepo.FindDigestSubscribers()
+	if err != nil {
+		return err
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	for i := range preferences {
+		preference := &preferences[i]
+		sendTime := s.defaultTime
+		if preference.DigestTime != nil {
+			sendTime = *preference.DigestTime
+		}
+		sendAt, ok := shiftTime(now, sendTime+":00")
+		if !ok || now.Before(sendAt) {
+			continue
+		}
+
+		if preference.DigestDaily && utils.IsWorkingDay(now) {
+			s.sendOnce(preference, false, today, now)
+		}
+		if preference.DigestWeekly && now.Weekday() == time.Monday {
+			s.sendOnce(preference, true, today, now)
+		}
+	}
+
+	return nil
+}
+
+// sendOnce claims the user's digest for the day and sends it. Failures are logged so one
+// manager's digest doesn't hold up the others.
+func (s *DigestService) sendOnce(preference *models.NotificationPreference, weekly bool, today, now time.Time) {
+	claimed, err := s.notificationRepo.ClaimDigest(preference.ID, weekly, today)
+	if err != nil {
+		log.Printf("Failed to claim digest for user %d: %v", preference.UserID, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+	if err := s.send(preference.UserID, weekly, now); err != nil {
+		log.Printf("Failed to send digest to user %d: %v", preference.UserID, err)
+	}
+}
+
+func (s *DigestService) send(userID uint, weekly bool, now time.Time) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	allowed, err := s.CanReceiveDigest(user)
+	if err != nil || !allowed || user.Email == "" {
+		return err
+	}
+
+	digest, err := s.Build(user, weekly, now)
+	if err != nil {
+		return err
+	}
+
+	name := user.Username
+	if user.Employee != nil {
+		name = user.Employee.Name
+	}
+	return s.emailService.SendEmail(models.EmailKindManagerDigest, user.Email, user.Locale, EmailTemplateData{
+		Name:      name,
+		ActionURL: s.frontendURL,
+		Digest:    *digest,
+	})
+}
+
+// Build reports on the manager's department and its sub-departments. A daily digest
+// covers the last working day before now, with a trend over the week up to it. A weekly
+// digest covers the previous Monday to Sunday, with a trend over its working days.
+func (s *DigestService) Build(user *models.User, weekly bool, now time.Time) (*ManagerDigest, error) {
+	manager, err := s.employeeRepo.FindByEmployeeID(*user.EmployeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var periodStart, periodEnd, trendStart time.Time
+	if weekly {
+		daysSinceMonday := (int(today.Weekday()) + 6) % 7
+		periodStart = today.AddDate(0, 0, -daysSinceMonday-7)
+		periodEnd = periodStart.AddDate(0, 0, 6)
+		trendStart = periodStart
+	} else {
+		periodStart = today.AddDate(0, 0, -1)
+		for i := 0; i < 14 && !utils.IsWorkingDay(periodStart); i++ {
+			periodStart = periodStart.AddDate(0, 0, -1)
+		}
+		periodEnd = periodStart
+		trendStart = periodEnd.AddDate(0, 0, -(digestTrendDays - 1))
+	}
+
+	departmentIDs, err := s.departmentService.GetDepartmentScope(manager.DepartmentID, true)
+	if err != nil {
+		return nil, err
+	}
+	employees, err := s.employeeRepo.FindByDepartments(departmentIDs)
+	if err != nil {
+		return nil, err
+	}
+	var roster []models.Employee
+	for _, employee := range employees {
+		if employee.Status == "active" {
+			roster = append(roster, employee)
+		}
+	}
+
+	pendingApprovals, err := s.notificationRepo.CountUnreadInCategories(user.ID, []string{
+		models.NotificationCategoryApproval,
+		models.NotificationCategoryCorrection,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	digest := &ManagerDigest{
+		Weekly:           weekly,
+		PeriodStart:      periodStart.Format("2006-01-02"),
+		PeriodEnd:        periodEnd.Format("2006-01-02"),
+		DepartmentName:   manager.Department.Name,
+		TotalEmployees:   len(roster),
+		LateArrivals:     []DigestEntry{},
+		Absences:         []DigestEntry{},
+		OpenSessions:     []DigestEntry{},
+		PendingApprovals: pendingApprovals,
+		Trend:            []DigestDay{},
+	}
+
+	attended := make(map[string]map[string]AttendanceReport) // Date, then employee ID
+	err = s.reportService.StreamAttendanceReport(trendStart.Format("2006-01-02"), periodEnd.Format("2006-01-02"), manager.DepartmentID, true, func(rows []AttendanceReport) error {
+		for _, row := range rows {
+			if attended[row.Date] == nil {
+				attended[row.Date] = make(map[string]AttendanceReport)
+			}
+			attended[row.Date][row.EmployeeID] = row
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lateByEmployee := make(map[string]*DigestEntry)
+	absentByEmployee := make(map[string]*DigestEntry)
+
+	for day := trendStart; !day.After(periodEnd); day = day.AddDate(0, 0, 1) {
+		if !utils.IsWorkingDay(day) {
+			continue
+		}
+		date := day.Format("2006-01-02")
+		inPeriod := !day.Before(periodStart)
+		trend := DigestDay{Date: date}
+		expected := 0
+
+		for _, employee := range roster {
+			if !employee.JoinDate.Before(day.AddDate(0, 0, 1)) {
+				continue // Not employed yet
+			}
+			expected++
+			row, present := attended[date][employee.EmployeeID]
+
+			if !present {
+				trend.Absent++
+				if inPeriod {
+					addDigestDay(absentByEmployee, employee.EmployeeID, employee.Name, date)
+				}
+				continue
+			}
+
+			trend.Present++
+			if row.Status == "late" {
+				trend.Late++
+				if inPeriod {
+					entry := addDigestDay(lateByEmployee, employee.EmployeeID, employee.Name, date)
+					entry.Minutes += row.LateMinutes
+					entry.Time = row.ClockIn.Format("15:04")
+				}
+			}
+			if inPeriod && row.ClockOut.IsZero() && day.Before(today) {
+				digest.OpenSessions = append(digest.OpenSessions, DigestEntry{
+					EmployeeID:   employee.EmployeeID,
+					EmployeeName: employee.Name,
+					Date:         date,
+					Time:         row.ClockIn.Format("15:04"),
+				})
+			}
+		}
+
+		if expected > 0 {
+			trend.Rate = trend.Present * 100 / expected
+		}
+		digest.Trend = append(digest.Trend, trend)
+		if inPeriod {
+			digest.Present += trend.Present
+			digest.Late += trend.Late
+			digest.Absent += trend.Absent
+		}
+	}
+
+	digest.LateArrivals = sortedDigestEntries(lateByEmployee, weekly)
+	digest.Absences = sortedDigestEntries(absentByEmployee, weekly)
+	sort.Slice(digest.OpenSessions, func(i, j int) bool {
+		if digest.OpenSessions[i].Date != digest.OpenSessions[j].Date {
+			return digest.OpenSessions[i].Date < digest.OpenSessions[j].Date
+		}
+		return digest.OpenSessions[i].EmployeeName < digest.OpenSessions[j].EmployeeName
+	})
+
+	return digest, nil
+}
+
+// addDigestDay counts a day for the employee's entry, creating it on their first day
+func addDigestDay(entries map[string]*DigestEntry, employeeID, name, date string) *DigestEntry {
+	entry, ok := entries[employeeID]
+	if !ok {
+		entry = &DigestEntry{EmployeeID: employeeID, EmployeeName: name, Date: date}
+		entries[employeeID] = entry
+	}
+	entry.Days++
+	return entry
+}
+
+// sortedDigestEntries lists the entries by name. Weekly digests count days, so the
+// date and time of a single day are dropped from them.
+func sortedDigestEntries(entries map[string]*DigestEntry, weekly bool) []DigestEntry {
+	list := make([]DigestEntry, 0, len(entries))
+	for _, entry := range entries {
+		if weekly {
+			entry.Date = ""
+			entry.Time = ""
+		} else {
+			entry.Days = 0
+		}
+		list = append(list, *entry)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].EmployeeName < list[j].EmployeeName })
+	return list
+}
+
+// StartDigestScheduler sends due manager digests in the background every interval
+func StartDigestScheduler(interval time.Duration) {
+	digestService := NewDigestService()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for now := range ticker.C {
+			if err := digestService.SendDue(now); err != nil {
+				log.Printf("Failed to send manager digests: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// unsafeFilenameChars are replaced when a recipient address becomes part of a file name
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9@._-]+`)
+
+// FileSender writes each message as an .eml file instead of sending it, for test
+// environments without internet access. The files open in any mail client.
+type FileSender struct {
+	dir string
+}
+
+func NewFileSender(dir string) (*FileSender, error) {
+	if dir == "" {
+		dir = "emails"
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create email directory %s: %v", dir, err)
+	}
+	return &FileSender{dir: dir}, nil
+}
+
+func (s *FileSender) Send(message EmailMessage) error {
+	data, err := buildMIMEMessage(message)
+	if err != nil {
+		return fmt.Errorf("failed to build email: %v", err)
+	}
+
+	recipient := "unknown"
+	if len(message.To) > 0 {
+		recipient = unsafeFilenameChars.ReplaceAllString(envelopeAddress(message.To[0]), "_")
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405.000000000"), recipient)
+	path := filepath.Join(s.dir, name)
+
+	if err := os.WriteFile(path, data, 0o640); err != nil {
+		return fmt.Errorf("failed to write email file: %v", err)
+	}
+
+	fmt.Printf("📧 Email to %v written to %s\n", message.To, path)
+	return nil
+}
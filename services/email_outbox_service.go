@@ -0,0 +1,303 @@
+package services
+
+import (
+	"attendance-system/config"
+	"attendance-system/models"
+	"attendance-system/repositories"
+	"attendance-system/utils"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// emailSendLease is how long a claimed email is left alone before another worker
+	// may retry it, well above the senders' own 30 second timeouts
+	emailSendLease = 5 * time.Minute
+	// emailWorkerBatch is the most emails the worker sends per run
+	emailWorkerBatch = 20
+	// maxEmailErrorLength fits the last_error column
+	maxEmailErrorLength = 500
+)
+
+// outboxWake nudges the worker when an email is queued, so it goes out without waiting
+// for the next tick
+var outboxWake = make(chan struct{}, 1)
+
+// EmailOutboxService queues emails in the database and delivers them in the background,
+// retrying failed sends with exponential backoff until they go out or are marked dead
+type EmailOutboxService struct {
+	outboxRepo   *repositories.EmailOutboxRepository
+	auditService *AuditService
+	sender       EmailSender
+	fromEmail    string
+	maxAttempts  int
+	retryBase    time.Duration
+	retryMax     time.Duration
+	retention    time.Duration
+}
+
+func NewEmailOutboxService() *EmailOutboxService {
+	config := config.GetConfig()
+
+	sender, err := NewEmailSender(config)
+	if err != nil {
+		// main checks the configuration at startup, so this only happens in tools and tests
+		log.Printf("Email transport misconfigured, logging emails instead: %v", err)
+		sender = &logSender{}
+	}
+
+	return &EmailOutboxService{
+		outboxRepo:   repositories.NewEmailOutboxRepository(),
+		auditService: NewAuditService(),
+		sender:       sender,
+		fromEmail:    config.FromEmail,
+		maxAttempts:  getIntEnv("EMAIL_MAX_ATTEMPTS", 8),
+		retryBase:    getDurationEnv("EMAIL_RETRY_BASE", time.Minute),
+		retryMax:     getDurationEnv("EMAIL_RETRY_MAX", time.Hour),
+		retention:    time.Duration(getIntEnv("EMAIL_OUTBOX_RETENTION_DAYS", 30)) * 24 * time.Hour,
+	}
+}
+
+// Enqueue stores an email for delivery by the worker
+func (s *EmailOutboxService) Enqueue(kind, to, subject, htmlBody, textBody string) error {
+	return s.EnqueueAt(kind, to, subject, htmlBody, textBody, time.Now())
+}
+
+// EnqueueAt stores an email for delivery by the worker no earlier than sendAt
+func (s *EmailOutboxService) EnqueueAt(kind, to, subject, htmlBody, textBody string, sendAt time.Time) error {
+	return s.enqueue(kind, to, subject, htmlBody, textBody, nil, sendAt)
+}
+
+// EnqueueWithAttachment stores an email that carries a file for delivery by the worker
+func (s *EmailOutboxService) EnqueueWithAttachment(kind, to, subject, htmlBody, textBody string, attachment EmailAttachment) error {
+	return s.enqueue(kind, to, subject, htmlBody, textBody, &attachment, time.Now())
+}
+
+func (s *EmailOutboxService) enqueue(kind, to, subject, htmlBody, textBody string, attachment *EmailAttachment, sendAt time.Time) error {
+	encryptedHTML, err := utils.EncryptString(htmlBody)
+	if err != nil {
+		return err
+	}
+	encryptedText, err := utils.EncryptString(textBody)
+	if err != nil {
+		return err
+	}
+
+	email := &models.OutboxEmail{
+		Kind:          kind,
+		Recipient:     to,
+		Subject:       subject,
+		HTMLBody:      encryptedHTML,
+		TextBody:      encryptedText,
+		Status:        models.EmailStatusPending,
+		NextAttemptAt: sendAt,
+	}
+	if attachment != nil {
+		encryptedAttachment, err := utils.EncryptString(string(attachment.Content))
+		if err != nil {
+			return err
+		}
+		email.AttachmentName = attachment.Filename
+		email.AttachmentType = attachment.ContentType
+		email.Attachment = encryptedAttachment
+	}
+	if err := s.outboxRepo.Create(email); err != nil {
+		return err
+	}
+
+	if !sendAt.After(time.Now()) {
+		wakeOutboxWorker()
+	}
+	return nil
+}
+
+// GetEmails lists queued and delivered emails, newest first
+func (s *EmailOutboxService) GetEmails(filter models.OutboxEmailFilter, page, limit int) ([]models.OutboxEmail, *repositories.Pagination, error) {
+	switch filter.Status {
+	case "", models.EmailStatusPending, models.EmailStatusSent, models.EmailStatusDead:
+	default:
+		return nil, nil, utils.NewBadRequestError("status must be pending, sent or dead")
+	}
+
+	return s.outboxRepo.FindAll(filter, page, limit)
+}
+
+func (s *EmailOutboxService) GetEmailByID(id uint) (*models.OutboxEmail, error) {
+	email, err := s.outboxRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("email not found")
+		}
+		return nil, err
+	}
+	return email, nil
+}
+
+// Resend queues an email again with a fresh set of attempts. Links in the email are
+// re-sent as they were, so a link that has since expired or been replaced stays invalid.
+func (s *EmailOutboxService) Resend(id uint, actor models.AuditActor) (*models.OutboxEmail, error) {
+	email, err := s.GetEmailByID(id)
+	if err != nil {
+		return nil, err
+	}
+	before := auditSnapshot(email)
+
+	if err := s.outboxRepo.Requeue(email.ID, time.Now()); err != nil {
+		return nil, err
+	}
+	wakeOutboxWorker()
+
+	email, err = s.outboxRepo.FindByID(email.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, "email.resend", models.AuditEntityEmail, auditID(email.ID), before, auditSnapshot(email))
+
+	return email, nil
+}
+
+// DeliverDue sends the emails whose next attempt is due
+func (s *EmailOutboxService) DeliverDue() error {
+	now := time.Now()
+	ids, err := s.outboxRepo.FindDueIDs(now, emailWorkerBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		claimed, err := s.outboxRepo.Claim(id, now, now.Add(emailSendLease))
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue // Another worker got there first
+		}
+		if err := s.deliver(id); err != nil {
+			log.Printf("Failed to update outbox email %d: %v", id, err)
+		}
+	}
+
+	if len(ids) == emailWorkerBatch {
+		wakeOutboxWorker() // More may be due, carry on without waiting for the tick
+	}
+	return nil
+}
+
+// deliver makes one attempt at a claimed email and records the outcome
+func (s *EmailOutboxService) deliver(id uint) error {
+	email, err := s.outboxRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+
+	sendErr := s.send(email)
+	if sendErr == nil {
+		return s.outboxRepo.MarkSent(email.ID, time.Now())
+	}
+
+	lastError := sendErr.Error()
+	if len(lastError) > maxEmailErrorLength {
+		lastError = lastError[:maxEmailErrorLength]
+	}
+
+	if email.Attempts >= s.maxAttempts {
+		log.Printf("Giving up on %s email %d to %s after %d attempts: %v", email.Kind, email.ID, email.Recipient, email.Attempts, sendErr)
+		return s.outboxRepo.MarkFailed(email.ID, models.EmailStatusDead, time.Now(), lastError)
+	}
+
+	retryAt := time.Now().Add(s.backoff(email.Attempts))
+	log.Printf("Failed to send %s email %d to %s (attempt %d), retrying at %s: %v", email.Kind, email.ID, email.Recipient, email.Attempts, retryAt.Format(time.RFC3339), sendErr)
+	return s.outboxRepo.MarkFailed(email.ID, models.EmailStatusPending, retryAt, lastError)
+}
+
+func (s *EmailOutboxService) send(email *models.OutboxEmail) error {
+	htmlBody, err := utils.DecryptString(email.HTMLBody)
+	if err != nil {
+		return errors.New("cannot decrypt email body, was ENCRYPTION_KEY changed?")
+	}
+	textBody, err := utils.DecryptString(email.TextBody)
+	if err != nil {
+		return errors.New("cannot decrypt email body, was ENCRYPTION_KEY changed?")
+	}
+
+	message := EmailMessage{
+		From:    s.fromEmail,
+		To:      []string{email.Recipient},
+		Subject: email.Subject,
+		HTML:    htmlBody,
+		Text:    textBody,
+	}
+	if email.AttachmentName != "" {
+		content, err := utils.DecryptString(email.Attachment)
+		if err != nil {
+			return errors.New("cannot decrypt email attachment, was ENCRYPTION_KEY changed?")
+		}
+		message.Attachments = []EmailAttachment{{
+			Filename:    email.AttachmentName,
+			ContentType: email.AttachmentType,
+			Content:     []byte(content),
+		}}
+	}
+
+	return s.sender.Send(message)
+}
+
+func (s *EmailOutboxService) backoff(attempts int) time.Duration {
+	return retryBackoff(s.retryBase, s.retryMax, attempts)
+}
+
+// retryBackoff is the wait after the given number of failed attempts: the base delay
+// doubled for each attempt after the first, capped at the maximum
+func retryBackoff(base, max time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// PruneFinished removes sent and dead emails older than the retention period
+func (s *EmailOutboxService) PruneFinished() error {
+	return s.outboxRepo.DeleteFinishedBefore(time.Now().Add(-s.retention))
+}
+
+func wakeOutboxWorker() {
+	select {
+	case outboxWake <- struct{}{}:
+	default: // A wake-up is already pending
+	}
+}
+
+// StartEmailOutboxWorker delivers queued emails in the background, checking for due
+// emails every interval and whenever one is queued
+func StartEmailOutboxWorker(interval time.Duration) {
+	outboxService := NewEmailOutboxService()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		pruneTicker := time.NewTicker(time.Hour)
+		defer pruneTicker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-outboxWake:
+			case <-pruneTicker.C:
+				if err := outboxService.PruneFinished(); err != nil {
+					log.Printf("Failed to prune email outbox: %v", err)
+				}
+				continue
+			}
+
+			if err := outboxService.DeliverDue(); err != nil {
+				log.Printf("Failed to deliver queued emails: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const resendAPIURL = "https://api.resend.com/emails"
+
+// ResendSender delivers email through the Resend HTTP API
+type ResendSender struct {
+	apiKey string
+	client *http.Client
+}
+
+type ResendSendRequest struct {
+	From        string             `json:"from"`
+	To          []string           `json:"to"`
+	Subject     string             `json:"subject"`
+	Html        string             `json:"html"`
+	Text        string             `json:"text,omitempty"`
+	Attachments []ResendAttachment `json:"attachments,omitempty"`
+}
+
+type ResendAttachment struct {
+	Filename    string `json:"filename"`
+	Content     string `json:"content"` // Base64
+	ContentType string `json:"content_type,omitempty"`
+}
+
+type ResendResponse struct {
+	ID string `json:"id"`
+}
+
+type ResendError struct {
+	Message string `json:"message"`
+	Status  int    `json:"status"`
+}
+
+func NewResendSender(apiKey string) *ResendSender {
+	return &ResendSender{
+		apiKey: apiKey,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *ResendSender) Send(message EmailMessage) error {
+	emailData := ResendSendRequest{
+		From:    message.From,
+		To:      message.To,
+		Subject: message.Subject,
+		Html:    message.HTML,
+		Text:    message.Text,
+	}
+	for _, attachment := range message.Attachments {
+		emailData.Attachments = append(emailData.Attachments, ResendAttachment{
+			Filename:    attachment.Filename,
+			Content:     base64.StdEncoding.EncodeToString(attachment.Content),
+			ContentType: attachment.ContentType,
+		})
+	}
+
+	jsonData, err := json.Marshal(emailData)
+	if err != nil {
+		return fmt.Errorf("failed to marshal email data: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", resendAPIURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send email request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var resendErr ResendError
+		if err := json.NewDecoder(resp.Body).Decode(&resendErr); err != nil {
+			return fmt.Errorf("failed to send email: status %d", resp.StatusCode)
+		}
+		return fmt.Errorf("failed to send email: %s (status %d)", resendErr.Message, resendErr.Status)
+	}
+
+	var resendResp ResendResponse
+	if err := json.NewDecoder(resp.Body).Decode(&resendResp); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+
+	fmt.Printf("✅ Email sent successfully to %v with ID: %s\n", message.To, resendResp.ID)
+	return nil
+}
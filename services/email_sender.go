@@ -0,0 +1,218 @@
+package services
+
+import (
+	"attendance-system/config"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Email transports selectable with EMAIL_TRANSPORT
+const (
+	EmailTransportResend = "resend"
+	EmailTransportSMTP   = "smtp"
+	EmailTransportFile   = "file"
+	EmailTransportLog    = "log"
+)
+
+// EmailMessage is a rendered email ready to hand to a transport
+type EmailMessage struct {
+	From        string
+	To          []string
+	Subject     string
+	HTML        string
+	Text        string
+	Attachments []EmailAttachment
+}
+
+// EmailAttachment is a file sent along with an email
+type EmailAttachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// EmailSender delivers rendered messages. EmailService composes the messages and hands
+// them to the sender chosen by configuration.
+type EmailSender interface {
+	Send(message EmailMessage) error
+}
+
+// NewEmailSender builds the transport named by EMAIL_TRANSPORT. When it is unset, Resend is
+// used if RESEND_API_KEY is set and messages are only logged otherwise.
+func NewEmailSender(cfg *config.Config) (EmailSender, error) {
+	transport := cfg.EmailTransport
+	if transport == "" {
+		transport = EmailTransportLog
+		if cfg.ResendAPIKey != "" {
+			transport = EmailTransportResend
+		}
+	}
+
+	switch transport {
+	case EmailTransportResend:
+		if cfg.ResendAPIKey == "" {
+			return nil, fmt.Errorf("RESEND_API_KEY is required for the resend email transport")
+		}
+		return NewResendSender(cfg.ResendAPIKey), nil
+	case EmailTransportSMTP:
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required for the smtp email transport")
+		}
+		return NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPTLS)
+	case EmailTransportFile:
+		return NewFileSender(cfg.EmailFileDir)
+	case EmailTransportLog:
+		return &logSender{}, nil
+	default:
+		return nil, fmt.Errorf("unknown EMAIL_TRANSPORT %q (use resend, smtp, file or log)", transport)
+	}
+}
+
+// logSender prints messages instead of sending them, for local development
+type logSender struct{}
+
+func (s *logSender) Send(message EmailMessage) error {
+	fmt.Printf("📧 [DEV] Email would be sent to: %s\n", strings.Join(message.To, ", "))
+	fmt.Printf("📧 [DEV] Subject: %s\n", message.Subject)
+	fmt.Printf("📧 [DEV] Body: %s\n", message.Text)
+	for _, attachment := range message.Attachments {
+		fmt.Printf("📧 [DEV] Attachment: %s (%d bytes)\n", attachment.Filename, len(attachment.Content))
+	}
+	return nil
+}
+
+// buildMIMEMessage encodes the message as multipart/alternative with text and HTML parts,
+// as written to SMTP servers and .eml files. Messages with attachments wrap that in
+// multipart/mixed, followed by a base64 part for each attachment.
+func buildMIMEMessage(message EmailMessage) ([]byte, error) {
+	var buf bytes.Buffer
+
+	messageID, err := newMessageID(message.From)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	alternative := multipart.NewWriter(&body)
+	contentType := "multipart/alternative; boundary=" + alternative.Boundary()
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", message.Text},
+		{"text/html; charset=utf-8", message.HTML},
+	} {
+		partWriter, err := alternative.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoder := quotedprintable.NewWriter(partWriter)
+		if _, err := encoder.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := alternative.Close(); err != nil {
+		return nil, err
+	}
+
+	if len(message.Attachments) > 0 {
+		var mixedBody bytes.Buffer
+		mixed := multipart.NewWriter(&mixedBody)
+		partWriter, err := mixed.CreatePart(textproto.MIMEHeader{"Content-Type": {contentType}})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := partWriter.Write(body.Bytes()); err != nil {
+			return nil, err
+		}
+
+		for _, attachment := range message.Attachments {
+			partWriter, err := mixed.CreatePart(textproto.MIMEHeader{
+				"Content-Type":              {mime.FormatMediaType(attachment.ContentType, map[string]string{"name": attachment.Filename})},
+				"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+				"Content-Transfer-Encoding": {"base64"},
+			})
+			if err != nil {
+				return nil, err
+			}
+			if err := writeBase64Lines(partWriter, attachment.Content); err != nil {
+				return nil, err
+			}
+		}
+		if err := mixed.Close(); err != nil {
+			return nil, err
+		}
+
+		contentType = "multipart/mixed; boundary=" + mixed.Boundary()
+		body = mixedBody
+	}
+
+	headers := []string{
+		"From: " + message.From,
+		"To: " + strings.Join(message.To, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", message.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"Message-ID: " + messageID,
+		"MIME-Version: 1.0",
+		"Content-Type: " + contentType,
+	}
+	buf.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+	buf.Write(body.Bytes())
+
+	return buf.Bytes(), nil
+}
+
+// writeBase64Lines writes the content base64 encoded in lines of 76 characters, the most
+// MIME allows
+func writeBase64Lines(w io.Writer, content []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(content)
+	for len(encoded) > 0 {
+		n := 76
+		if len(encoded) < n {
+			n = len(encoded)
+		}
+		if _, err := io.WriteString(w, encoded[:n]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+	return nil
+}
+
+// newMessageID creates a unique Message-ID under the sender's domain
+func newMessageID(from string) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	domain := "localhost"
+	if address, err := mail.ParseAddress(from); err == nil {
+		if at := strings.LastIndex(address.Address, "@"); at >= 0 {
+			domain = address.Address[at+1:]
+		}
+	}
+	return "<" + hex.EncodeToString(random) + "@" + domain + ">", nil
+}
+
+// envelopeAddress extracts the bare address from a "Name <address>" header value
+func envelopeAddress(value string) string {
+	if address, err := mail.ParseAddress(value); err == nil {
+		return address.Address
+	}
+	return value
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTP connection security, set with SMTP_TLS
+const (
+	SMTPTLSStartTLS = "starttls" // Plain connection upgraded with STARTTLS, usually port 587
+	SMTPTLSImplicit = "tls"      // TLS from the first byte, usually port 465
+	SMTPTLSNone     = "none"     // Unencrypted, only for a relay on a trusted network
+)
+
+const smtpTimeout = 30 * time.Second
+
+// SMTPSender delivers email through an SMTP server or relay
+type SMTPSender struct {
+	host     string
+	port     int
+	username string
+	password string
+	security string
+}
+
+func NewSMTPSender(host string, port int, username, password, security string) (*SMTPSender, error) {
+	if security == "" {
+		security = SMTPTLSStartTLS
+	}
+	if security != SMTPTLSStartTLS && security != SMTPTLSImplicit && security != SMTPTLSNone {
+		return nil, fmt.Errorf("unknown SMTP_TLS %q (use starttls, tls or none)", security)
+	}
+	if port == 0 {
+		port = 587
+		if security == SMTPTLSImplicit {
+			port = 465
+		}
+	}
+
+	return &SMTPSender{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		security: security,
+	}, nil
+}
+
+func (s *SMTPSender) Send(message EmailMessage) error {
+	data, err := buildMIMEMessage(message)
+	if err != nil {
+		return fmt.Errorf("failed to build email: %v", err)
+	}
+
+	client, err := s.connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %v", err)
+	}
+	defer client.Close()
+
+	if s.security == SMTPTLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP server %s does not support STARTTLS", s.host)
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %v", err)
+		}
+	}
+
+	if s.username != "" {
+		// PlainAuth refuses to send credentials over an unencrypted connection to a remote host
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %v", err)
+		}
+	}
+
+	if err := client.Mail(envelopeAddress(message.From)); err != nil {
+		return fmt.Errorf("SMTP server rejected sender: %v", err)
+	}
+	for _, to := range message.To {
+		if err := client.Rcpt(envelopeAddress(to)); err != nil {
+			return fmt.Errorf("SMTP server rejected recipient %s: %v", to, err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message data: %v", err)
+	}
+	if _, err := writer.Write(data); err != nil {
+		return fmt.Errorf("failed to write message: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("SMTP server rejected message: %v", err)
+	}
+
+	return client.Quit()
+}
+
+// connect opens the connection, with TLS from the start when SMTP_TLS=tls
+func (s *SMTPSender) connect() (*smtp.Client, error) {
+	address := net.JoinHostPort(s.host, strconv.Itoa(s.port))
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+
+	var conn net.Conn
+	var err error
+	if s.security == SMTPTLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, &tls.Config{ServerName: s.host})
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"attendance-system/config"
+	"attendance-system/models"
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed email_templates
+var embeddedEmailTemplates embed.FS
+
+var emailColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// emailKinds are the emails with a template in every locale
+var emailKinds = []string{
+	models.EmailKindAccountSetup,
+	models.EmailKindWelcome,
+	models.EmailKindPasswordReset,
+	models.EmailKindEmailVerify,
+	models.EmailKindAttendanceAlert,
+	models.EmailKindManagerDigest,
+	models.EmailKindScheduledReport,
+}
+
+// EmailBranding is shown in every email's header and footer
+type EmailBranding struct {
+	AppName      string
+	CompanyName  string
+	LogoURL      string
+	PrimaryColor string
+	AccentColor  string
+}
+
+// EmailTemplateData is what an email template is rendered with. Callers fill in the
+// fields their email uses; the rest are set when rendering.
+type EmailTemplateData struct {
+	Name      string // Recipient's name
+	ActionURL string // Link behind the email's button
+	Alert     AttendanceAlert
+	Digest    ManagerDigest
+	Report    ScheduledReport
+
+	Brand   EmailBranding
+	Locale  string
+	Subject string
+	Summary string // One-line summary, for emails whose .txt file defines one
+	Year    int
+}
+
+// AttendanceAlert describes what an attendance alert is about
+type AttendanceAlert struct {
+	Type         string // Alert rule type
+	AboutSelf    bool   // Sent to the employee rather than their manager
+	EmployeeID   string
+	EmployeeName string
+	Minutes      int    // Minutes late, for late arrivals
+	Time         string // HH:MM clock-in time for late arrivals, otherwise the shift start or end
+}
+
+// ScheduledReport describes the report attached to a scheduled report email
+type ScheduledReport struct {
+	ScheduleName string
+	PeriodStart  string // YYYY-MM-DD
+	PeriodEnd    string
+	Department   string // Empty when the report covers every department
+	Filename     string
+}
+
+// renderedEmail is an email ready to be queued
+type renderedEmail struct {
+	Subject string
+	Summary string
+	HTML    string
+	Text    string
+}
+
+type emailTemplate struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+type emailTemplateSet struct {
+	templates     map[string]*emailTemplate // Keyed by locale/kind
+	branding      EmailBranding
+	defaultLocale string
+}
+
+var emailTemplates struct {
+	once sync.Once
+	set  *emailTemplateSet
+	err  error
+}
+
+// LoadEmailTemplates parses the email templates once. main calls it at startup so a broken
+// template or branding setting stops the server instead of failing every email.
+//
+// Templates are embedded in the binary. A file with the same path under EMAIL_TEMPLATE_DIR,
+// e.g. id/welcome.html, replaces the embedded one. Each email has a .txt file defining its
+// subject and plain text body and a .html file defining its HTML body, which is rendered
+// with html/template so values are escaped for where they appear.
+func LoadEmailTemplates() error {
+	emailTemplates.once.Do(func() {
+		emailTemplates.set, emailTemplates.err = loadEmailTemplateSet(config.GetConfig())
+	})
+	return emailTemplates.err
+}
+
+func getEmailTemplates() (*emailTemplateSet, error) {
+	if err := LoadEmailTemplates(); err != nil {
+		return nil, err
+	}
+	return emailTemplates.set, nil
+}
+
+func loadEmailTemplateSet(cfg *config.Config) (*emailTemplateSet, error) {
+	branding := EmailBranding{
+		AppName:      cfg.EmailAppName,
+		CompanyName:  cfg.EmailCompanyName,
+		LogoURL:      cfg.EmailLogoURL,
+		PrimaryColor: cfg.EmailPrimaryColor,
+		AccentColor:  cfg.EmailAccentColor,
+	}
+	if !emailColorPattern.MatchString(branding.PrimaryColor) || !emailColorPattern.MatchString(branding.AccentColor) {
+		return nil, fmt.Errorf("EMAIL_PRIMARY_COLOR and EMAIL_ACCENT_COLOR must be hex colours such as #667eea")
+	}
+	if branding.LogoURL != "" {
+		logoURL, err := url.Parse(branding.LogoURL)
+		if err != nil || (logoURL.Scheme != "https" && logoURL.Scheme != "http") || logoURL.Host == "" {
+			return nil, fmt.Errorf("EMAIL_LOGO_URL must be an absolute http(s) URL")
+		}
+	}
+	if !models.IsSupportedLocale(cfg.EmailDefaultLocale) {
+		return nil, fmt.Errorf("EMAIL_DEFAULT_LOCALE must be one of %s", strings.Join(models.SupportedLocales, ", "))
+	}
+
+	files := emailTemplateFiles{override: cfg.EmailTemplateDir}
+	set := &emailTemplateSet{
+		templates:     make(map[string]*emailTemplate),
+		branding:      branding,
+		defaultLocale: cfg.EmailDefaultLocale,
+	}
+
+	for _, locale := range models.SupportedLocales {
+		for _, kind := range emailKinds {
+			tmpl, err := files.parse(locale, kind)
+			if err != nil {
+				return nil, err
+			}
+			set.templates[locale+"/"+kind] = tmpl
+		}
+	}
+
+	return set, nil
+}
+
+// emailTemplateFiles reads template files from the override directory, falling back to
+// the embedded ones
+type emailTemplateFiles struct {
+	override string
+}
+
+func (f emailTemplateFiles) read(name string) (string, error) {
+	if f.override != "" {
+		content, err := os.ReadFile(path.Join(f.override, name))
+		if err == nil {
+			return string(content), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to read email template %s: %w", name, err)
+		}
+	}
+
+	content, err := fs.ReadFile(embeddedEmailTemplates, path.Join("email_templates", name))
+	if err != nil {
+		return "", fmt.Errorf("failed to read email template %s: %w", name, err)
+	}
+	return string(content), nil
+}
+
+func (f emailTemplateFiles) parse(locale, kind string) (*emailTemplate, error) {
+	var sources [6]string
+	names := [6]string{
+		"layout.html", locale + "/footer.html", locale + "/" + kind + ".html",
+		"layout.txt", locale + "/footer.txt", locale + "/" + kind + ".txt",
+	}
+	for i, name := range names {
+		content, err := f.read(name)
+		if err != nil {
+			return nil, err
+		}
+		sources[i] = content
+	}
+
+	html := htmltemplate.New(names[0])
+	for i := 0; i < 3; i++ {
+		if _, err := html.Parse(sources[i]); err != nil {
+			return nil, fmt.Errorf("invalid email template %s: %w", names[i], err)
+		}
+	}
+	text := texttemplate.New(names[3])
+	for i := 3; i < 6; i++ {
+		if _, err := text.Parse(sources[i]); err != nil {
+			return nil, fmt.Errorf("invalid email template %s: %w", names[i], err)
+		}
+	}
+	if text.Lookup("subject") == nil {
+		return nil, fmt.Errorf("email template %s does not define a subject", names[5])
+	}
+
+	return &emailTemplate{html: html, text: text}, nil
+}
+
+// render produces the email of the given kind in the recipient's locale, falling back to
+// EMAIL_DEFAULT_LOCALE when the recipient has none or it isn't supported
+func (s *emailTemplateSet) render(kind, locale string, data EmailTemplateData) (*renderedEmail, error) {
+	if !models.IsSupportedLocale(locale) {
+		locale = s.defaultLocale
+	}
+	tmpl, ok := s.templates[locale+"/"+kind]
+	if !ok {
+		return nil, fmt.Errorf("no email template for %s", kind)
+	}
+
+	data.Brand = s.branding
+	data.Locale = locale
+	data.Year = time.Now().Year()
+
+	var subject bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("failed to render %s email subject: %w", kind, err)
+	}
+	data.Subject = strings.Join(strings.Fields(subject.String()), " ")
+
+	if tmpl.text.Lookup("summary") != nil {
+		var summary bytes.Buffer
+		if err := tmpl.text.ExecuteTemplate(&summary, "summary", data); err != nil {
+			return nil, fmt.Errorf("failed to render %s email summary: %w", kind, err)
+		}
+		data.Summary = strings.Join(strings.Fields(summary.String()), " ")
+	}
+
+	var text bytes.Buffer
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s email: %w", kind, err)
+	}
+	var html bytes.Buffer
+	if err := tmpl.html.Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s email: %w", kind, err)
+	}
+
+	return &renderedEmail{
+		Subject: data.Subject,
+		Summary: data.Summary,
+		HTML:    html.String(),
+		Text:    strings.TrimSpace(text.String()),
+	}, nil
+}
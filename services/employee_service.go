@@ -6,34 +6,46 @@ import (
 	"attendance-system/repositories"
 	"attendance-system/utils"
 	"fmt"
+	"log"
 	"strconv"
 	"strings"
 	"time"
 )
 
 type EmployeeService struct {
-	employeeRepo *repositories.EmployeeRepository
-	userRepo     *repositories.UserRepository
-	emailService *ResendEmailService
-	config       *config.Config
+	employeeRepo   *repositories.EmployeeRepository
+	userRepo       *repositories.UserRepository
+	tokenService   *OneTimeTokenService
+	emailService   *EmailService
+	passwordPolicy *PasswordPolicyService
+	auditService   *AuditService
+	webhookService *WebhookService
+	config         *config.Config
 }
 
 func NewEmployeeService() *EmployeeService {
 	return &EmployeeService{
-		employeeRepo: repositories.NewEmployeeRepository(),
-		userRepo:     repositories.NewUserRepository(),
-		emailService: NewEmailService(),
-		config:       config.GetConfig(),
+		employeeRepo:   repositories.NewEmployeeRepository(),
+		userRepo:       repositories.NewUserRepository(),
+		tokenService:   NewOneTimeTokenService(),
+		emailService:   NewEmailService(),
+		passwordPolicy: NewPasswordPolicyService(),
+		auditService:   NewAuditService(),
+		webhookService: NewWebhookService(),
+		config:         config.GetConfig(),
 	}
 }
 
+// setupTokenTTL is how long an account setup link stays valid
+const setupTokenTTL = 7 * 24 * time.Hour
+
 type CreateEmployeeResult struct {
 	Employee   *models.Employee
 	SetupToken string
 	Message    string
 }
 
-func (s *EmployeeService) CreateEmployee(req models.EmployeeRequest) (*models.EmployeeCreateResponse, error) {
+func (s *EmployeeService) CreateEmployee(req models.EmployeeRequest, actor models.AuditActor) (*models.EmployeeCreateResponse, error) {
 	// Generate employee ID if not provided
 	employeeID := req.EmployeeID
 	if employeeID == "" {
@@ -56,9 +68,14 @@ func (s *EmployeeService) CreateEmployee(req models.EmployeeRequest) (*models.Em
 		return nil, utils.NewConflictError("User Account Already Exists With This Email")
 	}
 
+	if err := s.validateManager(employeeID, req.ManagerID); err != nil {
+		return nil, err
+	}
+
 	employee := &models.Employee{
 		EmployeeID:   employeeID,
 		DepartmentID: req.DepartmentID,
+		ManagerID:    req.ManagerID,
 		Name:         req.Name,
 		Phone:        req.Phone,
 		Address:      req.Address,
@@ -82,20 +99,22 @@ func (s *EmployeeService) CreateEmployee(req models.EmployeeRequest) (*models.Em
 	}
 
 	// Create user account with setup token (NO PASSWORD)
-	setupToken, err := s.createUserWithSetupToken(employee, req.Email)
+	setupToken, err := s.createUserWithSetupToken(employee, req.Email, req.Locale)
 	if err != nil {
 		// If user creation fails, delete the employee to maintain consistency
 		s.employeeRepo.Delete(employee.ID)
 		return nil, err
 	}
+	s.auditService.Record(actor, "employee.create", models.AuditEntityEmployee, auditID(employee.ID), nil, auditSnapshot(employee))
+	s.webhookService.Emit(models.WebhookEventEmployeeCreated, webhookEmployee(employee, ""))
 
-	// Send account setup email using Resend
-	emailErr := s.emailService.SendAccountSetupEmail(req.Email, employee.Name, setupToken)
-	message := "Employee created successfully. Setup email sent."
+	// Queue the account setup email, the outbox worker delivers it
+	emailErr := s.emailService.SendAccountSetupEmail(req.Email, req.Locale, employee.Name, setupToken)
+	message := "Employee created successfully. Setup email queued for delivery."
 	
 	if emailErr != nil {
-		fmt.Printf("⚠️ Failed to send setup email to %s: %v\n", req.Email, emailErr)
-		message = "Employee created successfully, but failed to send setup email. Use the setup token below."
+		log.Printf("Failed to queue setup email to %s: %v", req.Email, emailErr)
+		message = "Employee created successfully, but failed to queue the setup email. Use the setup token below."
 	}
 
 	response := &models.EmployeeCreateResponse{
@@ -107,7 +126,7 @@ func (s *EmployeeService) CreateEmployee(req models.EmployeeRequest) (*models.Em
 	return response, nil
 }
 
-func (s *EmployeeService) createUserWithSetupToken(employee *models.Employee, email string) (string, error) {
+func (s *EmployeeService) createUserWithSetupToken(employee *models.Employee, email, locale string) (string, error) {
 	// Generate username from email
 	username := strings.Split(email, "@")[0]
 	
@@ -117,65 +136,51 @@ func (s *EmployeeService) createUserWithSetupToken(employee *models.Employee, em
 		username = username + "_" + employee.EmployeeID
 	}
 
-	// Generate secure setup token
-	setupToken, err := models.GenerateSecureToken(32)
-	if err != nil {
-		return "", err
-	}
-
-	tokenExpires := time.Now().Add(7 * 24 * time.Hour) // 7 days expiry
-
-	// Create user with setup token (no password)
+	// Create user without a password until setup is complete
 	user := &models.User{
-		Username:     username,
-		Email:        email,
-		Password:     "", // No password set initially
-		Role:         "employee",
-		EmployeeID:   &employee.EmployeeID,
-		IsActive:     false, // Not active until setup complete
-		SetupToken:   &setupToken,
-		TokenExpires: &tokenExpires,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		Username:   username,
+		Email:      email,
+		Password:   "", // No password set initially
+		Role:       "employee",
+		EmployeeID: &employee.EmployeeID,
+		IsActive:   false, // Not active until setup complete
+		Locale:     locale,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
 	}
 
 	if err := s.userRepo.Create(user); err != nil {
 		return "", err
 	}
 
+	// Issue the account setup token (7 days expiry)
+	setupToken, err := s.tokenService.Issue(user.ID, models.TokenPurposeAccountSetup, setupTokenTTL, "")
+	if err != nil {
+		s.userRepo.Delete(user.ID)
+		return "", err
+	}
+
 	return setupToken, nil
 }
 
 // For emergency cases where email fails, allow manual token retrieval
-func (s *EmployeeService) GetEmployeeSetupToken(employeeID string) (string, error) {
+func (s *EmployeeService) GetEmployeeSetupToken(employeeID string, actor models.AuditActor) (string, error) {
 	user, err := s.userRepo.FindByEmployeeID(employeeID)
 	if err != nil {
 		return "", utils.NewNotFoundError("Employee user account not found")
 	}
 
-	if user.SetupToken == nil {
-		return "", utils.NewBadRequestError("No setup token available")
+	if user.IsActive {
+		return "", utils.NewBadRequestError("Account setup is already complete")
 	}
 
-	if user.TokenExpires != nil && user.TokenExpires.Before(time.Now()) {
-		// Generate new token if expired
-		newToken, err := models.GenerateSecureToken(32)
-		if err != nil {
-			return "", err
-		}
-		
-		newExpiry := time.Now().Add(7 * 24 * time.Hour)
-		user.SetupToken = &newToken
-		user.TokenExpires = &newExpiry
-		
-		if err := s.userRepo.Update(user); err != nil {
-			return "", err
-		}
-		
-		return newToken, nil
+	// Only hashes are stored, so hand out a fresh token, replacing the previous one
+	token, err := s.tokenService.Issue(user.ID, models.TokenPurposeAccountSetup, setupTokenTTL, actor.IPAddress)
+	if err != nil {
+		return "", err
 	}
-
-	return *user.SetupToken, nil
+	s.auditService.Record(actor, "user.setup_token_issue", models.AuditEntityUser, auditID(user.ID), nil, nil)
+	return token, nil
 }
 
 func (s *EmployeeService) generateEmployeeID() (string, error) {
@@ -247,11 +252,13 @@ func (s *EmployeeService) GetEmployeeByEmployeeID(employeeID string) (*models.Em
 	return s.employeeRepo.FindByEmployeeID(employeeID)
 }
 
-func (s *EmployeeService) UpdateEmployee(id uint, req models.EmployeeRequest) (*models.Employee, error) {
+func (s *EmployeeService) UpdateEmployee(id uint, req models.EmployeeRequest, actor models.AuditActor) (*models.Employee, error) {
 	employee, err := s.employeeRepo.FindByID(id)
 	if err != nil {
 		return nil, err
 	}
+	before := auditSnapshot(employee)
+	previousStatus := employee.Status
 
 	// Check if employee ID is being changed and if it already exists
 	if employee.EmployeeID != req.EmployeeID {
@@ -266,8 +273,13 @@ func (s *EmployeeService) UpdateEmployee(id uint, req models.EmployeeRequest) (*
 		}
 	}
 
+	if err := s.validateManager(employee.EmployeeID, req.ManagerID); err != nil {
+		return nil, err
+	}
+
 	employee.EmployeeID = req.EmployeeID
 	employee.DepartmentID = req.DepartmentID
+	employee.ManagerID = req.ManagerID
 	employee.Name = req.Name
 	employee.Phone = req.Phone
 	employee.Address = req.Address
@@ -279,6 +291,10 @@ func (s *EmployeeService) UpdateEmployee(id uint, req models.EmployeeRequest) (*
 	if err := s.employeeRepo.Update(employee); err != nil {
 		return nil, err
 	}
+	s.auditService.Record(actor, "employee.update", models.AuditEntityEmployee, auditID(employee.ID), before, auditSnapshot(employee))
+	if employee.Status != previousStatus {
+		s.webhookService.Emit(models.WebhookEventEmployeeStatusChanged, webhookEmployee(employee, previousStatus))
+	}
 
 	return employee, nil
 }
@@ -301,7 +317,7 @@ func (s *EmployeeService) updateUserEmployeeID(oldEmployeeID, newEmployeeID stri
 	return s.userRepo.UpdateByEmployeeID(oldEmployeeID, updates)
 }
 
-func (s *EmployeeService) DeleteEmployee(id uint) error {
+func (s *EmployeeService) DeleteEmployee(id uint, actor models.AuditActor) error {
 	employee, err := s.employeeRepo.FindByID(id)
 	if err != nil {
 		return err
@@ -316,7 +332,11 @@ func (s *EmployeeService) DeleteEmployee(id uint) error {
 		}
 	}
 
-	return s.employeeRepo.Delete(id)
+	if err := s.employeeRepo.Delete(id); err != nil {
+		return err
+	}
+	s.auditService.Record(actor, "employee.delete", models.AuditEntityEmployee, auditID(employee.ID), auditSnapshot(employee), nil)
+	return nil
 }
 
 func (s *EmployeeService) GetEmployeesByDepartment(departmentID uint) ([]models.Employee, error) {
@@ -373,41 +393,112 @@ func (s *EmployeeService) GetEmployeeWithUser(employeeID string) (*models.Employ
 }
 
 // UpdateEmployeeStatus updates only the employee status
-func (s *EmployeeService) UpdateEmployeeStatus(id uint, status string) error {
+func (s *EmployeeService) UpdateEmployeeStatus(id uint, status string, actor models.AuditActor) error {
 	employee, err := s.employeeRepo.FindByID(id)
 	if err != nil {
 		return err
 	}
+	before := auditSnapshot(employee)
+	previousStatus := employee.Status
 
 	employee.Status = status
 	employee.UpdatedAt = time.Now()
 
-	return s.employeeRepo.Update(employee)
+	if err := s.employeeRepo.Update(employee); err != nil {
+		return err
+	}
+	s.auditService.Record(actor, "employee.status_change", models.AuditEntityEmployee, auditID(employee.ID), before, auditSnapshot(employee))
+	if status != previousStatus {
+		s.webhookService.Emit(models.WebhookEventEmployeeStatusChanged, webhookEmployee(employee, previousStatus))
+	}
+	return nil
 }
 
-func (s *EmployeeService) CompleteAccountSetup(token, newPassword string) error {
-	// Find user by setup token
-	user, err := s.userRepo.FindBySetupToken(token)
+func (s *EmployeeService) CompleteAccountSetup(token, newPassword string, actor models.AuditActor) error {
+	setupToken, err := s.tokenService.Consume(token, models.TokenPurposeAccountSetup)
 	if err != nil {
-		return utils.NewNotFoundError("Invalid or expired setup token")
+		return err
 	}
+	user := setupToken.User
 
-	// Check if token is expired
-	if user.TokenExpires == nil || user.TokenExpires.Before(time.Now()) {
-		return utils.NewBadRequestError("Setup token has expired")
+	if user.IsActive {
+		return utils.NewBadRequestError("Account setup is already complete")
 	}
+	before := auditSnapshot(user)
 
 	// Set new password
-	user.Password = newPassword
-	if err := user.HashPassword(); err != nil {
+	if err := s.passwordPolicy.SetPassword(user, newPassword); err != nil {
+		return err
+	}
+
+	// Activate user; the setup link proves the email address
+	now := time.Now()
+	user.ActivateUser()
+	user.EmailVerifiedAt = &now
+
+	if err := s.userRepo.Update(user); err != nil {
 		return err
 	}
+	s.passwordPolicy.RecordChange(user)
+	s.auditService.Record(auditActorForUser(actor, user), "user.account_setup", models.AuditEntityUser, auditID(user.ID), before, auditSnapshot(user))
+	return nil
+}
+
+// validateManager makes sure the manager exists and would not create a reporting cycle
+func (s *EmployeeService) validateManager(employeeID string, managerID *string) error {
+	if managerID == nil {
+		return nil
+	}
+	if *managerID == employeeID {
+		return utils.NewBadRequestError("employee cannot be their own manager")
+	}
+
+	manager, err := s.employeeRepo.FindByEmployeeID(*managerID)
+	if err != nil {
+		return utils.NewBadRequestError("manager not found")
+	}
+
+	// Walk up from the new manager; reaching the employee means a cycle
+	visited := map[string]bool{}
+	for manager.ManagerID != nil && !visited[manager.EmployeeID] {
+		visited[manager.EmployeeID] = true
+		if *manager.ManagerID == employeeID {
+			return utils.NewBadRequestError("manager cannot be one of the employee's reports")
+		}
+		manager, err = s.employeeRepo.FindByEmployeeID(*manager.ManagerID)
+		if err != nil {
+			break
+		}
+	}
+	return nil
+}
 
-	// Activate user and clear setup token
-	user.IsActive = true
-	user.SetupToken = nil
-	user.TokenExpires = nil
-	user.UpdatedAt = time.Now()
+// GetReportingChain returns the employee's managers, starting with the direct manager
+func (s *EmployeeService) GetReportingChain(id uint) ([]models.Employee, error) {
+	employee, err := s.employeeRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
 
-	return s.userRepo.Update(user)
-}
\ No newline at end of file
+	chain := []models.Employee{}
+	visited := map[string]bool{employee.EmployeeID: true}
+	for employee.ManagerID != nil && !visited[*employee.ManagerID] {
+		visited[*employee.ManagerID] = true
+		employee, err = s.employeeRepo.FindByEmployeeID(*employee.ManagerID)
+		if err != nil {
+			break
+		}
+		chain = append(chain, *employee)
+	}
+
+	return chain, nil
+}
+
+// GetDirectReports returns the employees who report directly to the given employee
+func (s *EmployeeService) GetDirectReports(id uint) ([]models.Employee, error) {
+	employee, err := s.employeeRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.employeeRepo.FindDirectReports(employee.EmployeeID)
+}
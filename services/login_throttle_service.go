@@ -0,0 +1,253 @@
+package services
+
+import (
+	"attendance-system/models"
+	"attendance-system/repositories"
+	"attendance-system/utils"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// tooManyAttemptsMessage is returned by the IP throttle
+const tooManyAttemptsMessage = "Too many failed attempts. Please try again later."
+
+// lockedAccountMessage is the message logins fail with for any other reason, so a
+// locked account can't be told apart from a wrong password or an unknown username
+const lockedAccountMessage = "Invalid Credentials"
+
+// LoginThrottleService slows down password and token guessing. Failures are
+// counted per IP for every throttled scope, and per account for logins, with a
+// growing delay between attempts and a temporary lockout after too many.
+type LoginThrottleService struct {
+	attemptRepo loginAttemptStore
+	userRepo    accountLockStore
+}
+
+// loginAttemptStore is the part of LoginAttemptRepository the throttle uses
+type loginAttemptStore interface {
+	Create(attempt *models.LoginAttempt) error
+	CountFailuresSince(scope, ipAddress string, since time.Time) (int64, *time.Time, error)
+	FindEvents(event string, page, limit int) ([]models.LoginAttempt, *repositories.Pagination, error)
+	DeleteBefore(before time.Time) (int64, error)
+}
+
+// accountLockStore is the part of UserRepository that keeps the per-account counters and locks
+type accountLockStore interface {
+	FindByID(id uint) (*models.User, error)
+	IncrementFailedLogins(userID uint) (int, error)
+	RestartFailedLogins(userID uint) error
+	LockUntil(userID uint, until time.Time) error
+	ResetFailedLogins(userID uint) error
+	FindLocked(now time.Time) ([]models.User, error)
+}
+
+func NewLoginThrottleService() *LoginThrottleService {
+	return &LoginThrottleService{
+		attemptRepo: repositories.NewLoginAttemptRepository(),
+		userRepo:    repositories.NewUserRepository(),
+	}
+}
+
+// CheckIP rejects the request when the IP has failed too often in the scope recently.
+// It returns how long the client should wait before retrying.
+func (s *LoginThrottleService) CheckIP(scope, ipAddress string) (time.Duration, error) {
+	window := getDurationEnv("THROTTLE_IP_WINDOW", 15*time.Minute)
+	count, latest, err := s.attemptRepo.CountFailuresSince(scope, ipAddress, time.Now().Add(-window))
+	if err != nil {
+		return 0, err
+	}
+
+	if count >= int64(getIntEnv("THROTTLE_IP_MAX_ATTEMPTS", 20)) {
+		return window, utils.NewTooManyRequestsError(tooManyAttemptsMessage)
+	}
+
+	if latest != nil {
+		if wait := time.Until(latest.Add(progressiveDelay(int(count)))); wait > 0 {
+			return wait, utils.NewTooManyRequestsError(tooManyAttemptsMessage)
+		}
+	}
+
+	return 0, nil
+}
+
+// RecordIPFailure counts a failed attempt against the IP
+func (s *LoginThrottleService) RecordIPFailure(scope, ipAddress string) {
+	attempt := &models.LoginAttempt{
+		Scope:     scope,
+		Event:     models.LoginEventFailed,
+		IPAddress: ipAddress,
+		CreatedAt: time.Now(),
+	}
+	if err := s.attemptRepo.Create(attempt); err != nil {
+		log.Printf("Failed to record failed attempt: %v", err)
+	}
+}
+
+// CheckAccount rejects logins to an account that is locked or still inside its progressive
+// delay. The rejection looks like a failed login, since a distinct one would tell anyone
+// guessing that the account exists.
+func (s *LoginThrottleService) CheckAccount(user *models.User) error {
+	if user.IsLocked() {
+		return utils.NewUnauthorizedError(lockedAccountMessage)
+	}
+	if user.LastFailedLoginAt != nil && time.Now().Before(user.LastFailedLoginAt.Add(progressiveDelay(user.FailedLoginAttempts))) {
+		return utils.NewUnauthorizedError(lockedAccountMessage)
+	}
+	return nil
+}
+
+// RecordAccountFailure counts a failed login against the account and locks it once the limit is reached.
+// The count starts again after a lockout runs out, otherwise the next mistake would lock the
+// account for another full period and anyone could keep it locked with one guess per period.
+func (s *LoginThrottleService) RecordAccountFailure(user *models.User, identifier, ipAddress string) {
+	failures := 1
+	var err error
+	if user.LockExpired() {
+		err = s.userRepo.RestartFailedLogins(user.ID)
+	} else {
+		failures, err = s.userRepo.IncrementFailedLogins(user.ID)
+	}
+	if err != nil {
+		log.Printf("Failed to record failed login for user %d: %v", user.ID, err)
+		return
+	}
+
+	if failures < getIntEnv("LOGIN_MAX_ATTEMPTS", 5) {
+		return
+	}
+
+	lockedUntil := time.Now().Add(getDurationEnv("LOGIN_LOCKOUT_DURATION", 15*time.Minute))
+	if err := s.userRepo.LockUntil(user.ID, lockedUntil); err != nil {
+		log.Printf("Failed to lock user %d: %v", user.ID, err)
+		return
+	}
+
+	detail := fmt.Sprintf("locked until %s after %d failed logins", lockedUntil.Format(time.RFC3339), failures)
+	s.logEvent(models.LoginEventLocked, &user.ID, identifier, ipAddress, detail)
+}
+
+// RecordAccountSuccess clears the failed login counter after a successful login
+func (s *LoginThrottleService) RecordAccountSuccess(user *models.User) {
+	if user.FailedLoginAttempts == 0 && user.LockedUntil == nil {
+		return
+	}
+	if err := s.userRepo.ResetFailedLogins(user.ID); err != nil {
+		log.Printf("Failed to reset failed logins for user %d: %v", user.ID, err)
+	}
+}
+
+// UnlockAccount lifts a lockout and resets the failed login counter
+func (s *LoginThrottleService) UnlockAccount(userID uint, adminUsername, ipAddress string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.ResetFailedLogins(user.ID); err != nil {
+		return err
+	}
+
+	s.logEvent(models.LoginEventUnlocked, &user.ID, user.Username, ipAddress, "unlocked by "+adminUsername)
+	return nil
+}
+
+// GetLockedAccounts lists accounts that are currently locked
+func (s *LoginThrottleService) GetLockedAccounts() ([]models.LockedUserResponse, error) {
+	users, err := s.userRepo.FindLocked(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	responses := []models.LockedUserResponse{}
+	for _, user := range users {
+		responses = append(responses, models.LockedUserResponse{
+			ID:                  user.ID,
+			Username:            user.Username,
+			Email:               user.Email,
+			FailedLoginAttempts: user.FailedLoginAttempts,
+			LockedUntil:         user.LockedUntil,
+		})
+	}
+	return responses, nil
+}
+
+// GetLockoutEvents lists lockout and unlock events, newest first
+func (s *LoginThrottleService) GetLockoutEvents(event string, page, limit int) ([]models.LoginAttempt, *repositories.Pagination, error) {
+	return s.attemptRepo.FindEvents(event, page, limit)
+}
+
+// PruneAttempts removes failed attempts that no longer count towards any window
+func (s *LoginThrottleService) PruneAttempts() error {
+	_, err := s.attemptRepo.DeleteBefore(time.Now().Add(-24 * time.Hour))
+	return err
+}
+
+func (s *LoginThrottleService) logEvent(event string, userID *uint, identifier, ipAddress, detail string) {
+	log.Printf("🔒 Account %s: user=%s ip=%s %s", event, identifier, ipAddress, detail)
+
+	attempt := &models.LoginAttempt{
+		Scope:      models.ThrottleScopeLogin,
+		Event:      event,
+		UserID:     userID,
+		Identifier: identifier,
+		IPAddress:  ipAddress,
+		Detail:     detail,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.attemptRepo.Create(attempt); err != nil {
+		log.Printf("Failed to record %s event: %v", event, err)
+	}
+}
+
+// progressiveDelay is the wait required after the given number of consecutive failures:
+// none for the first two, then 1s, 2s, 4s... capped at 30s
+func progressiveDelay(failures int) time.Duration {
+	if failures < 3 {
+		return 0
+	}
+	shift := failures - 3
+	if shift > 5 {
+		return 30 * time.Second
+	}
+	delay := time.Second << uint(shift)
+	if delay > 30*time.Second {
+		delay = 30 * time.Second
+	}
+	return delay
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// getCountEnv is like getIntEnv but accepts zero, for settings where zero turns a feature off
+func getCountEnv(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value < 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func getBoolEnv(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"attendance-system/models"
+	"attendance-system/repositories"
+	"testing"
+	"time"
+)
+
+// fakeLockStore keeps one user's lockout state in memory
+type fakeLockStore struct {
+	user *models.User
+}
+
+func (f *fakeLockStore) FindByID(id uint) (*models.User, error) {
+	return f.user, nil
+}
+
+func (f *fakeLockStore) IncrementFailedLogins(userID uint) (int, error) {
+	now := time.Now()
+	f.user.FailedLoginAttempts++
+	f.user.LastFailedLoginAt = &now
+	return f.user.FailedLoginAttempts, nil
+}
+
+func (f *fakeLockStore) RestartFailedLogins(userID uint) error {
+	now := time.Now()
+	f.user.FailedLoginAttempts = 1
+	f.user.LastFailedLoginAt = &now
+	f.user.LockedUntil = nil
+	return nil
+}
+
+func (f *fakeLockStore) LockUntil(userID uint, until time.Time) error {
+	f.user.LockedUntil = &until
+	return nil
+}
+
+func (f *fakeLockStore) ResetFailedLogins(userID uint) error {
+	f.user.FailedLoginAttempts = 0
+	f.user.LastFailedLoginAt = nil
+	f.user.LockedUntil = nil
+	return nil
+}
+
+func (f *fakeLockStore) FindLocked(now time.Time) ([]models.User, error) {
+	return nil, nil
+}
+
+// fakeAttemptStore records the lockout events the throttle logs
+type fakeAttemptStore struct {
+	attempts []models.LoginAttempt
+}
+
+func (f *fakeAttemptStore) Create(attempt *models.LoginAttempt) error {
+	f.attempts = append(f.attempts, *attempt)
+	return nil
+}
+
+func (f *fakeAttemptStore) CountFailuresSince(scope, ipAddress string, since time.Time) (int64, *time.Time, error) {
+	return 0, nil, nil
+}
+
+func (f *fakeAttemptStore) FindEvents(event string, page, limit int) ([]models.LoginAttempt, *repositories.Pagination, error) {
+	return nil, nil, nil
+}
+
+func (f *fakeAttemptStore) DeleteBefore(before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func newTestThrottle(user *models.User) (*LoginThrottleService, *fakeAttemptStore) {
+	attempts := &fakeAttemptStore{}
+	return &LoginThrottleService{attemptRepo: attempts, userRepo: &fakeLockStore{user: user}}, attempts
+}
+
+func TestRecordAccountFailureLocksAtLimit(t *testing.T) {
+	t.Setenv("LOGIN_MAX_ATTEMPTS", "5")
+	user := &models.User{ID: 1, Username: "jdoe"}
+	throttle, attempts := newTestThrottle(user)
+
+	for i := 0; i < 5; i++ {
+		throttle.RecordAccountFailure(user, user.Username, "10.0.0.1")
+	}
+
+	if !user.IsLocked() {
+		t.Fatalf("account not locked after %d failures", user.FailedLoginAttempts)
+	}
+	if len(attempts.attempts) != 1 || attempts.attempts[0].Event != models.LoginEventLocked {
+		t.Fatalf("expected one lock event, got %+v", attempts.attempts)
+	}
+}
+
+func TestRecordAccountFailureAfterLockExpires(t *testing.T) {
+	t.Setenv("LOGIN_MAX_ATTEMPTS", "5")
+	expired := time.Now().Add(-time.Minute)
+	lastFailure := expired.Add(-15 * time.Minute)
+	user := &models.User{
+		ID:                  1,
+		Username:            "jdoe",
+		FailedLoginAttempts: 5,
+		LastFailedLoginAt:   &lastFailure,
+		LockedUntil:         &expired,
+	}
+	throttle, attempts := newTestThrottle(user)
+
+	if err := throttle.CheckAccount(user); err != nil {
+		t.Fatalf("expired lock still rejects logins: %v", err)
+	}
+
+	throttle.RecordAccountFailure(user, user.Username, "10.0.0.1")
+
+	if user.IsLocked() {
+		t.Fatal("one bad attempt after the lock expired locked the account again")
+	}
+	if user.FailedLoginAttempts != 1 {
+		t.Fatalf("failed login count = %d, want 1", user.FailedLoginAttempts)
+	}
+	if len(attempts.attempts) != 0 {
+		t.Fatalf("unexpected lock events: %+v", attempts.attempts)
+	}
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"attendance-system/models"
+	"attendance-system/repositories"
+	"attendance-system/utils"
+	"os"
+	"strings"
+	"time"
+)
+
+// recoveryCodeCount is how many recovery codes a user gets on enrollment or regeneration
+const recoveryCodeCount = 10
+
+// MFAService manages TOTP two-factor enrollment and verifies second-factor codes
+type MFAService struct {
+	userRepo     *repositories.UserRepository
+	recoveryRepo *repositories.MFARecoveryCodeRepository
+	roleService  *RoleService
+}
+
+func NewMFAService() *MFAService {
+	return &MFAService{
+		userRepo:     repositories.NewUserRepository(),
+		recoveryRepo: repositories.NewMFARecoveryCodeRepository(),
+		roleService:  NewRoleService(),
+	}
+}
+
+// GetStatus reports whether the user has 2FA enabled and whether their role requires it
+func (s *MFAService) GetStatus(userID uint) (*models.MFAStatusResponse, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining, err := s.recoveryRepo.CountUnused(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MFAStatusResponse{
+		Enabled:                user.MFAEnabled,
+		Required:               s.roleService.RoleRequiresMFA(user.Role),
+		EnabledAt:              user.MFAEnabledAt,
+		RecoveryCodesRemaining: remaining,
+	}, nil
+}
+
+// BeginEnrollment generates a new secret for the user. 2FA is not enabled until
+// ConfirmEnrollment receives a valid code for it.
+func (s *MFAService) BeginEnrollment(userID uint) (*models.MFAEnrollmentResponse, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, utils.NewBadRequestError("two-factor authentication is already enabled")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, utils.NewInternalServerError("failed to generate secret")
+	}
+	encrypted, err := utils.EncryptString(secret)
+	if err != nil {
+		return nil, utils.NewInternalServerError("failed to store secret")
+	}
+
+	err = s.userRepo.UpdateFields(user.ID, map[string]interface{}{
+		"mfa_secret":         encrypted,
+		"mfa_last_used_step": nil,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	account := user.Email
+	if account == "" {
+		account = user.Username
+	}
+
+	return &models.MFAEnrollmentResponse{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(s.getIssuer(), account, secret),
+	}, nil
+}
+
+// ConfirmEnrollment enables 2FA once the user proves their authenticator works,
+// and returns the recovery codes. They are only shown this once.
+func (s *MFAService) ConfirmEnrollment(userID uint, code string) (*models.MFARecoveryCodesResponse, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, utils.NewBadRequestError("two-factor authentication is already enabled")
+	}
+	if user.MFASecret == "" {
+		return nil, utils.NewBadRequestError("two-factor enrollment has not been started")
+	}
+
+	ok, err := s.verifyTOTP(user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, utils.NewBadRequestError("invalid authentication code")
+	}
+
+	now := time.Now()
+	err = s.userRepo.UpdateFields(user.ID, map[string]interface{}{
+		"mfa_enabled":    true,
+		"mfa_enabled_at": now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueRecoveryCodes(user.ID)
+}
+
+// Disable turns off 2FA after checking the password and a current code.
+// Users whose role requires 2FA cannot turn it off.
+func (s *MFAService) Disable(userID uint, req models.MFADisableRequest) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.MFAEnabled {
+		return utils.NewBadRequestError("two-factor authentication is not enabled")
+	}
+	if s.roleService.RoleRequiresMFA(user.Role) {
+		return utils.NewForbiddenError("two-factor authentication is required for your role")
+	}
+	if !user.CheckPassword(req.Password) {
+		return utils.NewBadRequestError("password is incorrect")
+	}
+
+	ok, err := s.VerifyCode(user, req.Code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return utils.NewBadRequestError("invalid authentication code")
+	}
+
+	err = s.userRepo.UpdateFields(user.ID, map[string]interface{}{
+		"mfa_enabled":        false,
+		"mfa_secret":         "",
+		"mfa_enabled_at":     nil,
+		"mfa_last_used_step": nil,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.recoveryRepo.ReplaceForUser(user.ID, nil)
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after checking a current code
+func (s *MFAService) RegenerateRecoveryCodes(userID uint, code string) (*models.MFARecoveryCodesResponse, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.MFAEnabled {
+		return nil, utils.NewBadRequestError("two-factor authentication is not enabled")
+	}
+
+	ok, err := s.VerifyCode(user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, utils.NewBadRequestError("invalid authentication code")
+	}
+
+	return s.issueRecoveryCodes(user.ID)
+}
+
+// VerifyCode checks an authenticator code or, failing that, an unused recovery code.
+// A recovery code is spent when it matches.
+func (s *MFAService) VerifyCode(user *models.User, code string) (bool, error) {
+	if !user.MFAEnabled {
+		return false, nil
+	}
+
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) == 6 {
+		return s.verifyTOTP(user, code)
+	}
+
+	return s.recoveryRepo.Use(user.ID, utils.HashToken(normalizeRecoveryCode(code)))
+}
+
+// verifyTOTP checks the code against the user's secret and refuses a step that was already used
+func (s *MFAService) verifyTOTP(user *models.User, code string) (bool, error) {
+	secret, err := utils.DecryptString(user.MFASecret)
+	if err != nil {
+		return false, utils.NewInternalServerError("failed to read two-factor secret")
+	}
+
+	step, ok := utils.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	return s.userRepo.ClaimMFAStep(user.ID, step)
+}
+
+// issueRecoveryCodes generates a fresh set of recovery codes, replacing any existing ones
+func (s *MFAService) issueRecoveryCodes(userID uint) (*models.MFARecoveryCodesResponse, error) {
+	plain := make([]string, 0, recoveryCodeCount)
+	records := make([]models.MFARecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		random, err := models.GenerateSecureToken(5)
+		if err != nil {
+			return nil, utils.NewInternalServerError("failed to generate recovery codes")
+		}
+		code := random[:5] + "-" + random[5:]
+		plain = append(plain, code)
+		records = append(records, models.MFARecoveryCode{
+			UserID:    userID,
+			CodeHash:  utils.HashToken(normalizeRecoveryCode(code)),
+			CreatedAt: time.Now(),
+		})
+	}
+
+	if err := s.recoveryRepo.ReplaceForUser(userID, records); err != nil {
+		return nil, err
+	}
+
+	return &models.MFARecoveryCodesResponse{RecoveryCodes: plain}, nil
+}
+
+// getIssuer reads MFA_ISSUER, the name shown in authenticator apps
+func (s *MFAService) getIssuer() string {
+	issuer := os.Getenv("MFA_ISSUER")
+	if issuer == "" {
+		issuer = "Attendance System"
+	}
+	return issuer
+}
+
+// normalizeRecoveryCode lowercases the code and drops the separator so it matches however it was typed
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(code, "-", ""))
+}
@@ -0,0 +1,350 @@
+package services
+
+import (
+	"attendance-system/models"
+	"attendance-system/repositories"
+	"attendance-system/utils"
+	"time"
+)
+
+// NotificationService delivers notifications to users through the channels they chose,
+// their in-app inbox and email, and serves the inbox
+type NotificationService struct {
+	notificationRepo  *repositories.NotificationRepository
+	userRepo          *repositories.UserRepository
+	employeeRepo      *repositories.EmployeeRepository
+	departmentService *DepartmentService
+	auditService      *AuditService
+	digestService     *DigestService
+	outbox            *EmailOutboxService
+}
+
+func NewNotificationService() *NotificationService {
+	return &NotificationService{
+		notificationRepo:  repositories.NewNotificationRepository(),
+		userRepo:          repositories.NewUserRepository(),
+		employeeRepo:      repositories.NewEmployeeRepository(),
+		departmentService: NewDepartmentService(),
+		auditService:      NewAuditService(),
+		digestService:     NewDigestService(),
+		outbox:            NewEmailOutboxService(),
+	}
+}
+
+// GetNotifications lists the user's inbox, unread first. employeeID is the user's own
+// employee, if any, which the team scope uses to tell items about their reports apart.
+func (s *NotificationService) GetNotifications(userID uint, employeeID string, filter models.NotificationFilter, page, limit int) ([]models.Notification, *repositories.Pagination, error) {
+	switch filter.Category {
+	case "", models.NotificationCategoryAlert, models.NotificationCategoryReminder, models.NotificationCategoryApproval,
+		models.NotificationCategoryCorrection, models.NotificationCategoryAnnouncement:
+	default:
+		return nil, nil, utils.NewBadRequestError("category must be alert, reminder, approval, correction or announcement")
+	}
+	switch filter.Scope {
+	case "", models.NotificationScopePersonal, models.NotificationScopeTeam:
+	default:
+		return nil, nil, utils.NewBadRequestError("scope must be personal or team")
+	}
+
+	return s.notificationRepo.FindForUser(userID, employeeID, filter, page, limit)
+}
+
+func (s *NotificationService) GetUnreadCount(userID uint) (int64, error) {
+	return s.notificationRepo.CountUnread(userID)
+}
+
+func (s *NotificationService) MarkRead(userID, id uint) error {
+	found, err := s.notificationRepo.MarkRead(userID, id, time.Now())
+	if err != nil {
+		return err
+	}
+	if !found {
+		return utils.NewNotFoundError("notification not found")
+	}
+	return nil
+}
+
+// MarkAllRead marks the user's whole inbox read and returns how many notifications it changed
+func (s *NotificationService) MarkAllRead(userID uint) (int64, error) {
+	return s.notificationRepo.MarkAllRead(userID, time.Now())
+}
+
+// SendAnnouncement puts an announcement in the inbox of every active user, or of those in
+// a department. Announcements reach the inbox even with in-app notifications turned off.
+func (s *NotificationService) SendAnnouncement(req models.AnnouncementRequest, actor models.AuditActor) (*models.AnnouncementResponse, error) {
+	var users []models.User
+	var err error
+
+	if req.Scope == "department" {
+		if req.DepartmentID == nil {
+			return nil, utils.NewBadRequestError("department_id is required for the department scope")
+		}
+		departmentIDs, err := s.departmentService.GetDepartmentScope(*req.DepartmentID, req.IncludeSubDepartments)
+		if err != nil {
+			return nil, err
+		}
+		employees, err := s.employeeRepo.FindByDepartments(departmentIDs)
+		if err != nil {
+			return nil, err
+		}
+		users, err = s.usersOf(employees)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		users, err = s.userRepo.FindActive()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	recipients, err := s.announce(users, req.Title, req.Message, actor)
+	if err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, "announcement.send", models.AuditEntityAnnouncement, "", nil, map[string]interface{}{
+		"title":         req.Title,
+		"scope":         req.Scope,
+		"department_id": req.DepartmentID,
+		"recipients":    recipients,
+	})
+
+	return &models.AnnouncementResponse{Recipients: recipients}, nil
+}
+
+// SendTeamAnnouncement puts an announcement in the inbox of the manager's direct reports
+func (s *NotificationService) SendTeamAnnouncement(managerEmployeeID string, req models.TeamAnnouncementRequest, actor models.AuditActor) (*models.AnnouncementResponse, error) {
+	reports, err := s.employeeRepo.FindDirectReports(managerEmployeeID)
+	if err != nil {
+		return nil, err
+	}
+	if len(reports) == 0 {
+		return nil, utils.NewBadRequestError("you have no direct reports")
+	}
+
+	users, err := s.usersOf(reports)
+	if err != nil {
+		return nil, err
+	}
+	recipients, err := s.announce(users, req.Title, req.Message, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AnnouncementResponse{Recipients: recipients}, nil
+}
+
+// usersOf returns the active user accounts of the employees
+func (s *NotificationService) usersOf(employees []models.Employee) ([]models.User, error) {
+	if len(employees) == 0 {
+		return nil, nil
+	}
+	employeeIDs := make([]string, len(employees))
+	for i, employee := range employees {
+		employeeIDs[i] = employee.EmployeeID
+	}
+
+	users, err := s.userRepo.FindUsersByEmployeeIDs(employeeIDs)
+	if err != nil {
+		return nil, err
+	}
+	active := users[:0]
+	for _, user := range users {
+		if user.IsActive {
+			active = append(active, user)
+		}
+	}
+	return active, nil
+}
+
+func (s *NotificationService) announce(users []models.User, title, message string, actor models.AuditActor) (int, error) {
+	now := time.Now()
+	notifications := make([]models.Notification, len(users))
+	for i, user := range users {
+		notifications[i] = models.Notification{
+			UserID:     user.ID,
+			Type:       "announcement",
+			Category:   models.NotificationCategoryAnnouncement,
+			Title:      title,
+			Message:    message,
+			SenderName: actor.Name,
+			CreatedAt:  now,
+		}
+	}
+	if err := s.notificationRepo.CreateBatch(notifications); err != nil {
+		return 0, err
+	}
+	return len(notifications), nil
+}
+
+// GetPreferences returns the user's notification preferences, or the defaults if they
+// never set any
+func (s *NotificationService) GetPreferences(userID uint) (*models.NotificationPreference, error) {
+	preference, err := s.notificationRepo.FindPreference(userID)
+	if err != nil {
+		return nil, err
+	}
+	if preference == nil {
+		preference = &models.NotificationPreference{
+			UserID:       userID,
+			EmailEnabled: true,
+			InAppEnabled: true,
+		}
+	}
+	return preference, nil
+}
+
+func (s *NotificationService) UpdatePreferences(userID uint, req models.NotificationPreferenceRequest) (*models.NotificationPreference, error) {
+	preference, err := s.GetPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.EmailEnabled != nil {
+		preference.EmailEnabled = *req.EmailEnabled
+	}
+	if req.InAppEnabled != nil {
+		preference.InAppEnabled = *req.InAppEnabled
+	}
+	if req.QuietHoursStart != nil || req.QuietHoursEnd != nil {
+		start, end := "", ""
+		if req.QuietHoursStart != nil {
+			start = *req.QuietHoursStart
+		}
+		if req.QuietHoursEnd != nil {
+			end = *req.QuietHoursEnd
+		}
+
+		switch {
+		case start == "" && end == "":
+			preference.QuietHoursStart = nil
+			preference.QuietHoursEnd = nil
+		case start == "" || end == "":
+			return nil, utils.NewBadRequestError("quiet_hours_start and quiet_hours_end must be set together")
+		default:
+			if _, err := time.Parse("15:04", start); err != nil {
+				return nil, utils.NewBadRequestError("quiet_hours_start must be in HH:MM format")
+			}
+			if _, err := time.Parse("15:04", end); err != nil {
+				return nil, utils.NewBadRequestError("quiet_hours_end must be in HH:MM format")
+			}
+			if start == end {
+				return nil, utils.NewBadRequestError("quiet_hours_start and quiet_hours_end must differ")
+			}
+			preference.QuietHoursStart = &start
+			preference.QuietHoursEnd = &end
+		}
+	}
+
+	if req.DigestTime != nil {
+		if *req.DigestTime == "" {
+			preference.DigestTime = nil
+		} else if _, err := time.Parse("15:04", *req.DigestTime); err != nil {
+			return nil, utils.NewBadRequestError("digest_time must be in HH:MM format")
+		} else {
+			digestTime := *req.DigestTime
+			preference.DigestTime = &digestTime
+		}
+	}
+	if req.DigestDaily != nil {
+		preference.DigestDaily = *req.DigestDaily
+	}
+	if req.DigestWeekly != nil {
+		preference.DigestWeekly = *req.DigestWeekly
+	}
+	if (req.DigestDaily != nil && *req.DigestDaily) || (req.DigestWeekly != nil && *req.DigestWeekly) {
+		user, err := s.userRepo.FindByID(userID)
+		if err != nil {
+			return nil, err
+		}
+		allowed, err := s.digestService.CanReceiveDigest(user)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, utils.NewForbiddenError("digests are only available to managers linked to an employee")
+		}
+	}
+
+	preference.UpdatedAt = time.Now()
+	if err := s.notificationRepo.SavePreference(preference); err != nil {
+		return nil, err
+	}
+
+	return preference, nil
+}
+
+// Notify renders the email of the given kind in the user's locale and delivers it through
+// the user's enabled channels. The in-app notification is filled in with the email's
+// subject and summary. Emails due during the user's quiet hours are held back until they end.
+func (s *NotificationService) Notify(user *models.User, notification models.Notification, emailKind string, data EmailTemplateData) error {
+	preference, err := s.GetPreferences(user.ID)
+	if err != nil {
+		return err
+	}
+	if !preference.InAppEnabled && !preference.EmailEnabled {
+		return nil
+	}
+
+	templates, err := getEmailTemplates()
+	if err != nil {
+		return err
+	}
+	rendered, err := templates.render(emailKind, user.Locale, data)
+	if err != nil {
+		return err
+	}
+
+	if preference.InAppEnabled {
+		notification.UserID = user.ID
+		notification.Title = rendered.Subject
+		notification.Message = rendered.Summary
+		notification.CreatedAt = time.Now()
+		if err := s.notificationRepo.Create(&notification); err != nil {
+			return err
+		}
+	}
+
+	if preference.EmailEnabled && user.Email != "" {
+		sendAt := quietHoursEnd(preference, time.Now())
+		if err := s.outbox.EnqueueAt(emailKind, user.Email, rendered.Subject, rendered.HTML, rendered.Text, sendAt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// quietHoursEnd returns when the user's quiet hours end if t falls inside them, or t otherwise
+func quietHoursEnd(preference *models.NotificationPreference, t time.Time) time.Time {
+	if preference.QuietHoursStart == nil || preference.QuietHoursEnd == nil {
+		return t
+	}
+	start, err := time.Parse("15:04", *preference.QuietHoursStart)
+	if err != nil {
+		return t
+	}
+	end, err := time.Parse("15:04", *preference.QuietHoursEnd)
+	if err != nil {
+		return t
+	}
+
+	startAt := time.Date(t.Year(), t.Month(), t.Day(), start.Hour(), start.Minute(), 0, 0, t.Location())
+	endAt := time.Date(t.Year(), t.Month(), t.Day(), end.Hour(), end.Minute(), 0, 0, t.Location())
+
+	if startAt.Before(endAt) {
+		if !t.Before(startAt) && t.Before(endAt) {
+			return endAt
+		}
+		return t
+	}
+
+	// Quiet hours span midnight, e.g. 22:00 to 07:00
+	if !t.Before(startAt) {
+		return endAt.AddDate(0, 0, 1)
+	}
+	if t.Before(endAt) {
+		return endAt
+	}
+	return t
+}
@@ -0,0 +1,444 @@
+package services
+
+import (
+	"attendance-system/models"
+	"attendance-system/repositories"
+	"attendance-system/utils"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	oidcStateTTL = 10 * time.Minute // Time allowed to finish a login at the identity provider
+	oidcCacheTTL = time.Hour        // How long discovery metadata and signing keys are reused
+)
+
+// oidcSigningMethods are the ID token algorithms accepted. "none" and HMAC are never allowed.
+var oidcSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// oidcProviderMetadata is the part of the discovery document the login flow needs
+type oidcProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcIDTokenClaims are the ID token claims used to match a user
+type oidcIDTokenClaims struct {
+	Email           string      `json:"email"`
+	EmailVerified   interface{} `json:"email_verified"` // Some providers send "true" as a string
+	Nonce           string      `json:"nonce"`
+	AuthorizedParty string      `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+// oidcProviderCache is shared so discovery and JWKS are not fetched on every login
+var oidcProviderCache = struct {
+	sync.Mutex
+	issuer      string
+	metadata    *oidcProviderMetadata
+	fetchedAt   time.Time
+	keys        utils.JWKS
+	keysFetched time.Time
+}{}
+
+// OIDCService implements OpenID Connect authorization-code login with PKCE
+type OIDCService struct {
+	stateRepo    oidcStateStore
+	userRepo     oidcUserStore
+	tokenService setupTokenInvalidator
+	httpClient   *http.Client
+}
+
+// oidcStateStore is the part of OIDCStateRepository the login flow uses
+type oidcStateStore interface {
+	Create(state *models.OIDCState) error
+	Consume(stateHash string) (*models.OIDCState, error)
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+// oidcUserStore is the part of UserRepository used to match and link identities
+type oidcUserStore interface {
+	FindByOIDCSubject(subject string) (*models.User, error)
+	FindByEmail(email string) (*models.User, error)
+	UpdateFields(userID uint, updates map[string]interface{}) error
+}
+
+// setupTokenInvalidator retires the setup links of accounts activated through single sign-on
+type setupTokenInvalidator interface {
+	InvalidateAll(userID uint, purpose string) error
+}
+
+func NewOIDCService() *OIDCService {
+	return &OIDCService{
+		stateRepo:    repositories.NewOIDCStateRepository(),
+		userRepo:     repositories.NewUserRepository(),
+		tokenService: NewOneTimeTokenService(),
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Enabled reports whether single sign-on is configured
+func (s *OIDCService) Enabled() bool {
+	return s.getIssuerURL() != "" && os.Getenv("OIDC_CLIENT_ID") != ""
+}
+
+// BeginLogin starts a login and returns the identity provider URL to send the browser to
+func (s *OIDCService) BeginLogin() (*models.OIDCLoginResponse, error) {
+	if !s.Enabled() {
+		return nil, utils.NewNotFoundError("single sign-on is not configured")
+	}
+
+	metadata, err := s.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := models.GenerateSecureToken(32)
+	if err != nil {
+		return nil, utils.NewInternalServerError("failed to start single sign-on")
+	}
+	nonce, err := models.GenerateSecureToken(16)
+	if err != nil {
+		return nil, utils.NewInternalServerError("failed to start single sign-on")
+	}
+	verifier, err := models.GenerateSecureToken(32)
+	if err != nil {
+		return nil, utils.NewInternalServerError("failed to start single sign-on")
+	}
+
+	record := &models.OIDCState{
+		StateHash:    utils.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(oidcStateTTL),
+		CreatedAt:    time.Now(),
+	}
+	if err := s.stateRepo.Create(record); err != nil {
+		return nil, utils.NewInternalServerError("failed to start single sign-on")
+	}
+
+	authURL, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return nil, utils.NewInternalServerError("identity provider has an invalid authorization endpoint")
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", os.Getenv("OIDC_CLIENT_ID"))
+	query.Set("redirect_uri", s.getRedirectURL())
+	query.Set("scope", s.getScopes())
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+
+	return &models.OIDCLoginResponse{
+		AuthorizationURL: authURL.String(),
+		State:            state,
+	}, nil
+}
+
+// Authenticate finishes a login with the code the identity provider returned and
+// resolves the local user the verified ID token belongs to
+func (s *OIDCService) Authenticate(code, state string) (*models.User, error) {
+	if !s.Enabled() {
+		return nil, utils.NewNotFoundError("single sign-on is not configured")
+	}
+
+	stored, err := s.stateRepo.Consume(utils.HashToken(state))
+	if err != nil || stored.ExpiresAt.Before(time.Now()) {
+		return nil, utils.NewBadRequestError("invalid or expired login state")
+	}
+
+	metadata, err := s.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, err := s.exchangeCode(metadata, code, stored.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := s.verifyIDToken(metadata, rawIDToken, stored.Nonce)
+	if err != nil {
+		log.Printf("OIDC ID token rejected: %v", err)
+		return nil, utils.NewUnauthorizedError("single sign-on failed")
+	}
+
+	return s.resolveUser(claims)
+}
+
+// PruneExpired removes logins that were started but never finished
+func (s *OIDCService) PruneExpired() error {
+	_, err := s.stateRepo.DeleteExpired(time.Now())
+	return err
+}
+
+// exchangeCode redeems the authorization code and PKCE verifier for an ID token
+func (s *OIDCService) exchangeCode(metadata *oidcProviderMetadata, code, verifier string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", s.getRedirectURL())
+	form.Set("client_id", os.Getenv("OIDC_CLIENT_ID"))
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequest(http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", utils.NewInternalServerError("failed to contact identity provider")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// Confidential clients authenticate with client_secret_basic; public clients rely on PKCE alone
+	if secret := os.Getenv("OIDC_CLIENT_SECRET"); secret != "" {
+		req.SetBasicAuth(url.QueryEscape(os.Getenv("OIDC_CLIENT_ID")), url.QueryEscape(secret))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		log.Printf("OIDC token request failed: %v", err)
+		return "", utils.NewCustomError(http.StatusBadGateway, "failed to contact identity provider")
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", utils.NewCustomError(http.StatusBadGateway, "invalid response from identity provider")
+	}
+	if resp.StatusCode != http.StatusOK || body.IDToken == "" {
+		log.Printf("OIDC token request rejected: status=%d error=%s %s", resp.StatusCode, body.Error, body.ErrorDescription)
+		return "", utils.NewUnauthorizedError("single sign-on failed")
+	}
+
+	return body.IDToken, nil
+}
+
+// verifyIDToken checks the signature against the provider's JWKS and the standard claims
+func (s *OIDCService) verifyIDToken(metadata *oidcProviderMetadata, rawIDToken, nonce string) (*oidcIDTokenClaims, error) {
+	clientID := os.Getenv("OIDC_CLIENT_ID")
+	claims := &oidcIDTokenClaims{}
+
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		keyID, _ := token.Header["kid"].(string)
+		return s.signingKey(metadata, keyID)
+	},
+		jwt.WithValidMethods(oidcSigningMethods),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, errors.New("nonce mismatch")
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != clientID {
+		return nil, errors.New("token was issued to another client")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("missing subject")
+	}
+
+	return claims, nil
+}
+
+// resolveUser finds the user for a verified identity: by the linked subject first, then by email.
+// With OIDC_JIT_LINKING, accounts still waiting for setup are activated on first sign-in.
+func (s *OIDCService) resolveUser(claims *oidcIDTokenClaims) (*models.User, error) {
+	if user, err := s.userRepo.FindByOIDCSubject(claims.Subject); err == nil {
+		if !user.IsActive {
+			return nil, utils.NewUnauthorizedError("account is not active")
+		}
+		return user, nil
+	}
+
+	if claims.Email == "" || !claimIsTrue(claims.EmailVerified) {
+		return nil, utils.NewUnauthorizedError("identity provider did not return a verified email")
+	}
+
+	user, err := s.userRepo.FindByEmail(claims.Email)
+	if err != nil {
+		return nil, utils.NewUnauthorizedError("no account matches this email")
+	}
+	if user.OIDCSubject != nil && *user.OIDCSubject != claims.Subject {
+		return nil, utils.NewUnauthorizedError("account is linked to a different identity")
+	}
+
+	updates := map[string]interface{}{"oidc_subject": claims.Subject}
+	now := time.Now()
+	if user.EmailVerifiedAt == nil {
+		updates["email_verified_at"] = now
+		user.EmailVerifiedAt = &now
+	}
+
+	if !user.IsActive {
+		// Only accounts that never finished setup can be activated, not deactivated ones:
+		// they have no password and have never signed in
+		if user.Password != "" || user.LastLogin != nil || !s.jitLinkingEnabled() {
+			return nil, utils.NewUnauthorizedError("account is not active")
+		}
+		updates["is_active"] = true
+		user.ActivateUser()
+		if err := s.tokenService.InvalidateAll(user.ID, models.TokenPurposeAccountSetup); err != nil {
+			log.Printf("Failed to invalidate setup tokens for user %d: %v", user.ID, err)
+		}
+	}
+
+	if err := s.userRepo.UpdateFields(user.ID, updates); err != nil {
+		return nil, err
+	}
+	user.OIDCSubject = &claims.Subject
+
+	return user, nil
+}
+
+// discover fetches the provider's discovery document, reusing it for oidcCacheTTL
+func (s *OIDCService) discover() (*oidcProviderMetadata, error) {
+	issuer := s.getIssuerURL()
+
+	oidcProviderCache.Lock()
+	defer oidcProviderCache.Unlock()
+
+	if oidcProviderCache.metadata != nil && oidcProviderCache.issuer == issuer && time.Since(oidcProviderCache.fetchedAt) < oidcCacheTTL {
+		return oidcProviderCache.metadata, nil
+	}
+
+	var metadata oidcProviderMetadata
+	if err := s.getJSON(issuer+"/.well-known/openid-configuration", &metadata); err != nil {
+		log.Printf("OIDC discovery failed: %v", err)
+		return nil, utils.NewCustomError(http.StatusBadGateway, "failed to contact identity provider")
+	}
+	if strings.TrimSuffix(metadata.Issuer, "/") != issuer {
+		log.Printf("OIDC discovery issuer mismatch: expected %s, got %s", issuer, metadata.Issuer)
+		return nil, utils.NewCustomError(http.StatusBadGateway, "identity provider configuration is invalid")
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, utils.NewCustomError(http.StatusBadGateway, "identity provider configuration is invalid")
+	}
+
+	oidcProviderCache.issuer = issuer
+	oidcProviderCache.metadata = &metadata
+	oidcProviderCache.fetchedAt = time.Now()
+	oidcProviderCache.keys = utils.JWKS{}
+	oidcProviderCache.keysFetched = time.Time{}
+	return &metadata, nil
+}
+
+// signingKey returns the provider key with the given ID. The key set is fetched again
+// when the ID is unknown, so keys rotated at the provider are picked up.
+func (s *OIDCService) signingKey(metadata *oidcProviderMetadata, keyID string) (interface{}, error) {
+	oidcProviderCache.Lock()
+	defer oidcProviderCache.Unlock()
+
+	key, found := findJWK(oidcProviderCache.keys, keyID)
+	age := time.Since(oidcProviderCache.keysFetched)
+	// Refetch unknown key IDs at most every 10 seconds so made-up IDs can't hammer the provider
+	if age > oidcCacheTTL || (!found && age > 10*time.Second) {
+		var keys utils.JWKS
+		if err := s.getJSON(metadata.JWKSURI, &keys); err != nil {
+			return nil, fmt.Errorf("fetching JWKS: %w", err)
+		}
+		oidcProviderCache.keys = keys
+		oidcProviderCache.keysFetched = time.Now()
+		key, found = findJWK(keys, keyID)
+	}
+	if !found {
+		return nil, fmt.Errorf("unknown signing key %q", keyID)
+	}
+
+	return key.PublicKey()
+}
+
+func (s *OIDCService) getJSON(target string, out interface{}) error {
+	resp, err := s.httpClient.Get(target)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", target, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
+
+// findJWK picks the key by ID, or the only key when the token doesn't name one
+func findJWK(keys utils.JWKS, keyID string) (*utils.JWK, bool) {
+	if keyID == "" {
+		if len(keys.Keys) == 1 {
+			return &keys.Keys[0], true
+		}
+		return nil, false
+	}
+	return keys.Find(keyID)
+}
+
+func claimIsTrue(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	}
+	return false
+}
+
+// getIssuerURL reads OIDC_ISSUER_URL without a trailing slash
+func (s *OIDCService) getIssuerURL() string {
+	return strings.TrimSuffix(os.Getenv("OIDC_ISSUER_URL"), "/")
+}
+
+// getRedirectURL reads OIDC_REDIRECT_URL, the frontend page that receives the code and state
+func (s *OIDCService) getRedirectURL() string {
+	redirectURL := os.Getenv("OIDC_REDIRECT_URL")
+	if redirectURL == "" {
+		redirectURL = s.getFrontendURL() + "/auth/sso/callback"
+	}
+	return redirectURL
+}
+
+func (s *OIDCService) getScopes() string {
+	scopes := os.Getenv("OIDC_SCOPES")
+	if scopes == "" {
+		scopes = "openid email profile"
+	}
+	return scopes
+}
+
+func (s *OIDCService) jitLinkingEnabled() bool {
+	return strings.EqualFold(os.Getenv("OIDC_JIT_LINKING"), "true")
+}
+
+func (s *OIDCService) getFrontendURL() string {
+	frontendURL := os.Getenv("FRONTEND_URL")
+	if frontendURL == "" {
+		frontendURL = "http://localhost:5173"
+	}
+	return frontendURL
+}
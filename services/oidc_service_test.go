@@ -0,0 +1,361 @@
+package services
+
+import (
+	"attendance-system/models"
+	"attendance-system/utils"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testOIDCClientID = "attendance-system"
+
+// mockOIDCProvider is a local identity provider serving discovery, JWKS and a token
+// endpoint that only redeems a code with the PKCE verifier it was issued for
+type mockOIDCProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]mockAuthorization
+}
+
+// mockAuthorization is a code the provider handed out and the ID token it redeems for
+type mockAuthorization struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	provider := &mockOIDCProvider{key: key, codes: map[string]mockAuthorization{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcProviderMetadata{
+			Issuer:                provider.server.URL,
+			AuthorizationEndpoint: provider.server.URL + "/authorize",
+			TokenEndpoint:         provider.server.URL + "/token",
+			JWKSURI:               provider.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		jwk, err := utils.NewJWK("test-key", "RS256", &key.PublicKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(utils.JWKS{Keys: []utils.JWK{jwk}})
+	})
+	mux.HandleFunc("/token", provider.token)
+
+	provider.server = httptest.NewServer(mux)
+	t.Cleanup(provider.server.Close)
+	t.Setenv("OIDC_ISSUER_URL", provider.server.URL)
+	t.Setenv("OIDC_CLIENT_ID", testOIDCClientID)
+	t.Setenv("OIDC_CLIENT_SECRET", "")
+	t.Setenv("OIDC_REDIRECT_URL", "http://localhost:5173/auth/sso/callback")
+	return provider
+}
+
+func (p *mockOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	p.mu.Lock()
+	authorization, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != authorization.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"id_token": p.sign(authorization.claims)})
+}
+
+// authorize plays the user signing in at the provider: it reads the authorization URL
+// from BeginLogin and returns a code for an ID token with the given claims
+func (p *mockOIDCProvider) authorize(t *testing.T, authorizationURL string, claims jwt.MapClaims) string {
+	t.Helper()
+	parsed, err := url.Parse(authorizationURL)
+	if err != nil {
+		t.Fatalf("parse authorization URL: %v", err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("authorization URL has no S256 code challenge: %s", authorizationURL)
+	}
+	if query.Get("client_id") != testOIDCClientID {
+		t.Fatalf("client_id = %q, want %q", query.Get("client_id"), testOIDCClientID)
+	}
+
+	idClaims := p.claims(query.Get("nonce"))
+	for name, value := range claims {
+		idClaims[name] = value
+	}
+
+	code := "code-" + query.Get("state")[:8]
+	p.mu.Lock()
+	p.codes[code] = mockAuthorization{challenge: query.Get("code_challenge"), claims: idClaims}
+	p.mu.Unlock()
+	return code
+}
+
+// claims are valid ID token claims for the subject "provider-user-1"
+func (p *mockOIDCProvider) claims(nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            p.server.URL,
+		"aud":            testOIDCClientID,
+		"sub":            "provider-user-1",
+		"email":          "jdoe@example.com",
+		"email_verified": true,
+		"nonce":          nonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+	}
+}
+
+func (p *mockOIDCProvider) sign(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test-key"
+	signed, err := token.SignedString(p.key)
+	if err != nil {
+		panic(err)
+	}
+	return signed
+}
+
+// fakeOIDCStateStore keeps started logins in memory
+type fakeOIDCStateStore struct {
+	states map[string]models.OIDCState
+}
+
+func (f *fakeOIDCStateStore) Create(state *models.OIDCState) error {
+	f.states[state.StateHash] = *state
+	return nil
+}
+
+func (f *fakeOIDCStateStore) Consume(stateHash string) (*models.OIDCState, error) {
+	state, ok := f.states[stateHash]
+	if !ok {
+		return nil, utils.NewNotFoundError("login state not found")
+	}
+	delete(f.states, stateHash)
+	return &state, nil
+}
+
+func (f *fakeOIDCStateStore) DeleteExpired(now time.Time) (int64, error) {
+	return 0, nil
+}
+
+// fakeOIDCUserStore holds a single user and the fields written to it
+type fakeOIDCUserStore struct {
+	user    *models.User
+	updates map[string]interface{}
+}
+
+func (f *fakeOIDCUserStore) FindByOIDCSubject(subject string) (*models.User, error) {
+	if f.user == nil || f.user.OIDCSubject == nil || *f.user.OIDCSubject != subject {
+		return nil, utils.NewNotFoundError("user not found")
+	}
+	return f.user, nil
+}
+
+func (f *fakeOIDCUserStore) FindByEmail(email string) (*models.User, error) {
+	if f.user == nil || f.user.Email != email {
+		return nil, utils.NewNotFoundError("user not found")
+	}
+	return f.user, nil
+}
+
+func (f *fakeOIDCUserStore) UpdateFields(userID uint, updates map[string]interface{}) error {
+	f.updates = updates
+	return nil
+}
+
+// fakeSetupTokens records which users had their setup links retired
+type fakeSetupTokens struct {
+	invalidated []uint
+}
+
+func (f *fakeSetupTokens) InvalidateAll(userID uint, purpose string) error {
+	f.invalidated = append(f.invalidated, userID)
+	return nil
+}
+
+func newTestOIDCService(user *models.User) (*OIDCService, *fakeOIDCStateStore, *fakeOIDCUserStore, *fakeSetupTokens) {
+	states := &fakeOIDCStateStore{states: map[string]models.OIDCState{}}
+	users := &fakeOIDCUserStore{user: user}
+	tokens := &fakeSetupTokens{}
+	return &OIDCService{
+		stateRepo:    states,
+		userRepo:     users,
+		tokenService: tokens,
+		httpClient:   &http.Client{Timeout: 5 * time.Second},
+	}, states, users, tokens
+}
+
+func TestOIDCLoginPKCERoundTrip(t *testing.T) {
+	provider := newMockOIDCProvider(t)
+	user := &models.User{ID: 3, Username: "jdoe", Email: "jdoe@example.com", Password: "hashed", IsActive: true}
+	service, _, users, _ := newTestOIDCService(user)
+
+	login, err := service.BeginLogin()
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	code := provider.authorize(t, login.AuthorizationURL, nil)
+
+	got, err := service.Authenticate(code, login.State)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if got.ID != user.ID {
+		t.Fatalf("authenticated user %d, want %d", got.ID, user.ID)
+	}
+	if users.updates["oidc_subject"] != "provider-user-1" {
+		t.Fatalf("identity not linked, updates = %v", users.updates)
+	}
+
+	// The state is single-use
+	if _, err := service.Authenticate(code, login.State); err == nil {
+		t.Fatal("state was accepted twice")
+	}
+}
+
+func TestOIDCLoginRejectsWrongVerifier(t *testing.T) {
+	provider := newMockOIDCProvider(t)
+	user := &models.User{ID: 3, Email: "jdoe@example.com", Password: "hashed", IsActive: true}
+	service, states, _, _ := newTestOIDCService(user)
+
+	login, err := service.BeginLogin()
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	code := provider.authorize(t, login.AuthorizationURL, nil)
+
+	hash := utils.HashToken(login.State)
+	state := states.states[hash]
+	state.CodeVerifier = "not-the-verifier"
+	states.states[hash] = state
+
+	if _, err := service.Authenticate(code, login.State); err == nil {
+		t.Fatal("code redeemed with the wrong PKCE verifier")
+	}
+}
+
+func TestOIDCVerifyIDTokenRejectsBadClaims(t *testing.T) {
+	provider := newMockOIDCProvider(t)
+	service, _, _, _ := newTestOIDCService(nil)
+	metadata, err := service.discover()
+	if err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+
+	if _, err := service.verifyIDToken(metadata, provider.sign(provider.claims("nonce-1")), "nonce-1"); err != nil {
+		t.Fatalf("valid ID token rejected: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		claim string
+		value interface{}
+	}{
+		{"wrong audience", "aud", "another-client"},
+		{"wrong issuer", "iss", "https://evil.example.com"},
+		{"wrong nonce", "nonce", "nonce-2"},
+		{"expired", "exp", time.Now().Add(-time.Hour).Unix()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := provider.claims("nonce-1")
+			claims[tt.claim] = tt.value
+			if _, err := service.verifyIDToken(metadata, provider.sign(claims), "nonce-1"); err == nil {
+				t.Fatalf("ID token with %s accepted", tt.name)
+			}
+		})
+	}
+}
+
+func TestOIDCResolveUserRequiresVerifiedEmail(t *testing.T) {
+	user := &models.User{ID: 3, Email: "jdoe@example.com", Password: "hashed", IsActive: true}
+
+	for _, verified := range []interface{}{false, "false", nil} {
+		service, _, users, _ := newTestOIDCService(user)
+		claims := &oidcIDTokenClaims{Email: user.Email, EmailVerified: verified}
+		claims.Subject = "provider-user-1"
+
+		if _, err := service.resolveUser(claims); err == nil {
+			t.Fatalf("email_verified=%v accepted", verified)
+		}
+		if users.updates != nil {
+			t.Fatalf("email_verified=%v linked the account", verified)
+		}
+	}
+}
+
+func TestOIDCResolveUserJITActivation(t *testing.T) {
+	lastLogin := time.Now().Add(-24 * time.Hour)
+	tests := []struct {
+		name     string
+		user     models.User
+		jit      string
+		activate bool
+	}{
+		{"pending setup", models.User{Password: ""}, "true", true},
+		{"pending setup without JIT linking", models.User{Password: ""}, "false", false},
+		{"deactivated with a password", models.User{Password: "hashed"}, "true", false},
+		{"deactivated after signing in", models.User{Password: "", LastLogin: &lastLogin}, "true", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OIDC_JIT_LINKING", tt.jit)
+			user := tt.user
+			user.ID = 3
+			user.Email = "jdoe@example.com"
+			service, _, users, tokens := newTestOIDCService(&user)
+			claims := &oidcIDTokenClaims{Email: user.Email, EmailVerified: true}
+			claims.Subject = "provider-user-1"
+
+			got, err := service.resolveUser(claims)
+			if !tt.activate {
+				if err == nil || user.IsActive || users.updates != nil {
+					t.Fatalf("account activated: err=%v updates=%v", err, users.updates)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("resolveUser: %v", err)
+			}
+			if !got.IsActive || users.updates["is_active"] != true {
+				t.Fatalf("account not activated, updates = %v", users.updates)
+			}
+			if len(tokens.invalidated) != 1 || tokens.invalidated[0] != user.ID {
+				t.Fatalf("setup tokens not invalidated: %v", tokens.invalidated)
+			}
+		})
+	}
+}
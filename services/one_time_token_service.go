@@ -0,0 +1,100 @@
+package services
+
+import (
+	"attendance-system/models"
+	"attendance-system/repositories"
+	"attendance-system/utils"
+	"time"
+)
+
+type OneTimeTokenService struct {
+	tokenRepo *repositories.OneTimeTokenRepository
+}
+
+func NewOneTimeTokenService() *OneTimeTokenService {
+	return &OneTimeTokenService{
+		tokenRepo: repositories.NewOneTimeTokenRepository(),
+	}
+}
+
+// Issue creates a single-use token for the user, replacing any outstanding token of the same purpose.
+// The plain token is returned once and only its hash is stored.
+func (s *OneTimeTokenService) Issue(userID uint, purpose string, ttl time.Duration, requestIP string) (string, error) {
+	if err := s.tokenRepo.InvalidateForUser(userID, purpose); err != nil {
+		return "", err
+	}
+
+	plain, err := models.GenerateSecureToken(32)
+	if err != nil {
+		return "", utils.NewInternalServerError("failed to generate token")
+	}
+
+	token := &models.OneTimeToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(plain),
+		SingleUse: true,
+		ExpiresAt: time.Now().Add(ttl),
+		RequestIP: requestIP,
+		CreatedAt: time.Now(),
+	}
+	if err := s.tokenRepo.Create(token); err != nil {
+		return "", err
+	}
+
+	return plain, nil
+}
+
+// Verify checks that the token is valid for the purpose without using it up
+func (s *OneTimeTokenService) Verify(plain, purpose string) (*models.OneTimeToken, error) {
+	if plain == "" {
+		return nil, utils.NewBadRequestError("token is required")
+	}
+
+	token, err := s.tokenRepo.FindByHash(utils.HashToken(plain), purpose)
+	if err != nil {
+		return nil, utils.NewNotFoundError("invalid or expired token")
+	}
+	if !token.IsUsable() || token.User == nil {
+		return nil, utils.NewBadRequestError("token has expired or has already been used")
+	}
+
+	return token, nil
+}
+
+// Consume verifies the token and marks it as used so it cannot be presented again
+func (s *OneTimeTokenService) Consume(plain, purpose string) (*models.OneTimeToken, error) {
+	token, err := s.Verify(plain, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.SingleUse {
+		used, err := s.tokenRepo.MarkUsed(token.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !used {
+			return nil, utils.NewBadRequestError("token has expired or has already been used")
+		}
+	}
+
+	return token, nil
+}
+
+// HasPending checks if the user has an outstanding token of the purpose
+func (s *OneTimeTokenService) HasPending(userID uint, purpose string) bool {
+	pending, err := s.tokenRepo.HasPending(userID, purpose)
+	return err == nil && pending
+}
+
+// InvalidateAll marks every outstanding token of the purpose for the user as used
+func (s *OneTimeTokenService) InvalidateAll(userID uint, purpose string) error {
+	return s.tokenRepo.InvalidateForUser(userID, purpose)
+}
+
+// PruneExpired removes expired tokens
+func (s *OneTimeTokenService) PruneExpired() error {
+	_, err := s.tokenRepo.DeleteExpired(time.Now())
+	return err
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"attendance-system/models"
+	"attendance-system/repositories"
+	"attendance-system/utils"
+	"bufio"
+	_ "embed"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcrypt ignores everything after 72 bytes, so longer passwords are refused rather than silently cut
+const passwordMaxLength = 72
+
+//go:embed common_passwords.txt
+var commonPasswords string
+
+// passwordDenylist holds the embedded list plus PASSWORD_DENYLIST_FILE, loaded once
+var passwordDenylist struct {
+	once      sync.Once
+	passwords map[string]bool
+}
+
+// PasswordPolicyService is the single place passwords are checked and set. Register,
+// account setup, password reset and change-password all go through it.
+type PasswordPolicyService struct {
+	historyRepo *repositories.PasswordHistoryRepository
+}
+
+func NewPasswordPolicyService() *PasswordPolicyService {
+	return &PasswordPolicyService{
+		historyRepo: repositories.NewPasswordHistoryRepository(),
+	}
+}
+
+// GetPolicy returns the active rules, read from the PASSWORD_* environment variables
+func (s *PasswordPolicyService) GetPolicy() models.PasswordPolicyResponse {
+	return models.PasswordPolicyResponse{
+		MinLength:        getIntEnv("PASSWORD_MIN_LENGTH", 8),
+		MaxLength:        passwordMaxLength,
+		RequireUppercase: getBoolEnv("PASSWORD_REQUIRE_UPPERCASE", true),
+		RequireLowercase: getBoolEnv("PASSWORD_REQUIRE_LOWERCASE", true),
+		RequireDigit:     getBoolEnv("PASSWORD_REQUIRE_DIGIT", true),
+		RequireSymbol:    getBoolEnv("PASSWORD_REQUIRE_SYMBOL", false),
+		HistoryCount:     getCountEnv("PASSWORD_HISTORY_COUNT", 5),
+		MaxAgeDays:       getCountEnv("PASSWORD_MAX_AGE_DAYS", 0),
+	}
+}
+
+// Validate checks the password against the policy. The user is used to refuse passwords
+// containing the username or email, and to check history when the account already exists.
+func (s *PasswordPolicyService) Validate(password string, user *models.User) error {
+	policy := s.GetPolicy()
+	problems := []string{}
+
+	length := len([]rune(password))
+	if length < policy.MinLength {
+		problems = append(problems, "be at least "+strconv.Itoa(policy.MinLength)+" characters")
+	}
+	if len(password) > policy.MaxLength {
+		problems = append(problems, "be at most "+strconv.Itoa(policy.MaxLength)+" bytes")
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if policy.RequireUppercase && !hasUpper {
+		problems = append(problems, "contain an uppercase letter")
+	}
+	if policy.RequireLowercase && !hasLower {
+		problems = append(problems, "contain a lowercase letter")
+	}
+	if policy.RequireDigit && !hasDigit {
+		problems = append(problems, "contain a digit")
+	}
+	if policy.RequireSymbol && !hasSymbol {
+		problems = append(problems, "contain a symbol")
+	}
+
+	if len(problems) > 0 {
+		return utils.NewBadRequestError("password must " + strings.Join(problems, ", "))
+	}
+
+	if isDenylisted(password) {
+		return utils.NewBadRequestError("password is too common, please choose another")
+	}
+
+	if user != nil {
+		lower := strings.ToLower(password)
+		if user.Username != "" && strings.Contains(lower, strings.ToLower(user.Username)) {
+			return utils.NewBadRequestError("password must not contain your username")
+		}
+		if local := strings.SplitN(user.Email, "@", 2)[0]; len(local) >= 3 && strings.Contains(lower, strings.ToLower(local)) {
+			return utils.NewBadRequestError("password must not contain your email address")
+		}
+
+		if user.ID != 0 && policy.HistoryCount > 0 {
+			if user.CheckPassword(password) {
+				return utils.NewBadRequestError("new password must be different from the current password")
+			}
+			history, err := s.historyRepo.FindRecent(user.ID, policy.HistoryCount)
+			if err != nil {
+				return err
+			}
+			for _, entry := range history {
+				if bcrypt.CompareHashAndPassword([]byte(entry.PasswordHash), []byte(password)) == nil {
+					return utils.NewBadRequestError("password was used recently, please choose another")
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// SetPassword validates and hashes a new password on the user. The caller saves the
+// user and then calls RecordChange.
+func (s *PasswordPolicyService) SetPassword(user *models.User, password string) error {
+	if err := s.Validate(password, user); err != nil {
+		return err
+	}
+
+	user.Password = password
+	if err := user.HashPassword(); err != nil {
+		return utils.NewInternalServerError("failed to hash password")
+	}
+
+	now := time.Now()
+	user.PasswordChangedAt = &now
+	user.UpdatedAt = now
+	return nil
+}
+
+// RecordChange adds the user's current password hash to their history and trims old entries
+func (s *PasswordPolicyService) RecordChange(user *models.User) {
+	keep := s.GetPolicy().HistoryCount
+	if keep == 0 || user.Password == "" {
+		return
+	}
+
+	entry := &models.PasswordHistory{
+		UserID:       user.ID,
+		PasswordHash: user.Password,
+		CreatedAt:    time.Now(),
+	}
+	if err := s.historyRepo.Create(entry); err != nil {
+		log.Printf("Failed to record password history for user %d: %v", user.ID, err)
+		return
+	}
+	if err := s.historyRepo.DeleteAllButRecent(user.ID, keep); err != nil {
+		log.Printf("Failed to trim password history for user %d: %v", user.ID, err)
+	}
+}
+
+// IsExpired reports whether the user's password is older than PASSWORD_MAX_AGE_DAYS
+func (s *PasswordPolicyService) IsExpired(user *models.User) bool {
+	maxAgeDays := s.GetPolicy().MaxAgeDays
+	if maxAgeDays == 0 || user.Password == "" {
+		return false
+	}
+
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	return time.Since(changedAt) > time.Duration(maxAgeDays)*24*time.Hour
+}
+
+// isDenylisted checks the password against the embedded common password list and the
+// optional PASSWORD_DENYLIST_FILE (one password per line, e.g. a breached password dump)
+func isDenylisted(password string) bool {
+	passwordDenylist.once.Do(func() {
+		passwordDenylist.passwords = map[string]bool{}
+		addDenylistEntries(bufio.NewScanner(strings.NewReader(commonPasswords)))
+
+		if path := os.Getenv("PASSWORD_DENYLIST_FILE"); path != "" {
+			file, err := os.Open(path)
+			if err != nil {
+				log.Printf("Failed to open password denylist %s: %v", path, err)
+				return
+			}
+			defer file.Close()
+			addDenylistEntries(bufio.NewScanner(file))
+		}
+	})
+
+	return passwordDenylist.passwords[strings.ToLower(password)]
+}
+
+func addDenylistEntries(scanner *bufio.Scanner) {
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		passwordDenylist.passwords[strings.ToLower(line)] = true
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("Failed to read password denylist: %v", err)
+	}
+}
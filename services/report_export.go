@@ -0,0 +1,524 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Report export formats. Anything other than csv and pdf is exported as an Excel
+// workbook, which the export endpoints call excel and report schedules call xlsx.
+const (
+	ReportFormatExcel = "excel"
+	ReportFormatXLSX  = "xlsx"
+	ReportFormatCSV   = "csv"
+	ReportFormatPDF   = "pdf"
+)
+
+// ReportExportInfo describes an export for the formats that print it, currently PDF
+type ReportExportInfo struct {
+	Department  string // All departments when empty
+	Period      string
+	GeneratedBy string // Username or schedule that asked for the export
+	GeneratedAt time.Time
+}
+
+// ReportFileType returns the content type and file extension of an export format
+func ReportFileType(format string) (string, string) {
+	switch format {
+	case ReportFormatCSV:
+		return "text/csv", "csv"
+	case ReportFormatPDF:
+		return "application/pdf", "pdf"
+	}
+	return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "xlsx"
+}
+
+// AttendanceReportRows produces the rows of an attendance report, handing them to
+// write a batch at a time, such as a ReportService.StreamAttendanceReport call
+type AttendanceReportRows func(write func([]AttendanceReport) error) error
+
+// WriteAttendanceReport writes the attendance report to w in the given format as its
+// rows are produced, so the report is never held in memory as a whole
+func WriteAttendanceReport(w io.Writer, format string, rows AttendanceReportRows, info ReportExportInfo) error {
+	switch format {
+	case ReportFormatCSV:
+		return writeAttendanceCSV(w, rows)
+	case ReportFormatPDF:
+		return writeAttendancePDF(w, rows, info)
+	}
+	return writeAttendanceExcel(w, rows)
+}
+
+// WriteSummaryReport writes the summary report to w in the given format
+func WriteSummaryReport(w io.Writer, format string, summary *SummaryReport, info ReportExportInfo) error {
+	switch format {
+	case ReportFormatCSV:
+		return writeSummaryCSV(w, summary)
+	case ReportFormatPDF:
+		return writeSummaryPDF(w, summary, info)
+	}
+	return writeSummaryExcel(w, summary)
+}
+
+// WriteDepartmentReport writes the department report to w in the given format
+func WriteDepartmentReport(w io.Writer, format string, report map[string]interface{}, info ReportExportInfo) error {
+	// The department report is built from structs and maps, read it back as plain JSON
+	// values so the writers can walk it the same way whatever it was built from
+	encoded, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(encoded, &normalized); err != nil {
+		return err
+	}
+
+	switch format {
+	case ReportFormatCSV:
+		return writeDepartmentCSV(w, normalized)
+	case ReportFormatPDF:
+		return writeDepartmentPDF(w, normalized, info)
+	}
+	return writeDepartmentExcel(w, normalized)
+}
+
+// Excel Export Methods
+func writeAttendanceExcel(w io.Writer, rows AttendanceReportRows) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	// Create a new sheet
+	sheetName := "Attendance Report"
+	index, err := f.NewSheet(sheetName)
+	if err != nil {
+		return err
+	}
+
+	// Style headers
+	style, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true, Color: "FFFFFF"},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"2c5aa0"}, Pattern: 1},
+	})
+
+	// Rows are streamed, which spills them to a temporary file once they outgrow a
+	// memory buffer, and column widths have to be set before the first row
+	sw, err := f.NewStreamWriter(sheetName)
+	if err != nil {
+		return err
+	}
+	sw.SetColWidth(1, 1, 15)
+	sw.SetColWidth(2, 2, 25)
+	sw.SetColWidth(3, 3, 20)
+	sw.SetColWidth(4, 6, 18)
+	sw.SetColWidth(7, 10, 15)
+
+	// Set headers
+	headers := []string{"Employee ID", "Employee Name", "Department", "Date", "Clock In", "Clock Out", "Work Hours", "Status", "Late Minutes", "Early Minutes"}
+	headerCells := make([]interface{}, len(headers))
+	for i, header := range headers {
+		headerCells[i] = excelize.Cell{StyleID: style, Value: header}
+	}
+	if err := sw.SetRow("A1", headerCells); err != nil {
+		return err
+	}
+
+	// Set data
+	row := 2
+	err = rows(func(reports []AttendanceReport) error {
+		for _, report := range reports {
+			clockOut := "Not Clocked Out"
+			if !report.ClockOut.IsZero() {
+				clockOut = report.ClockOut.Format("2006-01-02 15:04:05")
+			}
+
+			cell, _ := excelize.CoordinatesToCellName(1, row)
+			err := sw.SetRow(cell, []interface{}{
+				report.EmployeeID,
+				report.EmployeeName,
+				report.Department,
+				report.Date,
+				report.ClockIn.Format("2006-01-02 15:04:05"),
+				clockOut,
+				fmt.Sprintf("%.2f", report.WorkHours),
+				report.Status,
+				report.LateMinutes,
+				report.EarlyMinutes,
+			})
+			if err != nil {
+				return err
+			}
+			row++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := sw.Flush(); err != nil {
+		return err
+	}
+
+	// Set active sheet
+	f.SetActiveSheet(index)
+
+	return f.Write(w)
+}
+
+func writeSummaryExcel(w io.Writer, summary *SummaryReport) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheetName := "Summary Report"
+	index, err := f.NewSheet(sheetName)
+	if err != nil {
+		return err
+	}
+
+	// Set summary data
+	f.SetCellValue(sheetName, "A1", "Summary Report")
+	f.SetCellValue(sheetName, "A2", "Period")
+	f.SetCellValue(sheetName, "B2", summary.Period)
+
+	f.SetCellValue(sheetName, "A3", "Total Employees")
+	f.SetCellValue(sheetName, "B3", summary.TotalEmployees)
+
+	f.SetCellValue(sheetName, "A4", "Total Present")
+	f.SetCellValue(sheetName, "B4", summary.TotalPresent)
+
+	f.SetCellValue(sheetName, "A5", "Total Late")
+	f.SetCellValue(sheetName, "B5", summary.TotalLate)
+
+	f.SetCellValue(sheetName, "A6", "Total Absent")
+	f.SetCellValue(sheetName, "B6", summary.TotalAbsent)
+
+	f.SetCellValue(sheetName, "A7", "Total Work Hours")
+	f.SetCellValue(sheetName, "B7", summary.TotalWorkHours)
+
+	f.SetCellValue(sheetName, "A8", "Average Work Hours")
+	f.SetCellValue(sheetName, "B8", summary.AverageWorkHours)
+
+	// Apply styling
+	f.SetColWidth(sheetName, "A", "A", 20)
+	f.SetColWidth(sheetName, "B", "B", 25)
+
+	// Style headers
+	headerStyle, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true, Size: 16},
+	})
+	f.SetCellStyle(sheetName, "A1", "B1", headerStyle)
+
+	labelStyle, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+	})
+	f.SetCellStyle(sheetName, "A2", "A8", labelStyle)
+
+	f.SetActiveSheet(index)
+
+	return f.Write(w)
+}
+
+func writeDepartmentExcel(w io.Writer, report map[string]interface{}) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheetName := "Department Report"
+	index, err := f.NewSheet(sheetName)
+	if err != nil {
+		return err
+	}
+
+	// Safely extract department report data
+	deptReportInterface, exists := report["department_report"]
+	if !exists {
+		return fmt.Errorf("department_report not found in response")
+	}
+
+	deptReportMap, ok := deptReportInterface.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected type for department_report: %T", deptReportInterface)
+	}
+
+	departmentName, _ := deptReportMap["department_name"].(string)
+	totalEmployees, _ := deptReportMap["total_employees"].(float64) // JSON numbers are float64
+	period, _ := report["period"].(string)
+
+	// Set department header
+	f.SetCellValue(sheetName, "A1", "Department Report")
+	f.SetCellValue(sheetName, "A2", "Department")
+	f.SetCellValue(sheetName, "B2", departmentName)
+	f.SetCellValue(sheetName, "A3", "Period")
+	f.SetCellValue(sheetName, "B3", period)
+	f.SetCellValue(sheetName, "A4", "Total Employees")
+	f.SetCellValue(sheetName, "B4", int(totalEmployees))
+
+	// Set summary data
+	summaryInterface, exists := deptReportMap["summary"]
+	if !exists {
+		return fmt.Errorf("summary not found in department_report")
+	}
+
+	summary, ok := summaryInterface.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected type for summary: %T", summaryInterface)
+	}
+
+	f.SetCellValue(sheetName, "A6", "Summary Statistics")
+	f.SetCellValue(sheetName, "A7", "Total Present")
+	f.SetCellValue(sheetName, "B7", summary["total_present"])
+	f.SetCellValue(sheetName, "A8", "Total Late")
+	f.SetCellValue(sheetName, "B8", summary["total_late"])
+	f.SetCellValue(sheetName, "A9", "Total Absent")
+	f.SetCellValue(sheetName, "B9", summary["total_absent"])
+	f.SetCellValue(sheetName, "A10", "Attendance Rate")
+	f.SetCellValue(sheetName, "B10", fmt.Sprintf("%.2f%%", summary["attendance_rate"]))
+	f.SetCellValue(sheetName, "A11", "Average Work Hours")
+	f.SetCellValue(sheetName, "B11", fmt.Sprintf("%.2f", summary["average_work_hours"]))
+
+	// Employee statistics
+	f.SetCellValue(sheetName, "A13", "Employee Statistics")
+	employeeStatsInterface, exists := deptReportMap["employee_stats"]
+	if !exists {
+		return fmt.Errorf("employee_stats not found in department_report")
+	}
+
+	employeeStats, ok := employeeStatsInterface.([]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected type for employee_stats: %T", employeeStatsInterface)
+	}
+
+	headers := []string{"Employee ID", "Employee Name", "Present Days", "Late Days", "Absent Days", "Avg Work Hours"}
+	for i, header := range headers {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 14)
+		f.SetCellValue(sheetName, cell, header)
+	}
+
+	for i, empStatInterface := range employeeStats {
+		empStat, ok := empStatInterface.(map[string]interface{})
+		if !ok {
+			continue // Skip invalid entries
+		}
+
+		statsInterface, exists := empStat["stats"]
+		if !exists {
+			continue
+		}
+
+		stats, ok := statsInterface.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		row := i + 15
+		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), empStat["employee_id"])
+		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), empStat["employee_name"])
+		f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), stats["total_present"])
+		f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), stats["total_late"])
+		f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), stats["total_absent"])
+		f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), stats["avg_work_hours"])
+	}
+
+	// Apply styling
+	f.SetColWidth(sheetName, "A", "A", 20)
+	f.SetColWidth(sheetName, "B", "B", 25)
+	f.SetColWidth(sheetName, "C", "F", 15)
+
+	headerStyle, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true, Size: 16},
+	})
+	f.SetCellStyle(sheetName, "A1", "A1", headerStyle)
+
+	sectionStyle, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true, Size: 14},
+	})
+	f.SetCellStyle(sheetName, "A6", "A6", sectionStyle)
+	f.SetCellStyle(sheetName, "A13", "A13", sectionStyle)
+
+	tableHeaderStyle, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true, Color: "FFFFFF"},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"2c5aa0"}, Pattern: 1},
+	})
+	f.SetCellStyle(sheetName, "A14", "F14", tableHeaderStyle)
+
+	f.SetActiveSheet(index)
+
+	return f.Write(w)
+}
+
+// CSV Export Methods
+func writeAttendanceCSV(w io.Writer, rows AttendanceReportRows) error {
+	writer := csv.NewWriter(w)
+
+	// Write headers
+	headers := []string{"Employee ID", "Employee Name", "Department", "Date", "Clock In", "Clock Out", "Work Hours", "Status", "Late Minutes", "Early Minutes"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	// Write data, flushing each batch through to w
+	err := rows(func(reports []AttendanceReport) error {
+		for _, report := range reports {
+			clockOut := ""
+			if !report.ClockOut.IsZero() {
+				clockOut = report.ClockOut.Format("2006-01-02 15:04:05")
+			} else {
+				clockOut = "Not Clocked Out"
+			}
+
+			record := []string{
+				report.EmployeeID,
+				report.EmployeeName,
+				report.Department,
+				report.Date,
+				report.ClockIn.Format("2006-01-02 15:04:05"),
+				clockOut,
+				fmt.Sprintf("%.2f", report.WorkHours),
+				report.Status,
+				strconv.Itoa(report.LateMinutes),
+				strconv.Itoa(report.EarlyMinutes),
+			}
+
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+
+		writer.Flush()
+		return writer.Error()
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeSummaryCSV(w io.Writer, summary *SummaryReport) error {
+	writer := csv.NewWriter(w)
+
+	// Write headers
+	if err := writer.Write([]string{"Metric", "Value"}); err != nil {
+		return err
+	}
+
+	// Write data
+	records := [][]string{
+		{"Period", summary.Period},
+		{"Total Employees", strconv.FormatInt(summary.TotalEmployees, 10)},
+		{"Total Present", strconv.FormatInt(summary.TotalPresent, 10)},
+		{"Total Late", strconv.FormatInt(summary.TotalLate, 10)},
+		{"Total Absent", strconv.FormatInt(summary.TotalAbsent, 10)},
+		{"Total Work Hours", summary.TotalWorkHours},
+		{"Average Work Hours", summary.AverageWorkHours},
+	}
+
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeDepartmentCSV(w io.Writer, report map[string]interface{}) error {
+	writer := csv.NewWriter(w)
+
+	// Safely extract department report data
+	deptReportInterface, exists := report["department_report"]
+	if !exists {
+		return fmt.Errorf("department_report not found in response")
+	}
+
+	deptReport, ok := deptReportInterface.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected type for department_report: %T", deptReportInterface)
+	}
+
+	summaryInterface, exists := deptReport["summary"]
+	if !exists {
+		return fmt.Errorf("summary not found in department_report")
+	}
+
+	summary, ok := summaryInterface.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected type for summary: %T", summaryInterface)
+	}
+
+	employeeStatsInterface, exists := deptReport["employee_stats"]
+	if !exists {
+		return fmt.Errorf("employee_stats not found in department_report")
+	}
+
+	employeeStats, ok := employeeStatsInterface.([]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected type for employee_stats: %T", employeeStatsInterface)
+	}
+
+	departmentName, _ := deptReport["department_name"].(string)
+	period, _ := report["period"].(string)
+
+	// Write department header
+	if err := writer.Write([]string{"Department Report"}); err != nil {
+		return err
+	}
+	writer.Write([]string{"Department", departmentName})
+	writer.Write([]string{"Period", period})
+	writer.Write([]string{"Total Employees", fmt.Sprintf("%.0f", deptReport["total_employees"])})
+	writer.Write([]string{}) // Empty line
+
+	// Write summary
+	writer.Write([]string{"Summary Statistics"})
+	writer.Write([]string{"Total Present", fmt.Sprintf("%.0f", summary["total_present"])})
+	writer.Write([]string{"Total Late", fmt.Sprintf("%.0f", summary["total_late"])})
+	writer.Write([]string{"Total Absent", fmt.Sprintf("%.0f", summary["total_absent"])})
+	writer.Write([]string{"Attendance Rate", fmt.Sprintf("%.2f%%", summary["attendance_rate"])})
+	writer.Write([]string{"Average Work Hours", fmt.Sprintf("%.2f", summary["average_work_hours"])})
+	writer.Write([]string{}) // Empty line
+
+	// Write employee statistics header
+	writer.Write([]string{"Employee Statistics"})
+	writer.Write([]string{"Employee ID", "Employee Name", "Present Days", "Late Days", "Absent Days", "Avg Work Hours"})
+
+	// Write employee data
+	for _, empStatInterface := range employeeStats {
+		empStat, ok := empStatInterface.(map[string]interface{})
+		if !ok {
+			continue // Skip invalid entries
+		}
+
+		statsInterface, exists := empStat["stats"]
+		if !exists {
+			continue
+		}
+
+		stats, ok := statsInterface.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		employeeID, _ := empStat["employee_id"].(string)
+		employeeName, _ := empStat["employee_name"].(string)
+		record := []string{
+			employeeID,
+			employeeName,
+			fmt.Sprintf("%.0f", stats["total_present"]),
+			fmt.Sprintf("%.0f", stats["total_late"]),
+			fmt.Sprintf("%.0f", stats["total_absent"]),
+			fmt.Sprintf("%.2f", stats["avg_work_hours"]),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
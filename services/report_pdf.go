@@ -0,0 +1,572 @@
+package services
+
+import (
+	"attendance-system/config"
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layout of PDF reports, in points
+const (
+	pdfMargin       = 36.0
+	pdfFontSize     = 8.0
+	pdfRowHeight    = 14.0
+	pdfCellPadding  = 4.0
+	pdfFooterHeight = 28.0
+)
+
+// Glyph widths of the standard Helvetica fonts for the printable ASCII characters, in
+// thousandths of the font size. Other characters are measured as a digit.
+var (
+	pdfHelveticaWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	pdfHelveticaBoldWidths = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// pdfWinAnsi maps the characters outside Latin-1 that WinAnsiEncoding has a code for
+var pdfWinAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94,
+	'•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+// pdfColumn is a column of a PDF table
+type pdfColumn struct {
+	Title string
+	Width float64
+	Right bool // Right-aligned, for numbers
+}
+
+// pdfReport lays out a report on A4 pages using the standard Helvetica fonts, so it
+// needs no font files or external tools. Every page has a header with the company,
+// title, department and period and a footer with who generated the report and when.
+// Each page is written out as soon as it is full, so a report of any length only ever
+// holds one page in memory.
+type pdfReport struct {
+	title   string
+	company string
+	info    ReportExportInfo
+	width   float64
+	height  float64
+
+	out     *bufio.Writer
+	offset  int   // Bytes written so far
+	offsets []int // Of each object, by object number less one
+	pageIDs []int // Object numbers of the pages written so far
+	err     error // First write error, after which nothing more is written
+	page    *bytes.Buffer
+	y       float64 // Distance from the top of the page to the next line
+
+	columns  []pdfColumn // Of the table being written
+	rowCount int
+}
+
+// Objects written around the pages. The page tree and the page count are only known
+// once the last page is written, so they come last.
+const (
+	pdfCatalogID = iota + 1
+	pdfPagesID
+	pdfFontID
+	pdfBoldFontID
+	pdfInfoID
+	pdfPageCountID // Form drawing the page count, which every footer refers to
+)
+
+func newPDFReport(w io.Writer, title string, info ReportExportInfo, landscape bool) *pdfReport {
+	if info.GeneratedAt.IsZero() {
+		info.GeneratedAt = time.Now()
+	}
+	r := &pdfReport{
+		title:   title,
+		company: config.GetConfig().EmailCompanyName,
+		info:    info,
+		width:   595.28,
+		height:  841.89,
+		out:     bufio.NewWriter(w),
+		offsets: make([]int, pdfPageCountID),
+	}
+	if landscape {
+		r.width, r.height = r.height, r.width
+	}
+
+	r.emit("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+	r.object(pdfCatalogID, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPagesID))
+	r.object(pdfFontID, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	r.object(pdfBoldFontID, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	r.object(pdfInfoID, fmt.Sprintf("<< /Title (%s) /Author (%s) /Producer (%s) /CreationDate (D:%s) >>",
+		pdfEncode(r.title), pdfEncode(r.company), pdfEncode(r.company), r.info.GeneratedAt.UTC().Format("20060102150405Z")))
+
+	r.addPage()
+	return r
+}
+
+// addPage writes out the current page, if any, and starts a new one with the report header
+func (r *pdfReport) addPage() {
+	if r.page != nil {
+		r.finishPage()
+	}
+	r.page = &bytes.Buffer{}
+	r.y = pdfMargin
+
+	r.text(pdfMargin, r.y+12, 14, true, r.company)
+	r.text(pdfMargin, r.y+28, 11, true, r.title)
+
+	department := r.info.Department
+	if department == "" {
+		department = "All departments"
+	}
+	r.text(pdfMargin, r.y+42, 9, false, "Department: "+department)
+	r.text(pdfMargin, r.y+54, 9, false, "Period: "+r.info.Period)
+
+	r.y += 62
+	r.line(pdfMargin, r.y, r.width-pdfMargin, r.y, 0.75)
+	r.y += 12
+}
+
+// finishPage adds the footer to the current page and writes it out
+func (r *pdfReport) finishPage() {
+	generated := "Generated at " + r.info.GeneratedAt.Format("2006-01-02 15:04 MST")
+	if r.info.GeneratedBy != "" {
+		generated = "Generated by " + r.info.GeneratedBy + " at " + r.info.GeneratedAt.Format("2006-01-02 15:04 MST")
+	}
+	footerY := r.height - pdfMargin - pdfFooterHeight + 10
+	r.line(pdfMargin, footerY, r.width-pdfMargin, footerY, 0.5)
+	r.text(pdfMargin, footerY+12, 7, false, generated)
+
+	// The page count isn't known yet, so it is drawn by a form written with the last page
+	pageNumber := fmt.Sprintf("Page %d of ", len(r.pageIDs)+1)
+	x := r.width - pdfMargin - pdfTextWidth("Page 0000 of 0000", 7, false)
+	r.text(x, footerY+12, 7, false, pageNumber)
+	fmt.Fprintf(r.page, "q 1 0 0 1 %.2f %.2f cm /PageCount Do Q\n", x+pdfTextWidth(pageNumber, 7, false), r.height-footerY-12)
+
+	pageID, contentID := r.newObject(), r.newObject()
+	r.pageIDs = append(r.pageIDs, pageID)
+	r.object(pageID, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] "+
+		"/Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> /XObject << /PageCount %d 0 R >> >> /Contents %d 0 R >>",
+		pdfPagesID, r.width, r.height, pdfFontID, pdfBoldFontID, pdfPageCountID, contentID))
+	r.stream(contentID, "", r.page.Bytes())
+	r.page = nil
+}
+
+// close writes out the last page, the page tree and the cross-reference table
+func (r *pdfReport) close() error {
+	r.finishPage()
+
+	kids := make([]string, len(r.pageIDs))
+	for i, id := range r.pageIDs {
+		kids[i] = fmt.Sprintf("%d 0 R", id)
+	}
+	r.object(pdfPagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(r.pageIDs)))
+	r.stream(pdfPageCountID, fmt.Sprintf("/Type /XObject /Subtype /Form /BBox [0 -5 100 20] /Resources << /Font << /F1 %d 0 R >> >> ", pdfFontID),
+		[]byte(fmt.Sprintf("BT /F1 7.0 Tf 0 0 Td (%d) Tj ET\n", len(r.pageIDs))))
+
+	xref := r.offset
+	r.emit("xref\n0 %d\n0000000000 65535 f \n", len(r.offsets)+1)
+	for _, offset := range r.offsets {
+		r.emit("%010d 00000 n \n", offset)
+	}
+	r.emit("trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(r.offsets)+1, pdfCatalogID, pdfInfoID, xref)
+
+	if r.err != nil {
+		return r.err
+	}
+	return r.out.Flush()
+}
+
+// newObject reserves the next object number
+func (r *pdfReport) newObject() int {
+	r.offsets = append(r.offsets, 0)
+	return len(r.offsets)
+}
+
+func (r *pdfReport) object(id int, body string) {
+	r.offsets[id-1] = r.offset
+	r.emit("%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+// stream writes a compressed stream object, with any entries its dictionary needs
+// besides the length and filter
+func (r *pdfReport) stream(id int, entries string, content []byte) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(content)
+	zw.Close()
+
+	r.offsets[id-1] = r.offset
+	r.emit("%d 0 obj\n<< %s/Length %d /Filter /FlateDecode >>\nstream\n", id, entries, compressed.Len())
+	r.emit("%s\nendstream\nendobj\n", compressed.Bytes())
+}
+
+func (r *pdfReport) emit(format string, args ...interface{}) {
+	if r.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(r.out, format, args...)
+	r.offset += n
+	r.err = err
+}
+
+// ensureSpace starts a new page unless the height fits above the footer, repeating the
+// header row of a table being written
+func (r *pdfReport) ensureSpace(height float64) {
+	if r.y+height <= r.height-pdfMargin-pdfFooterHeight {
+		return
+	}
+	r.addPage()
+	if r.columns != nil {
+		r.tableHeader()
+	}
+}
+
+// heading writes a section title
+func (r *pdfReport) heading(title string) {
+	r.ensureSpace(20 + pdfRowHeight*2)
+	r.text(pdfMargin, r.y+11, 10, true, title)
+	r.y += 18
+}
+
+// keyValues writes label and value pairs as a two-column table
+func (r *pdfReport) keyValues(rows [][2]string) {
+	for _, row := range rows {
+		r.ensureSpace(pdfRowHeight)
+		r.text(pdfMargin, r.y+10, pdfFontSize+1, true, row[0])
+		r.text(pdfMargin+160, r.y+10, pdfFontSize+1, false, row[1])
+		r.y += pdfRowHeight
+	}
+	r.y += 8
+}
+
+// table writes a whole table, see startTable
+func (r *pdfReport) table(columns []pdfColumn, rows [][]string, totals []string) {
+	r.startTable(columns)
+	for _, row := range rows {
+		r.addRow(row)
+	}
+	r.endTable(totals)
+}
+
+// startTable writes the header row of a table whose rows follow with addRow. The header
+// row is repeated on every page the table runs onto, and cells too wide for their
+// column are shortened.
+func (r *pdfReport) startTable(columns []pdfColumn) {
+	r.ensureSpace(pdfRowHeight * 2)
+	r.columns = columns
+	r.rowCount = 0
+	r.tableHeader()
+}
+
+func (r *pdfReport) addRow(cells []string) {
+	r.ensureSpace(pdfRowHeight)
+	if r.rowCount%2 == 1 {
+		r.fill(pdfMargin, r.y, r.tableWidth(), pdfRowHeight, 0.96)
+	}
+	r.tableRow(cells, false)
+	r.rowCount++
+}
+
+// endTable finishes the table with a bold totals row when totals are given
+func (r *pdfReport) endTable(totals []string) {
+	if r.rowCount == 0 {
+		r.ensureSpace(pdfRowHeight)
+		r.text(pdfMargin+pdfCellPadding, r.y+10, pdfFontSize, false, "No records")
+		r.y += pdfRowHeight
+	}
+
+	if totals != nil {
+		r.ensureSpace(pdfRowHeight)
+		r.line(pdfMargin, r.y, pdfMargin+r.tableWidth(), r.y, 0.75)
+		r.tableRow(totals, true)
+	}
+	r.columns = nil
+	r.y += 8
+}
+
+func (r *pdfReport) tableHeader() {
+	r.fill(pdfMargin, r.y, r.tableWidth(), pdfRowHeight, 0.85)
+	titles := make([]string, len(r.columns))
+	for i, column := range r.columns {
+		titles[i] = column.Title
+	}
+	r.tableRow(titles, true)
+}
+
+func (r *pdfReport) tableRow(cells []string, bold bool) {
+	x := pdfMargin
+	for i, column := range r.columns {
+		if i < len(cells) {
+			text := pdfFit(cells[i], column.Width-2*pdfCellPadding, pdfFontSize, bold)
+			textX := x + pdfCellPadding
+			if column.Right {
+				textX = x + column.Width - pdfCellPadding - pdfTextWidth(text, pdfFontSize, bold)
+			}
+			r.text(textX, r.y+10, pdfFontSize, bold, text)
+		}
+		x += column.Width
+	}
+	r.y += pdfRowHeight
+}
+
+func (r *pdfReport) tableWidth() float64 {
+	width := 0.0
+	for _, column := range r.columns {
+		width += column.Width
+	}
+	return width
+}
+
+// signOff leaves room for the report to be signed off on paper
+func (r *pdfReport) signOff() {
+	r.ensureSpace(60)
+	r.y += 24
+	for i, label := range []string{"Prepared by", "Approved by"} {
+		x := pdfMargin + float64(i)*220
+		r.line(x, r.y+20, x+180, r.y+20, 0.5)
+		r.text(x, r.y+30, pdfFontSize, false, label+" (name, signature, date)")
+	}
+	r.y += 36
+}
+
+// text draws a line of text with its baseline y points from the top of the page
+func (r *pdfReport) text(x, y, size float64, bold bool, text string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(r.page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, r.height-y, pdfEncode(text))
+}
+
+// fill draws a grey rectangle whose top edge is y points from the top of the page
+func (r *pdfReport) fill(x, y, width, height, gray float64) {
+	fmt.Fprintf(r.page, "%.2f g %.2f %.2f %.2f %.2f re f 0 g\n", gray, x, r.height-y-height, width, height)
+}
+
+func (r *pdfReport) line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(r.page, "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, r.height-y1, x2, r.height-y2)
+}
+
+// pdfTextWidth measures text set in Helvetica at the given size
+func pdfTextWidth(text string, size float64, bold bool) float64 {
+	widths := &pdfHelveticaWidths
+	if bold {
+		widths = &pdfHelveticaBoldWidths
+	}
+	total := 0
+	for _, c := range text {
+		if c >= 32 && c <= 126 {
+			total += widths[c-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// pdfFit shortens text with an ellipsis until it fits the width
+func pdfFit(text string, width, size float64, bold bool) string {
+	if pdfTextWidth(text, size, bold) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && pdfTextWidth(string(runes)+"...", size, bold) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+// pdfEncode converts text to a WinAnsi string literal body, escaping the characters
+// PDF strings reserve and replacing those the standard fonts can't show
+func pdfEncode(text string) string {
+	var buf strings.Builder
+	for _, c := range text {
+		var b byte
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			buf.WriteByte('\\')
+			b = byte(c)
+		case c >= 32 && c <= 126, c >= 0xA0 && c <= 0xFF:
+			b = byte(c)
+		case pdfWinAnsi[c] != 0:
+			b = pdfWinAnsi[c]
+		default:
+			b = '?'
+		}
+		buf.WriteByte(b)
+	}
+	return buf.String()
+}
+
+// PDF Export Methods
+func writeAttendancePDF(w io.Writer, rows AttendanceReportRows, info ReportExportInfo) error {
+	pdf := newPDFReport(w, "Attendance Report", info, true)
+
+	pdf.startTable([]pdfColumn{
+		{Title: "Employee ID", Width: 70},
+		{Title: "Employee Name", Width: 125},
+		{Title: "Department", Width: 110},
+		{Title: "Date", Width: 60},
+		{Title: "Clock In", Width: 90},
+		{Title: "Clock Out", Width: 90},
+		{Title: "Work Hours", Width: 55, Right: true},
+		{Title: "Status", Width: 65},
+		{Title: "Late Min", Width: 50, Right: true},
+		{Title: "Early Min", Width: 50, Right: true},
+	})
+
+	var records, lateMinutes, earlyMinutes int
+	var workHours float64
+	err := rows(func(reports []AttendanceReport) error {
+		for _, report := range reports {
+			clockOut := "Not Clocked Out"
+			if !report.ClockOut.IsZero() {
+				clockOut = report.ClockOut.Format("2006-01-02 15:04:05")
+			}
+			pdf.addRow([]string{
+				report.EmployeeID,
+				report.EmployeeName,
+				report.Department,
+				report.Date,
+				report.ClockIn.Format("2006-01-02 15:04:05"),
+				clockOut,
+				fmt.Sprintf("%.2f", report.WorkHours),
+				report.Status,
+				strconv.Itoa(report.LateMinutes),
+				strconv.Itoa(report.EarlyMinutes),
+			})
+			records++
+			workHours += report.WorkHours
+			lateMinutes += report.LateMinutes
+			earlyMinutes += report.EarlyMinutes
+		}
+		return pdf.err
+	})
+	if err != nil {
+		return err
+	}
+
+	pdf.endTable([]string{
+		"Total", fmt.Sprintf("%d records", records), "", "", "", "",
+		fmt.Sprintf("%.2f", workHours), "", strconv.Itoa(lateMinutes), strconv.Itoa(earlyMinutes),
+	})
+	pdf.signOff()
+
+	return pdf.close()
+}
+
+func writeSummaryPDF(w io.Writer, summary *SummaryReport, info ReportExportInfo) error {
+	if info.Period == "" {
+		info.Period = summary.Period
+	}
+	pdf := newPDFReport(w, "Summary Report", info, false)
+
+	pdf.heading("Summary Statistics")
+	pdf.table([]pdfColumn{
+		{Title: "Metric", Width: 300},
+		{Title: "Value", Width: 223, Right: true},
+	}, [][]string{
+		{"Total Employees", strconv.FormatInt(summary.TotalEmployees, 10)},
+		{"Total Present", strconv.FormatInt(summary.TotalPresent, 10)},
+		{"Total Late", strconv.FormatInt(summary.TotalLate, 10)},
+		{"Total Absent", strconv.FormatInt(summary.TotalAbsent, 10)},
+		{"Average Work Hours", summary.AverageWorkHours},
+	}, []string{"Total Work Hours", summary.TotalWorkHours})
+	pdf.signOff()
+
+	return pdf.close()
+}
+
+func writeDepartmentPDF(w io.Writer, report map[string]interface{}, info ReportExportInfo) error {
+	deptReport, ok := report["department_report"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("department_report not found in response")
+	}
+	summary, ok := deptReport["summary"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("summary not found in department_report")
+	}
+	employeeStats, ok := deptReport["employee_stats"].([]interface{})
+	if !ok {
+		return fmt.Errorf("employee_stats not found in department_report")
+	}
+
+	if info.Department == "" {
+		info.Department, _ = deptReport["department_name"].(string)
+	}
+	if info.Period == "" {
+		info.Period, _ = report["period"].(string)
+	}
+	pdf := newPDFReport(w, "Department Report", info, false)
+
+	pdf.heading("Summary Statistics")
+	pdf.keyValues([][2]string{
+		{"Total Employees", fmt.Sprintf("%.0f", deptReport["total_employees"])},
+		{"Total Present", fmt.Sprintf("%.0f", summary["total_present"])},
+		{"Total Late", fmt.Sprintf("%.0f", summary["total_late"])},
+		{"Total Absent", fmt.Sprintf("%.0f", summary["total_absent"])},
+		{"Attendance Rate", fmt.Sprintf("%.2f%%", summary["attendance_rate"])},
+		{"Average Work Hours", fmt.Sprintf("%.2f", summary["average_work_hours"])},
+	})
+
+	pdf.heading("Employee Statistics")
+	columns := []pdfColumn{
+		{Title: "Employee ID", Width: 80},
+		{Title: "Employee Name", Width: 163},
+		{Title: "Present Days", Width: 70, Right: true},
+		{Title: "Late Days", Width: 70, Right: true},
+		{Title: "Absent Days", Width: 70, Right: true},
+		{Title: "Avg Work Hours", Width: 70, Right: true},
+	}
+
+	rows := make([][]string, 0, len(employeeStats))
+	for _, empStatInterface := range employeeStats {
+		empStat, ok := empStatInterface.(map[string]interface{})
+		if !ok {
+			continue // Skip invalid entries
+		}
+		stats, ok := empStat["stats"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		employeeID, _ := empStat["employee_id"].(string)
+		employeeName, _ := empStat["employee_name"].(string)
+		rows = append(rows, []string{
+			employeeID,
+			employeeName,
+			fmt.Sprintf("%.0f", stats["total_present"]),
+			fmt.Sprintf("%.0f", stats["total_late"]),
+			fmt.Sprintf("%.0f", stats["total_absent"]),
+			fmt.Sprintf("%.2f", stats["avg_work_hours"]),
+		})
+	}
+
+	totals := []string{
+		"Total",
+		fmt.Sprintf("%d employees", len(rows)),
+		fmt.Sprintf("%.0f", summary["total_present"]),
+		fmt.Sprintf("%.0f", summary["total_late"]),
+		fmt.Sprintf("%.0f", summary["total_absent"]),
+		fmt.Sprintf("%.2f", summary["average_work_hours"]),
+	}
+	pdf.table(columns, rows, totals)
+	pdf.signOff()
+
+	return pdf.close()
+}
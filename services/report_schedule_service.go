@@ -0,0 +1,413 @@
+package services
+
+import (
+	"attendance-system/models"
+	"attendance-system/repositories"
+	"attendance-system/utils"
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// maxReportRunErrorLength fits the error column of the run history
+	maxReportRunErrorLength = 500
+	// maxReportRecipientsLength fits the recipients column
+	maxReportRecipientsLength = 1000
+)
+
+// ReportScheduleService manages saved report schedules and runs them in the background,
+// generating each report with the same export code as the download endpoints and emailing
+// it to the schedule's recipients as an attachment
+type ReportScheduleService struct {
+	scheduleRepo   *repositories.ReportScheduleRepository
+	departmentRepo *repositories.DepartmentRepository
+	reportService  *ReportService
+	emailService   *EmailService
+	auditService   *AuditService
+}
+
+func NewReportScheduleService() *ReportScheduleService {
+	return &ReportScheduleService{
+		scheduleRepo:   repositories.NewReportScheduleRepository(),
+		departmentRepo: repositories.NewDepartmentRepository(),
+		reportService:  NewReportService(),
+		emailService:   NewEmailService(),
+		auditService:   NewAuditService(),
+	}
+}
+
+func (s *ReportScheduleService) GetSchedules() ([]models.ReportSchedule, error) {
+	return s.scheduleRepo.FindAll()
+}
+
+func (s *ReportScheduleService) GetScheduleByID(id uint) (*models.ReportSchedule, error) {
+	schedule, err := s.scheduleRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("report schedule not found")
+		}
+		return nil, err
+	}
+	return schedule, nil
+}
+
+func (s *ReportScheduleService) CreateSchedule(req models.ReportScheduleRequest, actor models.AuditActor) (*models.ReportSchedule, error) {
+	schedule := &models.ReportSchedule{
+		IsActive:  req.IsActive == nil || *req.IsActive,
+		CreatedAt: time.Now(),
+	}
+	if err := s.apply(schedule, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.scheduleRepo.Create(schedule); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, "report_schedule.create", models.AuditEntityReportSchedule, auditID(schedule.ID), nil, auditSnapshot(schedule.ToResponse()))
+
+	return schedule, nil
+}
+
+// UpdateSchedule changes a schedule. Its next run is worked out again from the new
+// recurrence, so a run that was due under the old one is skipped.
+func (s *ReportScheduleService) UpdateSchedule(id uint, req models.ReportScheduleRequest, actor models.AuditActor) (*models.ReportSchedule, error) {
+	schedule, err := s.GetScheduleByID(id)
+	if err != nil {
+		return nil, err
+	}
+	before := auditSnapshot(schedule.ToResponse())
+
+	if req.IsActive != nil {
+		schedule.IsActive = *req.IsActive
+	}
+	if err := s.apply(schedule, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.scheduleRepo.Update(schedule); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, "report_schedule.update", models.AuditEntityReportSchedule, auditID(schedule.ID), before, auditSnapshot(schedule.ToResponse()))
+
+	return schedule, nil
+}
+
+func (s *ReportScheduleService) DeleteSchedule(id uint, actor models.AuditActor) error {
+	schedule, err := s.GetScheduleByID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.scheduleRepo.Delete(schedule.ID); err != nil {
+		return err
+	}
+	s.auditService.Record(actor, "report_schedule.delete", models.AuditEntityReportSchedule, auditID(schedule.ID), auditSnapshot(schedule.ToResponse()), nil)
+
+	return nil
+}
+
+// apply validates the request and copies it onto the schedule, working out its next run
+func (s *ReportScheduleService) apply(schedule *models.ReportSchedule, req models.ReportScheduleRequest) error {
+	known := false
+	for _, period := range models.ReportPeriods {
+		if req.Period == period {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return utils.NewBadRequestError("period must be one of " + strings.Join(models.ReportPeriods, ", "))
+	}
+
+	if req.ReportType == models.ReportTypeDepartment {
+		if req.DepartmentID == nil {
+			return utils.NewBadRequestError("department_id is required for department reports")
+		}
+		// Department reports cover a calendar month
+		if req.Period != models.ReportPeriodLastMonth && req.Period != models.ReportPeriodMonthToDate {
+			return utils.NewBadRequestError("department reports need the last_month or month_to_date period")
+		}
+	}
+	if req.DepartmentID != nil {
+		if _, err := s.departmentRepo.FindByID(*req.DepartmentID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewBadRequestError("department not found")
+			}
+			return err
+		}
+	}
+
+	cron, err := utils.ParseCron(req.Cron)
+	if err != nil {
+		return utils.NewBadRequestError("invalid cron expression: " + err.Error())
+	}
+	now := time.Now()
+	nextRunAt := cron.Next(now)
+	if nextRunAt.IsZero() {
+		return utils.NewBadRequestError("cron expression never matches")
+	}
+
+	seen := map[string]bool{}
+	recipients := []string{}
+	for _, recipient := range req.Recipients {
+		recipient = strings.ToLower(strings.TrimSpace(recipient))
+		if !seen[recipient] {
+			seen[recipient] = true
+			recipients = append(recipients, recipient)
+		}
+	}
+	joined := strings.Join(recipients, ",")
+	if len(joined) > maxReportRecipientsLength {
+		return utils.NewBadRequestError(fmt.Sprintf("recipients must fit in %d characters", maxReportRecipientsLength))
+	}
+
+	schedule.Name = strings.TrimSpace(req.Name)
+	schedule.ReportType = req.ReportType
+	schedule.DepartmentID = req.DepartmentID
+	schedule.IncludeSubDepartments = req.IncludeSubDepartments
+	schedule.Period = req.Period
+	schedule.Format = req.Format
+	schedule.Cron = strings.TrimSpace(req.Cron)
+	schedule.Recipients = joined
+	schedule.Locale = req.Locale
+	schedule.NextRunAt = nil
+	if schedule.IsActive {
+		schedule.NextRunAt = &nextRunAt
+	}
+	schedule.UpdatedAt = now
+
+	return nil
+}
+
+// GetRuns lists the schedule's run history, newest first
+func (s *ReportScheduleService) GetRuns(scheduleID uint, page, limit int) ([]models.ReportScheduleRun, *repositories.Pagination, error) {
+	if _, err := s.GetScheduleByID(scheduleID); err != nil {
+		return nil, nil, err
+	}
+	return s.scheduleRepo.FindRuns(scheduleID, page, limit)
+}
+
+// RunNow generates and sends the report right away, even when the schedule is inactive,
+// and returns the run. The schedule's next run is unchanged.
+func (s *ReportScheduleService) RunNow(id uint, actor models.AuditActor) (*models.ReportScheduleRun, error) {
+	schedule, err := s.GetScheduleByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	run, err := s.run(schedule, models.ReportRunTriggerManual, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, "report_schedule.run", models.AuditEntityReportSchedule, auditID(schedule.ID), nil, auditSnapshot(run))
+
+	return run, nil
+}
+
+// RunDue runs the schedules whose next run is due. A schedule that missed several runs,
+// say while the server was down, runs once and carries on from its next run after now.
+func (s *ReportScheduleService) RunDue(now time.Time) error {
+	schedules, err := s.scheduleRepo.FindDue(now)
+	if err != nil {
+		return err
+	}
+
+	for i := range schedules {
+		schedule := &schedules[i]
+
+		var nextRunAt *time.Time
+		if cron, err := utils.ParseCron(schedule.Cron); err == nil {
+			if next := cron.Next(now); !next.IsZero() {
+				nextRunAt = &next
+			}
+		}
+
+		claimed, err := s.scheduleRepo.ClaimRun(schedule.ID, *schedule.NextRunAt, nextRunAt)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue // Another server got there first
+		}
+		if _, err := s.run(schedule, models.ReportRunTriggerSchedule, now); err != nil {
+			log.Printf("Failed to record run of report schedule %d: %v", schedule.ID, err)
+		}
+	}
+	return nil
+}
+
+// run generates the report for the schedule's period as of now and queues it to every
+// recipient, recording the run in the history. A failed report is recorded rather than
+// returned; the error is only for failures to record the run.
+func (s *ReportScheduleService) run(schedule *models.ReportSchedule, trigger string, now time.Time) (*models.ReportScheduleRun, error) {
+	start, end := reportPeriodRange(schedule.Period, now)
+	run := &models.ReportScheduleRun{
+		ScheduleID:  schedule.ID,
+		TriggeredBy: trigger,
+		Status:      models.ReportRunRunning,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		StartedAt:   now,
+	}
+	if err := s.scheduleRepo.CreateRun(run); err != nil {
+		return nil, err
+	}
+
+	run.Status = models.ReportRunSucceeded
+	if err := s.deliver(schedule, run); err != nil {
+		log.Printf("Report schedule %d failed: %v", schedule.ID, err)
+		run.Status = models.ReportRunFailed
+		run.Error = err.Error()
+		if len(run.Error) > maxReportRunErrorLength {
+			run.Error = run.Error[:maxReportRunErrorLength]
+		}
+	}
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+
+	if err := s.scheduleRepo.UpdateRun(run); err != nil {
+		return nil, err
+	}
+	if err := s.scheduleRepo.RecordLastRun(schedule.ID, now, run.Status); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// deliver generates the report file and queues it to each recipient, counting the
+// emails queued on the run
+func (s *ReportScheduleService) deliver(schedule *models.ReportSchedule, run *models.ReportScheduleRun) error {
+	startDate := run.PeriodStart.Format("2006-01-02")
+	endDate := run.PeriodEnd.Format("2006-01-02")
+	var departmentID uint
+	if schedule.DepartmentID != nil {
+		departmentID = *schedule.DepartmentID
+	}
+
+	department, err := s.reportService.DepartmentLabel(departmentID, schedule.IncludeSubDepartments)
+	if err != nil {
+		return err
+	}
+	info := ReportExportInfo{
+		Department:  department,
+		Period:      fmt.Sprintf("%s to %s", startDate, endDate),
+		GeneratedBy: "Report schedule: " + schedule.Name,
+		GeneratedAt: time.Now(),
+	}
+
+	var buf bytes.Buffer
+	var filename string
+	switch schedule.ReportType {
+	case models.ReportTypeAttendance:
+		rows := func(write func([]AttendanceReport) error) error {
+			return s.reportService.StreamAttendanceReport(startDate, endDate, departmentID, schedule.IncludeSubDepartments, write)
+		}
+		filename = fmt.Sprintf("attendance-report-%s-to-%s", startDate, endDate)
+		if err := WriteAttendanceReport(&buf, schedule.Format, rows, info); err != nil {
+			return err
+		}
+	case models.ReportTypeSummary:
+		summary, err := s.reportService.GenerateSummaryReport(startDate, endDate, departmentID, schedule.IncludeSubDepartments)
+		if err != nil {
+			return err
+		}
+		filename = fmt.Sprintf("summary-report-%s-to-%s", startDate, endDate)
+		if err := WriteSummaryReport(&buf, schedule.Format, summary, info); err != nil {
+			return err
+		}
+	case models.ReportTypeDepartment:
+		month, year := int(run.PeriodStart.Month()), run.PeriodStart.Year()
+		report, err := s.reportService.GenerateDepartmentReport(departmentID, month, year, schedule.IncludeSubDepartments)
+		if err != nil {
+			return err
+		}
+		filename = fmt.Sprintf("department-report-%d-%d-%d", departmentID, month, year)
+		info.Period = fmt.Sprintf("%d-%02d", year, month)
+		if err := WriteDepartmentReport(&buf, schedule.Format, report, info); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown report type %q", schedule.ReportType)
+	}
+
+	contentType, extension := ReportFileType(schedule.Format)
+	attachment := EmailAttachment{
+		Filename:    filename + "." + extension,
+		ContentType: contentType,
+		Content:     buf.Bytes(),
+	}
+	run.Filename = attachment.Filename
+	run.FileSize = int64(buf.Len())
+
+	data := EmailTemplateData{
+		Report: ScheduledReport{
+			ScheduleName: schedule.Name,
+			PeriodStart:  startDate,
+			PeriodEnd:    endDate,
+			Filename:     attachment.Filename,
+		},
+	}
+	if schedule.DepartmentID != nil {
+		if department, err := s.departmentRepo.FindByID(*schedule.DepartmentID); err == nil {
+			data.Report.Department = department.Name
+		}
+	}
+
+	var failed []string
+	for _, recipient := range schedule.RecipientList() {
+		if err := s.emailService.SendEmailWithAttachment(models.EmailKindScheduledReport, recipient, schedule.Locale, data, attachment); err != nil {
+			log.Printf("Failed to queue report schedule %d to %s: %v", schedule.ID, recipient, err)
+			failed = append(failed, recipient)
+			continue
+		}
+		run.Recipients++
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to queue the report to %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// reportPeriodRange returns the first and last day of a relative period as of now
+func reportPeriodRange(period string, now time.Time) (time.Time, time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	yesterday := today.AddDate(0, 0, -1)
+
+	switch period {
+	case models.ReportPeriodLast7Days:
+		return today.AddDate(0, 0, -7), yesterday
+	case models.ReportPeriodLast30Days:
+		return today.AddDate(0, 0, -30), yesterday
+	case models.ReportPeriodLastWeek:
+		// Weeks start on Monday
+		monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+		return monday.AddDate(0, 0, -7), monday.AddDate(0, 0, -1)
+	case models.ReportPeriodLastMonth:
+		firstOfMonth := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+		return firstOfMonth.AddDate(0, -1, 0), firstOfMonth.AddDate(0, 0, -1)
+	case models.ReportPeriodMonthToDate:
+		return time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location()), today
+	default: // yesterday
+		return yesterday, yesterday
+	}
+}
+
+// StartReportScheduler runs due report schedules in the background, checking every interval
+func StartReportScheduler(interval time.Duration) {
+	scheduleService := NewReportScheduleService()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for now := range ticker.C {
+			if err := scheduleService.RunDue(now); err != nil {
+				log.Printf("Failed to run report schedules: %v", err)
+			}
+		}
+	}()
+}
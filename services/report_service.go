@@ -1,22 +1,26 @@
 package services
 
 import (
+	"attendance-system/models"
 	"attendance-system/repositories"
+	"attendance-system/utils"
 	"fmt"
 	"time"
 )
 
 type ReportService struct {
-	attendanceRepo *repositories.AttendanceRepository
-	employeeRepo   *repositories.EmployeeRepository
-	departmentRepo *repositories.DepartmentRepository
+	attendanceRepo    *repositories.AttendanceRepository
+	employeeRepo      *repositories.EmployeeRepository
+	departmentRepo    *repositories.DepartmentRepository
+	departmentService *DepartmentService
 }
 
 func NewReportService() *ReportService {
 	return &ReportService{
-		attendanceRepo: repositories.NewAttendanceRepository(),
-		employeeRepo:   repositories.NewEmployeeRepository(),
-		departmentRepo: repositories.NewDepartmentRepository(),
+		attendanceRepo:    repositories.NewAttendanceRepository(),
+		employeeRepo:      repositories.NewEmployeeRepository(),
+		departmentRepo:    repositories.NewDepartmentRepository(),
+		departmentService: NewDepartmentService(),
 	}
 }
 
@@ -43,91 +47,141 @@ type SummaryReport struct {
 	AverageWorkHours string `json:"average_work_hours"`
 }
 
-func (s *ReportService) GenerateAttendanceReport(startDate, endDate string, departmentID uint) ([]AttendanceReport, error) {
-	var reports []AttendanceReport
+// DepartmentRollup is a summary for a department that includes all of its sub-departments
+type DepartmentRollup struct {
+	DepartmentID     uint               `json:"department_id"`
+	DepartmentName   string             `json:"department_name"`
+	TotalEmployees   int64              `json:"total_employees"`
+	TotalPresent     int64              `json:"total_present"`
+	TotalLate        int64              `json:"total_late"`
+	TotalAbsent      int64              `json:"total_absent"`
+	TotalWorkHours   string             `json:"total_work_hours"`
+	AverageWorkHours string             `json:"average_work_hours"`
+	Children         []DepartmentRollup `json:"children"`
+
+	workHours float64
+}
+
+type HierarchySummaryReport struct {
+	Period   string           `json:"period"`
+	Division DepartmentRollup `json:"division"`
+}
 
-	// Get attendances for the period
-	attendances, _, err := s.attendanceRepo.GetAttendanceLogs(startDate, endDate, departmentID, "", 1, 10000)
+// attendanceReportBatchSize is how many attendance records a report reads at a time
+const attendanceReportBatchSize = 500
+
+// GenerateAttendanceReport returns one page of the attendance report, newest first
+func (s *ReportService) GenerateAttendanceReport(startDate, endDate string, departmentID uint, includeSubDepartments bool, page, limit int) ([]AttendanceReport, *repositories.Pagination, error) {
+	departmentIDs, err := s.departmentService.GetDepartmentScope(departmentID, includeSubDepartments)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
+	attendances, pagination, err := s.attendanceRepo.GetAttendanceLogs(startDate, endDate, departmentIDs, "", page, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reports := make([]AttendanceReport, 0, len(attendances))
 	for _, attendance := range attendances {
-		report := AttendanceReport{
-			EmployeeID:   attendance.EmployeeID,
-			EmployeeName: attendance.Employee.Name,
-			Department:   attendance.Employee.Department.Name,
-			Date:         attendance.ClockIn.Format("2006-01-02"),
-			ClockIn:      attendance.ClockIn,
-			Status:       attendance.Status,
-		}
+		reports = append(reports, newAttendanceReport(attendance))
+	}
+	return reports, pagination, nil
+}
 
-		if attendance.ClockOut != nil {
-			report.ClockOut = *attendance.ClockOut
-			if attendance.WorkHours != nil {
-				report.WorkHours = *attendance.WorkHours
-			}
+// StreamAttendanceReport hands every row of the attendance report to fn a batch at a
+// time, so exports can write a period of any length without holding it in memory
+func (s *ReportService) StreamAttendanceReport(startDate, endDate string, departmentID uint, includeSubDepartments bool, fn func([]AttendanceReport) error) error {
+	departmentIDs, err := s.departmentService.GetDepartmentScope(departmentID, includeSubDepartments)
+	if err != nil {
+		return err
+	}
+
+	return s.attendanceRepo.EachAttendanceLog(startDate, endDate, departmentIDs, attendanceReportBatchSize, func(attendances []models.Attendance) error {
+		reports := make([]AttendanceReport, 0, len(attendances))
+		for _, attendance := range attendances {
+			reports = append(reports, newAttendanceReport(attendance))
 		}
+		return fn(reports)
+	})
+}
 
-		// Calculate late minutes
-		maxClockIn, _ := time.Parse("15:04:05", attendance.Employee.Department.MaxClockIn)
-		clockInTime := time.Date(attendance.ClockIn.Year(), attendance.ClockIn.Month(), attendance.ClockIn.Day(),
-			attendance.ClockIn.Hour(), attendance.ClockIn.Minute(), attendance.ClockIn.Second(), 0, attendance.ClockIn.Location())
-		maxClockInTime := time.Date(attendance.ClockIn.Year(), attendance.ClockIn.Month(), attendance.ClockIn.Day(),
-			maxClockIn.Hour(), maxClockIn.Minute(), maxClockIn.Second(), 0, attendance.ClockIn.Location())
+// newAttendanceReport builds a report row from an attendance record, working out how
+// late and how early it was against the department's hours
+func newAttendanceReport(attendance models.Attendance) AttendanceReport {
+	report := AttendanceReport{
+		EmployeeID:   attendance.EmployeeID,
+		EmployeeName: attendance.Employee.Name,
+		Department:   attendance.Employee.Department.Name,
+		Date:         attendance.ClockIn.Format("2006-01-02"),
+		ClockIn:      attendance.ClockIn,
+		Status:       attendance.Status,
+	}
 
-		if clockInTime.After(maxClockInTime) {
-			report.LateMinutes = int(clockInTime.Sub(maxClockInTime).Minutes())
+	if attendance.ClockOut != nil {
+		report.ClockOut = *attendance.ClockOut
+		if attendance.WorkHours != nil {
+			report.WorkHours = *attendance.WorkHours
 		}
+	}
 
-		// Calculate early leave minutes
-		if attendance.ClockOut != nil {
-			maxClockOut, _ := time.Parse("15:04:05", attendance.Employee.Department.MaxClockOut)
-			clockOutTime := time.Date(attendance.ClockOut.Year(), attendance.ClockOut.Month(), attendance.ClockOut.Day(),
-				attendance.ClockOut.Hour(), attendance.ClockOut.Minute(), attendance.ClockOut.Second(), 0, attendance.ClockOut.Location())
-			maxClockOutTime := time.Date(attendance.ClockOut.Year(), attendance.ClockOut.Month(), attendance.ClockOut.Day(),
-				maxClockOut.Hour(), maxClockOut.Minute(), maxClockOut.Second(), 0, attendance.ClockOut.Location())
+	// Calculate late minutes
+	maxClockIn, _ := time.Parse("15:04:05", attendance.Employee.Department.MaxClockIn)
+	clockInTime := time.Date(attendance.ClockIn.Year(), attendance.ClockIn.Month(), attendance.ClockIn.Day(),
+		attendance.ClockIn.Hour(), attendance.ClockIn.Minute(), attendance.ClockIn.Second(), 0, attendance.ClockIn.Location())
+	maxClockInTime := time.Date(attendance.ClockIn.Year(), attendance.ClockIn.Month(), attendance.ClockIn.Day(),
+		maxClockIn.Hour(), maxClockIn.Minute(), maxClockIn.Second(), 0, attendance.ClockIn.Location())
 
-			if clockOutTime.Before(maxClockOutTime) {
-				report.EarlyMinutes = int(maxClockOutTime.Sub(clockOutTime).Minutes())
-			}
-		}
+	if clockInTime.After(maxClockInTime) {
+		report.LateMinutes = int(clockInTime.Sub(maxClockInTime).Minutes())
+	}
+
+	// Calculate early leave minutes
+	if attendance.ClockOut != nil {
+		maxClockOut, _ := time.Parse("15:04:05", attendance.Employee.Department.MaxClockOut)
+		clockOutTime := time.Date(attendance.ClockOut.Year(), attendance.ClockOut.Month(), attendance.ClockOut.Day(),
+			attendance.ClockOut.Hour(), attendance.ClockOut.Minute(), attendance.ClockOut.Second(), 0, attendance.ClockOut.Location())
+		maxClockOutTime := time.Date(attendance.ClockOut.Year(), attendance.ClockOut.Month(), attendance.ClockOut.Day(),
+			maxClockOut.Hour(), maxClockOut.Minute(), maxClockOut.Second(), 0, attendance.ClockOut.Location())
 
-		reports = append(reports, report)
+		if clockOutTime.Before(maxClockOutTime) {
+			report.EarlyMinutes = int(maxClockOutTime.Sub(clockOutTime).Minutes())
+		}
 	}
 
-	return reports, nil
+	return report
 }
 
-func (s *ReportService) GenerateSummaryReport(startDate, endDate string, departmentID uint) (*SummaryReport, error) {
+func (s *ReportService) GenerateSummaryReport(startDate, endDate string, departmentID uint, includeSubDepartments bool) (*SummaryReport, error) {
 	var summary SummaryReport
 	summary.Period = fmt.Sprintf("%s to %s", startDate, endDate)
 
-	// Get total employees
-	totalEmployees, err := s.employeeRepo.GetActiveEmployeesCount()
+	departmentIDs, err := s.departmentService.GetDepartmentScope(departmentID, includeSubDepartments)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get total employees in scope
+	var totalEmployees int64
+	if len(departmentIDs) > 0 {
+		totalEmployees, err = s.employeeRepo.CountActiveByDepartments(departmentIDs)
+	} else {
+		totalEmployees, err = s.employeeRepo.GetActiveEmployeesCount()
+	}
 	if err != nil {
 		return nil, err
 	}
 	summary.TotalEmployees = totalEmployees
 
 	// Get attendance statistics
-	attendances, _, err := s.attendanceRepo.GetAttendanceLogs(startDate, endDate, departmentID, "", 1, 10000)
+	totals, err := s.attendanceRepo.GetAttendanceTotals(startDate, endDate, departmentIDs)
 	if err != nil {
 		return nil, err
 	}
 
-	var totalPresent, totalLate int64
-	var totalWorkHours float64
-
-	for _, attendance := range attendances {
-		totalPresent++
-		if attendance.Status == "late" {
-			totalLate++
-		}
-		if attendance.WorkHours != nil {
-			totalWorkHours += *attendance.WorkHours
-		}
-	}
+	totalPresent := totals.TotalPresent
+	totalLate := totals.TotalLate
+	totalWorkHours := totals.TotalWorkHours
 
 	summary.TotalPresent = totalPresent
 	summary.TotalLate = totalLate
@@ -145,7 +199,7 @@ func (s *ReportService) GenerateSummaryReport(startDate, endDate string, departm
 	return &summary, nil
 }
 
-func (s *ReportService) GenerateDepartmentReport(departmentID uint, month, year int) (map[string]interface{}, error) {
+func (s *ReportService) GenerateDepartmentReport(departmentID uint, month, year int, includeSubDepartments bool) (map[string]interface{}, error) {
 	if month == 0 {
 		month = int(time.Now().Month())
 	}
@@ -156,8 +210,13 @@ func (s *ReportService) GenerateDepartmentReport(departmentID uint, month, year
 	// startDate := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
 	// endDate := startDate.AddDate(0, 1, -1)
 
+	departmentIDs, err := s.departmentService.GetDepartmentScope(departmentID, includeSubDepartments)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get department employees
-	employees, err := s.employeeRepo.FindByDepartment(departmentID)
+	employees, err := s.employeeRepo.FindByDepartments(departmentIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -227,4 +286,95 @@ func (s *ReportService) GenerateDepartmentReport(departmentID uint, month, year
 		"department_report": departmentStats,
 		"period":            fmt.Sprintf("%d-%02d", year, month),
 	}, nil
-}
\ No newline at end of file
+}
+
+// DepartmentLabel names the departments a report filter covers, for printed reports
+func (s *ReportService) DepartmentLabel(departmentID uint, includeSubDepartments bool) (string, error) {
+	if departmentID == 0 {
+		return "All departments", nil
+	}
+	department, err := s.departmentRepo.FindByID(departmentID)
+	if err != nil {
+		return "", err
+	}
+	if includeSubDepartments {
+		return department.Name + " and sub-departments", nil
+	}
+	return department.Name, nil
+}
+
+// GenerateHierarchySummary rolls attendance up the department hierarchy, so each
+// node summarises itself and every sub-department below it
+func (s *ReportService) GenerateHierarchySummary(departmentID uint, startDate, endDate string) (*HierarchySummaryReport, error) {
+	departments, err := s.departmentRepo.FindAllDepartments()
+	if err != nil {
+		return nil, err
+	}
+
+	employeeCounts, err := s.employeeRepo.CountActiveGroupedByDepartment()
+	if err != nil {
+		return nil, err
+	}
+
+	attendanceTotals, err := s.attendanceRepo.GetDepartmentAttendanceTotals(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	childrenByParent := make(map[uint][]models.Department)
+	var root *models.Department
+	for i := range departments {
+		if departments[i].ID == departmentID {
+			root = &departments[i]
+		}
+		if departments[i].ParentID != nil {
+			parentID := *departments[i].ParentID
+			childrenByParent[parentID] = append(childrenByParent[parentID], departments[i])
+		}
+	}
+	if root == nil {
+		return nil, utils.NewNotFoundError("department not found")
+	}
+
+	visited := make(map[uint]bool)
+	var rollup func(department models.Department) DepartmentRollup
+	rollup = func(department models.Department) DepartmentRollup {
+		visited[department.ID] = true
+		totals := attendanceTotals[department.ID]
+		node := DepartmentRollup{
+			DepartmentID:   department.ID,
+			DepartmentName: department.Name,
+			TotalEmployees: employeeCounts[department.ID],
+			TotalPresent:   totals.TotalPresent,
+			TotalLate:      totals.TotalLate,
+			Children:       []DepartmentRollup{},
+			workHours:      totals.TotalWorkHours,
+		}
+
+		for _, child := range childrenByParent[department.ID] {
+			if visited[child.ID] {
+				continue
+			}
+			childRollup := rollup(child)
+			node.TotalEmployees += childRollup.TotalEmployees
+			node.TotalPresent += childRollup.TotalPresent
+			node.TotalLate += childRollup.TotalLate
+			node.workHours += childRollup.workHours
+			node.Children = append(node.Children, childRollup)
+		}
+
+		node.TotalAbsent = node.TotalEmployees - node.TotalPresent
+		node.TotalWorkHours = fmt.Sprintf("%.2f hours", node.workHours)
+		if node.TotalPresent > 0 {
+			node.AverageWorkHours = fmt.Sprintf("%.2f hours", node.workHours/float64(node.TotalPresent))
+		} else {
+			node.AverageWorkHours = "0 hours"
+		}
+		return node
+	}
+
+	return &HierarchySummaryReport{
+		Period:   fmt.Sprintf("%s to %s", startDate, endDate),
+		Division: rollup(*root),
+	}, nil
+}
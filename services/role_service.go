@@ -0,0 +1,271 @@
+package services
+
+import (
+	"attendance-system/models"
+	"attendance-system/repositories"
+	"attendance-system/utils"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rolePermissionCache keeps the resolved permission set of each role so the auth
+// middleware doesn't hit the database on every request. It is shared by all
+// RoleService instances and cleared whenever a role changes.
+var rolePermissionCache = struct {
+	sync.RWMutex
+	permissions map[string][]string
+}{permissions: map[string][]string{}}
+
+// adminRole is the built-in role that must always be able to manage users and roles, so
+// there is always a way to restore permissions through the API
+const adminRole = "admin"
+
+var adminRequiredPermissions = []string{models.PermissionRolesManage, models.PermissionUsersManage}
+
+type RoleService struct {
+	roleRepo     *repositories.RoleRepository
+	userRepo     *repositories.UserRepository
+	auditService *AuditService
+}
+
+func NewRoleService() *RoleService {
+	return &RoleService{
+		roleRepo:     repositories.NewRoleRepository(),
+		userRepo:     repositories.NewUserRepository(),
+		auditService: NewAuditService(),
+	}
+}
+
+func (s *RoleService) GetAllRoles() ([]models.Role, error) {
+	return s.roleRepo.FindAll()
+}
+
+func (s *RoleService) GetRoleByID(id uint) (*models.Role, error) {
+	return s.roleRepo.FindByID(id)
+}
+
+func (s *RoleService) GetAllPermissions() ([]models.Permission, error) {
+	return s.roleRepo.FindAllPermissions()
+}
+
+// RoleExists reports whether a role with the given name has been defined
+func (s *RoleService) RoleExists(name string) bool {
+	_, err := s.roleRepo.FindByName(name)
+	return err == nil
+}
+
+func (s *RoleService) CreateRole(req models.RoleRequest, actor models.AuditActor) (*models.Role, error) {
+	name := strings.TrimSpace(req.Name)
+	if s.RoleExists(name) {
+		return nil, utils.NewConflictError("role already exists")
+	}
+
+	permissions, err := s.resolvePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &models.Role{
+		Name:        name,
+		Description: req.Description,
+		RequireMFA:  req.RequireMFA,
+		Permissions: permissions,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	if err := s.roleRepo.Create(role); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, "role.create", models.AuditEntityRole, auditID(role.ID), nil, auditSnapshot(role.ToResponse()))
+
+	invalidateRolePermissions()
+	return role, nil
+}
+
+func (s *RoleService) UpdateRole(id uint, req models.RoleRequest, actor models.AuditActor) (*models.Role, error) {
+	role, err := s.roleRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	before := auditSnapshot(role.ToResponse())
+
+	name := strings.TrimSpace(req.Name)
+	if name != role.Name {
+		if role.IsSystem {
+			return nil, utils.NewBadRequestError("built-in roles cannot be renamed")
+		}
+		if s.RoleExists(name) {
+			return nil, utils.NewConflictError("role already exists")
+		}
+	}
+
+	if role.Name == adminRole {
+		for _, code := range adminRequiredPermissions {
+			if !slices.Contains(req.Permissions, code) {
+				return nil, utils.NewBadRequestError("the admin role must keep the " + code + " permission")
+			}
+		}
+	}
+
+	permissions, err := s.resolvePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	oldName := role.Name
+	role.Name = name
+	role.Description = req.Description
+	role.RequireMFA = req.RequireMFA
+	role.Permissions = permissions
+	role.UpdatedAt = time.Now()
+
+	if err := s.roleRepo.Update(role); err != nil {
+		return nil, err
+	}
+
+	// Users reference roles by name, so carry them over to the new name
+	if oldName != role.Name {
+		if err := s.userRepo.RenameRole(oldName, role.Name); err != nil {
+			return nil, err
+		}
+	}
+	s.auditService.Record(actor, "role.update", models.AuditEntityRole, auditID(role.ID), before, auditSnapshot(role.ToResponse()))
+
+	invalidateRolePermissions()
+	return role, nil
+}
+
+func (s *RoleService) DeleteRole(id uint, actor models.AuditActor) error {
+	role, err := s.roleRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if role.IsSystem {
+		return utils.NewBadRequestError("built-in roles cannot be deleted")
+	}
+
+	count, err := s.userRepo.CountByRole(role.Name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return utils.NewBadRequestError("cannot delete role that is assigned to users")
+	}
+
+	if err := s.roleRepo.Delete(id); err != nil {
+		return err
+	}
+	s.auditService.Record(actor, "role.delete", models.AuditEntityRole, auditID(role.ID), auditSnapshot(role.ToResponse()), nil)
+
+	invalidateRolePermissions()
+	return nil
+}
+
+// RoleRequiresMFA reports whether members of the role must use two-factor authentication
+func (s *RoleService) RoleRequiresMFA(name string) bool {
+	role, err := s.roleRepo.FindByName(name)
+	return err == nil && role.RequireMFA
+}
+
+// AssignRole changes the role of a user account
+func (s *RoleService) AssignRole(userID uint, roleName string, actor models.AuditActor) (*models.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !s.RoleExists(roleName) {
+		return nil, utils.NewBadRequestError("role not found")
+	}
+	if err := s.checkKeepsRoleManager(user, roleName); err != nil {
+		return nil, err
+	}
+	before := auditSnapshot(user)
+
+	user.Role = roleName
+	user.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, "user.role_assign", models.AuditEntityUser, auditID(user.ID), before, auditSnapshot(user))
+
+	return user, nil
+}
+
+// checkKeepsRoleManager refuses to move the last active holder of roles.manage to a role
+// without it, since nobody could grant it back afterwards
+func (s *RoleService) checkKeepsRoleManager(user *models.User, roleName string) error {
+	if !user.IsActive {
+		return nil
+	}
+	current, err := s.GetPermissionsForRole(user.Role)
+	if err != nil || !slices.Contains(current, models.PermissionRolesManage) {
+		return nil
+	}
+	next, err := s.GetPermissionsForRole(roleName)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(next, models.PermissionRolesManage) {
+		return nil
+	}
+
+	others, err := s.userRepo.CountActiveWithPermission(models.PermissionRolesManage, user.ID)
+	if err != nil {
+		return err
+	}
+	if others == 0 {
+		return utils.NewBadRequestError("cannot remove the last active user with the " + models.PermissionRolesManage + " permission")
+	}
+	return nil
+}
+
+// GetPermissionsForRole returns the permission codes granted to the role, using the shared cache
+func (s *RoleService) GetPermissionsForRole(roleName string) ([]string, error) {
+	rolePermissionCache.RLock()
+	permissions, ok := rolePermissionCache.permissions[roleName]
+	rolePermissionCache.RUnlock()
+	if ok {
+		return permissions, nil
+	}
+
+	role, err := s.roleRepo.FindByName(roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions = role.PermissionCodes()
+	rolePermissionCache.Lock()
+	rolePermissionCache.permissions[roleName] = permissions
+	rolePermissionCache.Unlock()
+
+	return permissions, nil
+}
+
+// resolvePermissions maps permission codes to their records, rejecting unknown codes
+func (s *RoleService) resolvePermissions(codes []string) ([]models.Permission, error) {
+	permissions, err := s.roleRepo.FindPermissionsByCodes(codes)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(permissions))
+	for _, permission := range permissions {
+		known[permission.Code] = true
+	}
+	for _, code := range codes {
+		if !known[code] {
+			return nil, utils.NewBadRequestError("unknown permission: " + code)
+		}
+	}
+
+	return permissions, nil
+}
+
+func invalidateRolePermissions() {
+	rolePermissionCache.Lock()
+	rolePermissionCache.permissions = map[string][]string{}
+	rolePermissionCache.Unlock()
+}
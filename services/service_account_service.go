@@ -0,0 +1,200 @@
+package services
+
+import (
+	"attendance-system/models"
+	"attendance-system/repositories"
+	"attendance-system/utils"
+	"crypto/subtle"
+	"log"
+	"strings"
+	"time"
+)
+
+// API keys look like ak_<8 hex prefix>_<64 hex secret>. The prefix is stored in the
+// clear so a key can be found, and shown in listings so admins can tell keys apart.
+const (
+	apiKeyMarker       = "ak_"
+	apiKeyPrefixLength = len(apiKeyMarker) + 8
+	apiKeyDefaultTTL   = 365 * 24 * time.Hour
+	apiKeyTouchPeriod  = time.Minute // Limits last-used writes for busy integrations
+)
+
+// ServiceAccountService manages service accounts and authenticates their API keys
+type ServiceAccountService struct {
+	accountRepo *repositories.ServiceAccountRepository
+}
+
+func NewServiceAccountService() *ServiceAccountService {
+	return &ServiceAccountService{
+		accountRepo: repositories.NewServiceAccountRepository(),
+	}
+}
+
+func (s *ServiceAccountService) GetAll() ([]models.ServiceAccount, error) {
+	return s.accountRepo.FindAll()
+}
+
+func (s *ServiceAccountService) GetByID(id uint) (*models.ServiceAccount, error) {
+	return s.accountRepo.FindByID(id)
+}
+
+func (s *ServiceAccountService) Create(req models.ServiceAccountRequest, createdBy *uint) (*models.ServiceAccount, error) {
+	name := strings.TrimSpace(req.Name)
+	if _, err := s.accountRepo.FindByName(name); err == nil {
+		return nil, utils.NewConflictError("service account already exists")
+	}
+
+	account := &models.ServiceAccount{
+		Name:        name,
+		Description: req.Description,
+		IsActive:    req.IsActive == nil || *req.IsActive,
+		CreatedBy:   createdBy,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if err := s.accountRepo.Create(account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func (s *ServiceAccountService) Update(id uint, req models.ServiceAccountRequest) (*models.ServiceAccount, error) {
+	account, err := s.accountRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name != account.Name {
+		if _, err := s.accountRepo.FindByName(name); err == nil {
+			return nil, utils.NewConflictError("service account already exists")
+		}
+	}
+
+	account.Name = name
+	account.Description = req.Description
+	if req.IsActive != nil {
+		account.IsActive = *req.IsActive
+	}
+	account.UpdatedAt = time.Now()
+
+	if err := s.accountRepo.Update(account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func (s *ServiceAccountService) Delete(id uint) error {
+	return s.accountRepo.Delete(id)
+}
+
+// CreateKey issues a new API key for the service account. The returned key is the
+// only copy; it cannot be recovered later.
+func (s *ServiceAccountService) CreateKey(accountID uint, req models.APIKeyRequest) (*models.CreatedAPIKeyResponse, error) {
+	account, err := s.accountRepo.FindByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes, err := validateScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(apiKeyDefaultTTL)
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			return nil, utils.NewBadRequestError("expires_at must be in the future")
+		}
+		expiresAt = *req.ExpiresAt
+	}
+
+	prefixPart, err := models.GenerateSecureToken(4)
+	if err != nil {
+		return nil, utils.NewInternalServerError("failed to generate API key")
+	}
+	secret, err := models.GenerateSecureToken(32)
+	if err != nil {
+		return nil, utils.NewInternalServerError("failed to generate API key")
+	}
+	prefix := apiKeyMarker + prefixPart
+	plain := prefix + "_" + secret
+
+	key := &models.APIKey{
+		ServiceAccountID: account.ID,
+		Name:             strings.TrimSpace(req.Name),
+		Prefix:           prefix,
+		KeyHash:          utils.HashToken(plain),
+		Scopes:           strings.Join(scopes, ","),
+		ExpiresAt:        expiresAt,
+		CreatedAt:        time.Now(),
+	}
+	if err := s.accountRepo.CreateKey(key); err != nil {
+		return nil, err
+	}
+
+	return &models.CreatedAPIKeyResponse{
+		APIKeyResponse: key.ToResponse(),
+		Key:            plain,
+	}, nil
+}
+
+// RevokeKey stops a key from authenticating
+func (s *ServiceAccountService) RevokeKey(accountID, keyID uint) error {
+	key, err := s.accountRepo.FindKey(accountID, keyID)
+	if err != nil {
+		return err
+	}
+	return s.accountRepo.RevokeKey(key.ID)
+}
+
+// IsAPIKey reports whether the credential has the API key format rather than a JWT
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, apiKeyMarker)
+}
+
+// Authenticate checks an API key and returns it with its service account
+func (s *ServiceAccountService) Authenticate(plain, ipAddress string) (*models.APIKey, error) {
+	if len(plain) <= apiKeyPrefixLength || plain[apiKeyPrefixLength] != '_' || !IsAPIKey(plain) {
+		return nil, utils.NewUnauthorizedError("invalid API key")
+	}
+
+	key, err := s.accountRepo.FindKeyByPrefix(plain[:apiKeyPrefixLength])
+	if err != nil {
+		return nil, utils.NewUnauthorizedError("invalid API key")
+	}
+	if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(utils.HashToken(plain))) != 1 {
+		return nil, utils.NewUnauthorizedError("invalid API key")
+	}
+	if !key.IsUsable() {
+		return nil, utils.NewUnauthorizedError("API key has expired or been revoked")
+	}
+	if key.ServiceAccount == nil || !key.ServiceAccount.IsActive {
+		return nil, utils.NewUnauthorizedError("service account is disabled")
+	}
+
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > apiKeyTouchPeriod || key.LastUsedIP != ipAddress {
+		if err := s.accountRepo.TouchKey(key.ID, ipAddress, time.Now()); err != nil {
+			log.Printf("Failed to record API key use: %v", err)
+		}
+	}
+
+	return key, nil
+}
+
+// validateScopes rejects unknown scopes and removes duplicates
+func validateScopes(scopes []string) ([]string, error) {
+	seen := map[string]bool{}
+	valid := []string{}
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if _, ok := models.ScopePermissions[scope]; !ok {
+			return nil, utils.NewBadRequestError("unknown scope: " + scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			valid = append(valid, scope)
+		}
+	}
+	return valid, nil
+}
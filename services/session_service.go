@@ -0,0 +1,174 @@
+package services
+
+import (
+	"attendance-system/models"
+	"attendance-system/repositories"
+	"attendance-system/utils"
+	"log"
+	"strings"
+	"time"
+)
+
+// sessionTouchInterval limits how often a request updates a session's last-seen time
+const sessionTouchInterval = time.Minute
+
+// SessionService tracks signed-in devices. A session lives as long as its refresh
+// token family, and revoking it also ends the access tokens carrying its ID.
+type SessionService struct {
+	sessionRepo      *repositories.SessionRepository
+	refreshTokenRepo *repositories.RefreshTokenRepository
+}
+
+func NewSessionService() *SessionService {
+	return &SessionService{
+		sessionRepo:      repositories.NewSessionRepository(),
+		refreshTokenRepo: repositories.NewRefreshTokenRepository(),
+	}
+}
+
+// Start records a new session for a login
+func (s *SessionService) Start(userID uint, sessionID string, client models.SessionClient) error {
+	deviceName := strings.TrimSpace(client.DeviceName)
+	if deviceName == "" {
+		deviceName = deviceNameFromUserAgent(client.UserAgent)
+	}
+
+	now := time.Now()
+	session := &models.UserSession{
+		SessionID:  sessionID,
+		UserID:     userID,
+		DeviceName: truncate(deviceName, 100),
+		UserAgent:  truncate(client.UserAgent, 255),
+		IPAddress:  client.IPAddress,
+		LastSeenAt: now,
+		CreatedAt:  now,
+	}
+	return s.sessionRepo.Create(session)
+}
+
+// Validate rejects revoked or unknown sessions and keeps the last-seen time current
+func (s *SessionService) Validate(sessionID string) error {
+	session, err := s.sessionRepo.FindBySessionID(sessionID)
+	if err != nil {
+		return utils.NewUnauthorizedError("session has ended")
+	}
+	if session.RevokedAt != nil {
+		return utils.NewUnauthorizedError("session has been revoked")
+	}
+
+	if time.Since(session.LastSeenAt) > sessionTouchInterval {
+		if err := s.sessionRepo.Touch(sessionID, "", time.Now()); err != nil {
+			log.Printf("Failed to update session last seen: %v", err)
+		}
+	}
+	return nil
+}
+
+// Touch records a refresh from the session's device
+func (s *SessionService) Touch(sessionID string, client models.SessionClient) {
+	if err := s.sessionRepo.Touch(sessionID, client.IPAddress, time.Now()); err != nil {
+		log.Printf("Failed to update session last seen: %v", err)
+	}
+}
+
+// GetUserSessions lists the user's active sessions, flagging the one with the given ID as current
+func (s *SessionService) GetUserSessions(userID uint, currentSessionID string, maxIdle time.Duration) ([]models.SessionResponse, error) {
+	sessions, err := s.sessionRepo.FindActiveByUser(userID, time.Now().Add(-maxIdle))
+	if err != nil {
+		return nil, err
+	}
+
+	responses := []models.SessionResponse{}
+	for _, session := range sessions {
+		responses = append(responses, session.ToResponse(currentSessionID))
+	}
+	return responses, nil
+}
+
+// RevokeUserSession ends one of the user's sessions by its ID
+func (s *SessionService) RevokeUserSession(userID, id uint) error {
+	session, err := s.sessionRepo.FindByIDForUser(id, userID)
+	if err != nil {
+		return err
+	}
+	if session.RevokedAt != nil {
+		return nil
+	}
+	return s.Revoke(session.SessionID)
+}
+
+// Revoke ends a session and its refresh token family
+func (s *SessionService) Revoke(sessionID string) error {
+	if err := s.sessionRepo.Revoke(sessionID); err != nil {
+		return utils.NewInternalServerError("failed to revoke session")
+	}
+	if err := s.refreshTokenRepo.RevokeFamily(sessionID); err != nil {
+		return utils.NewInternalServerError("failed to revoke refresh token")
+	}
+	return nil
+}
+
+// RevokeAllBefore ends every session the user started before the given time
+func (s *SessionService) RevokeAllBefore(userID uint, before time.Time) error {
+	return s.sessionRepo.RevokeUserSessionsBefore(userID, before)
+}
+
+// PruneInactive removes sessions revoked or idle since before the given time
+func (s *SessionService) PruneInactive(before time.Time) error {
+	_, err := s.sessionRepo.DeleteInactive(before)
+	return err
+}
+
+// deviceNameFromUserAgent builds a readable name such as "Chrome on Windows" when the client didn't send one
+func deviceNameFromUserAgent(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	ua := strings.ToLower(userAgent)
+
+	browser := ""
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "opr/") || strings.Contains(ua, "opera"):
+		browser = "Opera"
+	case strings.Contains(ua, "firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	}
+
+	platform := ""
+	switch {
+	case strings.Contains(ua, "android"):
+		platform = "Android"
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad"):
+		platform = "iOS"
+	case strings.Contains(ua, "windows"):
+		platform = "Windows"
+	case strings.Contains(ua, "mac os"):
+		platform = "macOS"
+	case strings.Contains(ua, "linux"):
+		platform = "Linux"
+	}
+
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	}
+	return truncate(userAgent, 100)
+}
+
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	return value[:max]
+}
@@ -0,0 +1,536 @@
+package services
+
+import (
+	"attendance-system/models"
+	"attendance-system/repositories"
+	"attendance-system/utils"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// webhookDeliveryLease is how long a claimed delivery is left alone before another
+	// worker may retry it, well above WEBHOOK_TIMEOUT
+	webhookDeliveryLease = 5 * time.Minute
+	// webhookWorkerBatch is the most deliveries the dispatcher sends per run
+	webhookWorkerBatch = 20
+	// maxWebhookErrorLength fits the last_error column
+	maxWebhookErrorLength = 500
+	// webhookSecretPrefix marks generated signing secrets
+	webhookSecretPrefix = "whsec_"
+)
+
+// webhookWake nudges the dispatcher when an event is queued, so it goes out without
+// waiting for the next tick
+var webhookWake = make(chan struct{}, 1)
+
+// WebhookService manages webhook subscriptions and delivers events to them in the
+// background, signed with each webhook's secret and retried with exponential backoff
+// until they are accepted or marked dead
+type WebhookService struct {
+	webhookRepo  webhookStore
+	auditService *AuditService
+	client       *http.Client
+	maxAttempts  int
+	retryBase    time.Duration
+	retryMax     time.Duration
+	retention    time.Duration
+}
+
+// webhookStore is the part of WebhookRepository the service uses
+type webhookStore interface {
+	FindAll() ([]models.Webhook, error)
+	FindByID(id uint) (*models.Webhook, error)
+	FindActive() ([]models.Webhook, error)
+	Create(webhook *models.Webhook) error
+	Update(webhook *models.Webhook) error
+	Delete(id uint) error
+	CreateDeliveries(deliveries []models.WebhookDelivery) error
+	CreateDelivery(delivery *models.WebhookDelivery) error
+	FindDeliveryByID(id uint) (*models.WebhookDelivery, error)
+	FindDelivery(webhookID, id uint) (*models.WebhookDelivery, error)
+	FindDeliveries(webhookID uint, filter models.WebhookDeliveryFilter, page, limit int) ([]models.WebhookDelivery, *repositories.Pagination, error)
+	FindDueDeliveryIDs(now time.Time, limit int) ([]uint, error)
+	ClaimDelivery(id uint, now, leaseUntil time.Time) (bool, error)
+	MarkDelivered(id uint, deliveredAt time.Time, responseStatus int, durationMs int64) error
+	MarkDeliveryFailed(id uint, status string, nextAttemptAt time.Time, responseStatus int, durationMs int64, lastError string) error
+	RequeueDelivery(id uint, now time.Time) error
+	DeleteFinishedDeliveriesBefore(cutoff time.Time) error
+}
+
+func NewWebhookService() *WebhookService {
+	return &WebhookService{
+		webhookRepo:  repositories.NewWebhookRepository(),
+		auditService: NewAuditService(),
+		client: &http.Client{
+			Timeout: getDurationEnv("WEBHOOK_TIMEOUT", 10*time.Second),
+			// A redirect is a failed delivery, the receiver should be configured with its final URL
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		maxAttempts: getIntEnv("WEBHOOK_MAX_ATTEMPTS", 8),
+		retryBase:   getDurationEnv("WEBHOOK_RETRY_BASE", time.Minute),
+		retryMax:    getDurationEnv("WEBHOOK_RETRY_MAX", time.Hour),
+		retention:   time.Duration(getIntEnv("WEBHOOK_DELIVERY_RETENTION_DAYS", 30)) * 24 * time.Hour,
+	}
+}
+
+func (s *WebhookService) GetWebhooks() ([]models.Webhook, error) {
+	return s.webhookRepo.FindAll()
+}
+
+func (s *WebhookService) GetWebhookByID(id uint) (*models.Webhook, error) {
+	webhook, err := s.webhookRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("webhook not found")
+		}
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// CreateWebhook subscribes a URL to events. The returned secret is generated unless the
+// request sets one; it is the only time the secret is shown.
+func (s *WebhookService) CreateWebhook(req models.WebhookRequest, actor models.AuditActor) (*models.WebhookSecretResponse, error) {
+	events, err := validateWebhook(req)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		token, err := models.GenerateSecureToken(24)
+		if err != nil {
+			return nil, utils.NewInternalServerError("failed to generate webhook secret")
+		}
+		secret = webhookSecretPrefix + token
+	}
+	encryptedSecret, err := utils.EncryptString(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := &models.Webhook{
+		Name:      strings.TrimSpace(req.Name),
+		URL:       req.URL,
+		Secret:    encryptedSecret,
+		Events:    strings.Join(events, ","),
+		IsActive:  req.IsActive == nil || *req.IsActive,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := s.webhookRepo.Create(webhook); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, "webhook.create", models.AuditEntityWebhook, auditID(webhook.ID), nil, auditSnapshot(webhook.ToResponse()))
+
+	return &models.WebhookSecretResponse{WebhookResponse: webhook.ToResponse(), Secret: secret}, nil
+}
+
+// UpdateWebhook changes a webhook, replacing its secret only when the request sets one
+func (s *WebhookService) UpdateWebhook(id uint, req models.WebhookRequest, actor models.AuditActor) (*models.Webhook, error) {
+	webhook, err := s.GetWebhookByID(id)
+	if err != nil {
+		return nil, err
+	}
+	events, err := validateWebhook(req)
+	if err != nil {
+		return nil, err
+	}
+	before := auditSnapshot(webhook.ToResponse())
+
+	webhook.Name = strings.TrimSpace(req.Name)
+	webhook.URL = req.URL
+	webhook.Events = strings.Join(events, ",")
+	if req.IsActive != nil {
+		webhook.IsActive = *req.IsActive
+	}
+	if req.Secret != "" {
+		encryptedSecret, err := utils.EncryptString(req.Secret)
+		if err != nil {
+			return nil, err
+		}
+		webhook.Secret = encryptedSecret
+	}
+	webhook.UpdatedAt = time.Now()
+
+	if err := s.webhookRepo.Update(webhook); err != nil {
+		return nil, err
+	}
+	after := auditSnapshot(webhook.ToResponse())
+	if req.Secret != "" {
+		after["secret_rotated"] = true
+	}
+	s.auditService.Record(actor, "webhook.update", models.AuditEntityWebhook, auditID(webhook.ID), before, after)
+
+	return webhook, nil
+}
+
+func (s *WebhookService) DeleteWebhook(id uint, actor models.AuditActor) error {
+	webhook, err := s.GetWebhookByID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.webhookRepo.Delete(webhook.ID); err != nil {
+		return err
+	}
+	s.auditService.Record(actor, "webhook.delete", models.AuditEntityWebhook, auditID(webhook.ID), auditSnapshot(webhook.ToResponse()), nil)
+
+	return nil
+}
+
+// validateWebhook checks the URL is http(s) and returns the event types without
+// duplicates, rejecting unknown ones
+func validateWebhook(req models.WebhookRequest) ([]string, error) {
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "https" && target.Scheme != "http") || target.Host == "" {
+		return nil, utils.NewBadRequestError("url must be an absolute http(s) URL")
+	}
+
+	seen := map[string]bool{}
+	events := []string{}
+	for _, event := range req.Events {
+		event = strings.TrimSpace(event)
+		known := false
+		for _, eventType := range models.WebhookEventTypes {
+			if event == eventType {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, utils.NewBadRequestError("unknown event type: " + event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// GetDeliveries lists the webhook's delivery log, newest first
+func (s *WebhookService) GetDeliveries(webhookID uint, filter models.WebhookDeliveryFilter, page, limit int) ([]models.WebhookDelivery, *repositories.Pagination, error) {
+	if _, err := s.GetWebhookByID(webhookID); err != nil {
+		return nil, nil, err
+	}
+	switch filter.Status {
+	case "", models.WebhookDeliveryPending, models.WebhookDeliveryDelivered, models.WebhookDeliveryDead:
+	default:
+		return nil, nil, utils.NewBadRequestError("status must be pending, delivered or dead")
+	}
+
+	return s.webhookRepo.FindDeliveries(webhookID, filter, page, limit)
+}
+
+// Redeliver queues a delivery again with a fresh set of attempts. The payload is sent
+// unchanged, with the same event ID, so receivers can tell it is a repeat.
+func (s *WebhookService) Redeliver(webhookID, deliveryID uint, actor models.AuditActor) (*models.WebhookDelivery, error) {
+	delivery, err := s.webhookRepo.FindDelivery(webhookID, deliveryID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("webhook delivery not found")
+		}
+		return nil, err
+	}
+	before := auditSnapshot(delivery)
+
+	if err := s.webhookRepo.RequeueDelivery(delivery.ID, time.Now()); err != nil {
+		return nil, err
+	}
+	wakeWebhookDispatcher()
+
+	delivery, err = s.webhookRepo.FindDelivery(webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, "webhook.redeliver", models.AuditEntityWebhook, auditID(webhookID), before, auditSnapshot(delivery))
+
+	return delivery, nil
+}
+
+// Ping sends a webhook.ping event to the webhook right away, even when it is disabled,
+// and returns the delivery with the outcome. Failed pings are not retried.
+func (s *WebhookService) Ping(id uint) (*models.WebhookDelivery, error) {
+	webhook, err := s.GetWebhookByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	eventID, payload, err := newWebhookEvent(models.WebhookEventPing, map[string]interface{}{
+		"webhook_id": webhook.ID,
+		"events":     webhook.EventList(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Created already claimed, so the dispatcher leaves it to us
+	delivery := &models.WebhookDelivery{
+		WebhookID:     webhook.ID,
+		EventID:       eventID,
+		EventType:     models.WebhookEventPing,
+		Payload:       payload,
+		Status:        models.WebhookDeliveryPending,
+		Attempts:      1,
+		NextAttemptAt: time.Now().Add(webhookDeliveryLease),
+	}
+	if err := s.webhookRepo.CreateDelivery(delivery); err != nil {
+		return nil, err
+	}
+	if err := s.attempt(delivery, webhook); err != nil {
+		return nil, err
+	}
+
+	return s.webhookRepo.FindDelivery(webhook.ID, delivery.ID)
+}
+
+// Emit queues the event for every active webhook subscribed to it. Failures are logged
+// rather than returned so they never fail the change that raised the event.
+func (s *WebhookService) Emit(eventType string, data interface{}) {
+	webhooks, err := s.webhookRepo.FindActive()
+	if err != nil {
+		log.Printf("Failed to load webhooks for %s event: %v", eventType, err)
+		return
+	}
+
+	var subscribed []models.Webhook
+	for _, webhook := range webhooks {
+		if webhook.Subscribes(eventType) {
+			subscribed = append(subscribed, webhook)
+		}
+	}
+	if len(subscribed) == 0 {
+		return
+	}
+
+	eventID, payload, err := newWebhookEvent(eventType, data)
+	if err != nil {
+		log.Printf("Failed to encode %s webhook event: %v", eventType, err)
+		return
+	}
+
+	now := time.Now()
+	deliveries := make([]models.WebhookDelivery, len(subscribed))
+	for i, webhook := range subscribed {
+		deliveries[i] = models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       eventID,
+			EventType:     eventType,
+			Payload:       payload,
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: now,
+		}
+	}
+	if err := s.webhookRepo.CreateDeliveries(deliveries); err != nil {
+		log.Printf("Failed to queue %s webhook event: %v", eventType, err)
+		return
+	}
+	wakeWebhookDispatcher()
+}
+
+// newWebhookEvent wraps the data in an event envelope with a new ID and encodes it
+func newWebhookEvent(eventType string, data interface{}) (string, string, error) {
+	token, err := models.GenerateSecureToken(16)
+	if err != nil {
+		return "", "", err
+	}
+	event := models.WebhookEvent{
+		ID:        "evt_" + token,
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return "", "", err
+	}
+	return event.ID, string(payload), nil
+}
+
+// webhookAttendance is the data of an attendance event
+func webhookAttendance(attendance *models.Attendance) models.WebhookAttendanceData {
+	return models.WebhookAttendanceData{
+		AttendanceID: attendance.AttendanceID,
+		EmployeeID:   attendance.EmployeeID,
+		ClockIn:      attendance.ClockIn,
+		ClockOut:     attendance.ClockOut,
+		WorkHours:    attendance.WorkHours,
+		Status:       attendance.Status,
+	}
+}
+
+// webhookEmployee is the data of an employee event
+func webhookEmployee(employee *models.Employee, previousStatus string) models.WebhookEmployeeData {
+	return models.WebhookEmployeeData{
+		EmployeeID:     employee.EmployeeID,
+		Name:           employee.Name,
+		DepartmentID:   employee.DepartmentID,
+		ManagerID:      employee.ManagerID,
+		Position:       employee.Position,
+		Status:         employee.Status,
+		PreviousStatus: previousStatus,
+	}
+}
+
+// DeliverDue sends the deliveries whose next attempt is due
+func (s *WebhookService) DeliverDue() error {
+	now := time.Now()
+	ids, err := s.webhookRepo.FindDueDeliveryIDs(now, webhookWorkerBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		claimed, err := s.webhookRepo.ClaimDelivery(id, now, now.Add(webhookDeliveryLease))
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue // Another worker got there first
+		}
+		if err := s.deliver(id); err != nil {
+			log.Printf("Failed to update webhook delivery %d: %v", id, err)
+		}
+	}
+
+	if len(ids) == webhookWorkerBatch {
+		wakeWebhookDispatcher() // More may be due, carry on without waiting for the tick
+	}
+	return nil
+}
+
+// deliver makes one attempt at a claimed delivery. Deliveries to a webhook that has
+// since been disabled are given up.
+func (s *WebhookService) deliver(id uint) error {
+	delivery, err := s.webhookRepo.FindDeliveryByID(id)
+	if err != nil {
+		return err
+	}
+	if delivery.Webhook == nil || !delivery.Webhook.IsActive {
+		return s.webhookRepo.MarkDeliveryFailed(delivery.ID, models.WebhookDeliveryDead, time.Now(), 0, 0, "webhook is disabled")
+	}
+	return s.attempt(delivery, delivery.Webhook)
+}
+
+// attempt sends a claimed delivery and records the outcome
+func (s *WebhookService) attempt(delivery *models.WebhookDelivery, webhook *models.Webhook) error {
+	started := time.Now()
+	responseStatus, sendErr := s.send(webhook, delivery)
+	durationMs := time.Since(started).Milliseconds()
+
+	if sendErr == nil {
+		return s.webhookRepo.MarkDelivered(delivery.ID, time.Now(), responseStatus, durationMs)
+	}
+
+	lastError := sendErr.Error()
+	if len(lastError) > maxWebhookErrorLength {
+		lastError = lastError[:maxWebhookErrorLength]
+	}
+
+	if delivery.EventType == models.WebhookEventPing || delivery.Attempts >= s.maxAttempts {
+		log.Printf("Giving up on %s webhook delivery %d to %s after %d attempts: %v", delivery.EventType, delivery.ID, webhook.URL, delivery.Attempts, sendErr)
+		return s.webhookRepo.MarkDeliveryFailed(delivery.ID, models.WebhookDeliveryDead, time.Now(), responseStatus, durationMs, lastError)
+	}
+
+	retryAt := time.Now().Add(retryBackoff(s.retryBase, s.retryMax, delivery.Attempts))
+	log.Printf("Failed to deliver %s webhook %d to %s (attempt %d), retrying at %s: %v", delivery.EventType, delivery.ID, webhook.URL, delivery.Attempts, retryAt.Format(time.RFC3339), sendErr)
+	return s.webhookRepo.MarkDeliveryFailed(delivery.ID, models.WebhookDeliveryPending, retryAt, responseStatus, durationMs, lastError)
+}
+
+// send posts the payload to the webhook, signed with its secret, and returns the HTTP
+// status of the response. Any status other than 2xx is a failure.
+func (s *WebhookService) send(webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	secret, err := utils.DecryptString(webhook.Secret)
+	if err != nil {
+		return 0, errors.New("cannot decrypt webhook secret, was ENCRYPTION_KEY changed?")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "attendance-system-webhooks")
+	req.Header.Set("X-Webhook-ID", delivery.EventID)
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+signWebhook(secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return resp.StatusCode, nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+	return resp.StatusCode, fmt.Errorf("receiver responded %s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
+
+// signWebhook is the hex HMAC-SHA256 of "<timestamp>.<payload>" keyed with the secret.
+// Signing the timestamp lets receivers reject replayed deliveries.
+func signWebhook(secret, timestamp, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// PruneFinished removes delivered and dead deliveries older than the retention period
+func (s *WebhookService) PruneFinished() error {
+	return s.webhookRepo.DeleteFinishedDeliveriesBefore(time.Now().Add(-s.retention))
+}
+
+func wakeWebhookDispatcher() {
+	select {
+	case webhookWake <- struct{}{}:
+	default: // A wake-up is already pending
+	}
+}
+
+// StartWebhookDispatcher delivers queued webhook events in the background, checking for
+// due deliveries every interval and whenever an event is queued
+func StartWebhookDispatcher(interval time.Duration) {
+	webhookService := NewWebhookService()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		pruneTicker := time.NewTicker(time.Hour)
+		defer pruneTicker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-webhookWake:
+			case <-pruneTicker.C:
+				if err := webhookService.PruneFinished(); err != nil {
+					log.Printf("Failed to prune webhook deliveries: %v", err)
+				}
+				continue
+			}
+
+			if err := webhookService.DeliverDue(); err != nil {
+				log.Printf("Failed to deliver webhook events: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"attendance-system/models"
+	"attendance-system/utils"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeWebhookStore records the outcome of delivery attempts. Any other repository
+// call panics on the nil embedded interface.
+type fakeWebhookStore struct {
+	webhookStore
+
+	delivered      bool
+	failedStatus   string
+	nextAttemptAt  time.Time
+	responseStatus int
+	lastError      string
+}
+
+func (f *fakeWebhookStore) MarkDelivered(id uint, deliveredAt time.Time, responseStatus int, durationMs int64) error {
+	f.delivered = true
+	f.responseStatus = responseStatus
+	return nil
+}
+
+func (f *fakeWebhookStore) MarkDeliveryFailed(id uint, status string, nextAttemptAt time.Time, responseStatus int, durationMs int64, lastError string) error {
+	f.failedStatus = status
+	f.nextAttemptAt = nextAttemptAt
+	f.responseStatus = responseStatus
+	f.lastError = lastError
+	return nil
+}
+
+// receivedWebhook is what the local receiver saw
+type receivedWebhook struct {
+	header http.Header
+	body   string
+}
+
+// newWebhookReceiver starts a local receiver that answers every request with the handler
+func newWebhookReceiver(t *testing.T, handler http.HandlerFunc) (*httptest.Server, chan receivedWebhook) {
+	t.Helper()
+	received := make(chan receivedWebhook, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		select {
+		case received <- receivedWebhook{header: r.Header.Clone(), body: string(body)}:
+		default:
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func newTestWebhookService(t *testing.T, receiverURL, secret string) (*WebhookService, *fakeWebhookStore, *models.Webhook) {
+	t.Helper()
+	t.Setenv("WEBHOOK_RETRY_BASE", "1m")
+	t.Setenv("WEBHOOK_RETRY_MAX", "1h")
+	t.Setenv("WEBHOOK_MAX_ATTEMPTS", "8")
+
+	encrypted, err := utils.EncryptString(secret)
+	if err != nil {
+		t.Fatalf("encrypt secret: %v", err)
+	}
+
+	store := &fakeWebhookStore{}
+	service := NewWebhookService()
+	service.webhookRepo = store
+	webhook := &models.Webhook{ID: 1, URL: receiverURL, Secret: encrypted, IsActive: true}
+	return service, store, webhook
+}
+
+func newTestDelivery(attempts int) *models.WebhookDelivery {
+	return &models.WebhookDelivery{
+		ID:        7,
+		WebhookID: 1,
+		EventID:   "evt_test",
+		EventType: models.WebhookEventClockIn,
+		Payload:   `{"id":"evt_test","type":"attendance.clock_in","data":{"employee_id":"EMP001"}}`,
+		Status:    models.WebhookDeliveryPending,
+		Attempts:  attempts,
+	}
+}
+
+func TestWebhookDeliverySignedAndDelivered(t *testing.T) {
+	const secret = "whsec_test"
+	server, received := newWebhookReceiver(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	service, store, webhook := newTestWebhookService(t, server.URL, secret)
+	delivery := newTestDelivery(1)
+
+	if err := service.attempt(delivery, webhook); err != nil {
+		t.Fatalf("attempt: %v", err)
+	}
+
+	request := <-received
+	if request.body != delivery.Payload {
+		t.Fatalf("body = %q, want %q", request.body, delivery.Payload)
+	}
+	timestamp := request.header.Get("X-Webhook-Timestamp")
+	if timestamp == "" {
+		t.Fatal("missing X-Webhook-Timestamp")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + delivery.Payload))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := request.header.Get("X-Webhook-Signature"); got != want {
+		t.Fatalf("X-Webhook-Signature = %q, want %q", got, want)
+	}
+	if got := request.header.Get("X-Webhook-Event"); got != delivery.EventType {
+		t.Fatalf("X-Webhook-Event = %q, want %q", got, delivery.EventType)
+	}
+
+	if !store.delivered || store.responseStatus != http.StatusNoContent {
+		t.Fatalf("delivery not recorded as delivered: %+v", store)
+	}
+}
+
+func TestWebhookDeliveryServerErrorRetriesWithBackoff(t *testing.T) {
+	server, _ := newWebhookReceiver(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+	service, store, webhook := newTestWebhookService(t, server.URL, "whsec_test")
+
+	before := time.Now()
+	if err := service.attempt(newTestDelivery(3), webhook); err != nil {
+		t.Fatalf("attempt: %v", err)
+	}
+
+	if store.delivered {
+		t.Fatal("5xx response recorded as delivered")
+	}
+	if store.failedStatus != models.WebhookDeliveryPending || store.responseStatus != http.StatusServiceUnavailable {
+		t.Fatalf("failure recorded as %q with status %d", store.failedStatus, store.responseStatus)
+	}
+	// The third attempt waits base * 2^2
+	wait := store.nextAttemptAt.Sub(before)
+	if wait < 4*time.Minute || wait > 4*time.Minute+5*time.Second {
+		t.Fatalf("next attempt in %s, want about 4m", wait)
+	}
+}
+
+func TestWebhookDeliveryRedirectIsFailure(t *testing.T) {
+	var followed atomic.Bool
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		followed.Store(true)
+	}))
+	defer target.Close()
+	server, _ := newWebhookReceiver(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusFound)
+	})
+	service, store, webhook := newTestWebhookService(t, server.URL, "whsec_test")
+
+	before := time.Now()
+	if err := service.attempt(newTestDelivery(1), webhook); err != nil {
+		t.Fatalf("attempt: %v", err)
+	}
+
+	if followed.Load() {
+		t.Fatal("redirect was followed")
+	}
+	if store.delivered || store.failedStatus != models.WebhookDeliveryPending || store.responseStatus != http.StatusFound {
+		t.Fatalf("redirect not recorded as a failed attempt: %+v", store)
+	}
+	wait := store.nextAttemptAt.Sub(before)
+	if wait < time.Minute || wait > time.Minute+5*time.Second {
+		t.Fatalf("next attempt in %s, want about 1m", wait)
+	}
+}
+
+func TestWebhookDeliveryGivesUpAfterMaxAttempts(t *testing.T) {
+	server, _ := newWebhookReceiver(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	service, store, webhook := newTestWebhookService(t, server.URL, "whsec_test")
+
+	if err := service.attempt(newTestDelivery(8), webhook); err != nil {
+		t.Fatalf("attempt: %v", err)
+	}
+
+	if store.failedStatus != models.WebhookDeliveryDead {
+		t.Fatalf("status after the last attempt = %q, want %q", store.failedStatus, models.WebhookDeliveryDead)
+	}
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"os"
+)
+
+// developmentEncryptionKeys are the fallback and the documented example value, neither of
+// which may protect real data
+var developmentEncryptionKeys = map[string]bool{
+	"":                         true,
+	"change-me-encryption-key": true,
+}
+
+// CheckEncryptionKey refuses to run production with a development ENCRYPTION_KEY
+func CheckEncryptionKey() error {
+	if os.Getenv("APP_ENV") == "production" && developmentEncryptionKeys[os.Getenv("ENCRYPTION_KEY")] {
+		return errors.New("ENCRYPTION_KEY must be set to a private value in production")
+	}
+	return nil
+}
+
+// encryptionKey derives the AES-256 key used for secrets stored at rest from ENCRYPTION_KEY
+func encryptionKey() []byte {
+	secret := os.Getenv("ENCRYPTION_KEY")
+	if secret == "" {
+		secret = "dev-encryption-key-change-in-production"
+	}
+	key := sha256.Sum256([]byte(secret))
+	return key[:]
+}
+
+// EncryptString encrypts a secret with AES-GCM and returns it base64 encoded with its nonce
+func EncryptString(plain string) (string, error) {
+	block, err := aes.NewCipher(encryptionKey())
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plain), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptString reverses EncryptString
+func DecryptString(encoded string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+
+	block, err := aes.NewCipher(encryptionKey())
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+
+	plain, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"attendance-system/models"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"math/big"
@@ -21,6 +23,13 @@ func GenerateID(prefix string, length int) string {
 	return prefix + hex.EncodeToString(bytes)
 }
 
+// HashToken returns the hex SHA-256 of an opaque token so it can be stored and looked up
+// without keeping the token itself
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 // GenerateEmployeeID generates a unique employee ID
 func GenerateEmployeeID() string {
 	timestamp := time.Now().Unix()
@@ -108,23 +117,91 @@ func GetUserRoleFromContext(c *gin.Context) string {
 	return ""
 }
 
+// GetEmployeeIDFromContext extracts the employee ID linked to the authenticated user
+func GetEmployeeIDFromContext(c *gin.Context) string {
+	if employeeID, exists := c.Get("employee_id"); exists {
+		return employeeID.(string)
+	}
+	return ""
+}
+
+// GetClaimsFromContext extracts the validated JWT claims of the current request
+func GetClaimsFromContext(c *gin.Context) *JWTClaims {
+	if claims, exists := c.Get("claims"); exists {
+		return claims.(*JWTClaims)
+	}
+	return nil
+}
+
+// GetPermissionsFromContext extracts the permissions granted to the user's role
+func GetPermissionsFromContext(c *gin.Context) []string {
+	if permissions, exists := c.Get("permissions"); exists {
+		return permissions.([]string)
+	}
+	return []string{}
+}
+
+// HasPermission checks if the user's role grants the given permission
+func HasPermission(c *gin.Context, permission string) bool {
+	for _, granted := range GetPermissionsFromContext(c) {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
 // IsAdmin checks if user is admin
 func IsAdmin(c *gin.Context) bool {
 	return GetUserRoleFromContext(c) == "admin"
 }
 
-// GetClientIP gets the client IP address
-func GetClientIP(c *gin.Context) string {
-	// Check for forwarded IP first
-	if forwarded := c.GetHeader("X-Forwarded-For"); forwarded != "" {
-		ips := strings.Split(forwarded, ",")
-		return strings.TrimSpace(ips[0])
+// CanAccessEmployee checks if the authenticated user may see the given employee's private data
+func CanAccessEmployee(c *gin.Context, employeeID string) bool {
+	if HasPermission(c, models.PermissionEmployeesViewAll) {
+		return true
 	}
-	
-	// Fall back to remote address
+	ownEmployeeID := GetEmployeeIDFromContext(c)
+	return ownEmployeeID != "" && ownEmployeeID == employeeID
+}
+
+// GetClientIP gets the client IP address. Forwarding headers are only read from the
+// proxies in TRUSTED_PROXIES, so clients can't choose their own address.
+func GetClientIP(c *gin.Context) string {
 	return c.ClientIP()
 }
 
+// GetSessionClient describes the device making the request for session tracking
+func GetSessionClient(c *gin.Context, deviceName string) models.SessionClient {
+	return models.SessionClient{
+		DeviceName: deviceName,
+		UserAgent:  c.Request.UserAgent(),
+		IPAddress:  GetClientIP(c),
+	}
+}
+
+// GetAuditActor describes who is making the request for the audit log
+func GetAuditActor(c *gin.Context) models.AuditActor {
+	actor := models.AuditActor{
+		Type:      models.AuditActorAnonymous,
+		IPAddress: GetClientIP(c),
+		RequestID: c.GetString("request_id"),
+	}
+
+	if userID := GetUserIDFromContext(c); userID != "" {
+		actor.Type = models.AuditActorUser
+		actor.ID = userID
+		actor.Name = c.GetString("username")
+		actor.EmployeeID = GetEmployeeIDFromContext(c)
+	} else if serviceAccountID, exists := c.Get("service_account_id"); exists {
+		actor.Type = models.AuditActorServiceAccount
+		actor.ID = fmt.Sprint(serviceAccountID)
+		actor.Name = c.GetString("username")
+	}
+
+	return actor
+}
+
 // FormatBytes formats bytes to human readable string
 func FormatBytes(bytes int64) string {
 	const unit = 1024
@@ -2,73 +2,72 @@ package utils
 
 import (
 	"errors"
-	"os"
-	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Token uses. Only access tokens are accepted by the API; an MFA challenge token
+// can only be exchanged for access tokens at /auth/mfa/verify.
+const (
+	TokenUseAccess       = "access"
+	TokenUseMFAChallenge = "mfa_challenge"
+)
+
+// RestrictionMFAEnrollment marks an access token that may only be used to enroll in
+// two-factor authentication, issued when the user's role requires it
+const RestrictionMFAEnrollment = "mfa_enrollment"
+
+// RestrictionPasswordChange marks an access token that may only be used to change an
+// expired password
+const RestrictionPasswordChange = "password_change"
+
 // JWTClaims represents the JWT claims structure
 type JWTClaims struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	UserID      string `json:"user_id"`
+	Username    string `json:"username"`
+	Role        string `json:"role"`
+	EmployeeID  string `json:"employee_id,omitempty"`
+	SessionID   string `json:"sid,omitempty"` // Refresh token family the access token belongs to
+	TokenUse    string `json:"use,omitempty"`
+	Restriction string `json:"restriction,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateJWT generates a new JWT token
-func GenerateJWT(userID, username, role string) (string, error) {
-	// Get JWT secret from environment
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "your-default-secret-key" // In production, always use environment variable
-	}
+// JWTIssuer is the iss claim of every token the API issues
+const JWTIssuer = "attendance-system"
 
-	// Set expiration time
-	expiryTime := time.Now().Add(24 * time.Hour) // 24 hours
-
-	// Create claims
-	claims := &JWTClaims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expiryTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "attendance-system",
-			Subject:   userID,
-		},
-	}
-
-	// Create token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// Sign token
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+// SignJWT signs the claims with the active key and sets the kid header
+func SignJWT(claims *JWTClaims) (string, error) {
+	set, err := getJWTKeySet()
 	if err != nil {
 		return "", err
 	}
 
-	return tokenString, nil
+	token := jwt.NewWithClaims(set.active.Method, claims)
+	token.Header["kid"] = set.active.ID
+	return token.SignedString(set.active.PrivateKey)
 }
 
-// ValidateJWT validates a JWT token
+// ValidateJWT validates a JWT token against the key named by its kid header
 func ValidateJWT(tokenString string) (*JWTClaims, error) {
-	// Get JWT secret from environment
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "your-default-secret-key"
+	set, err := getJWTKeySet()
+	if err != nil {
+		return nil, err
 	}
 
 	// Parse token
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
+		keyID, _ := token.Header["kid"].(string)
+		key, ok := set.byID[keyID]
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
 		// Validate signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if token.Method.Alg() != key.Method.Alg() {
 			return nil, errors.New("unexpected signing method")
 		}
-		return []byte(jwtSecret), nil
-	})
+		return key.PublicKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}), jwt.WithIssuer(JWTIssuer))
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,203 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwtKey is one key from JWT_KEYS_DIR. Keys without a private part can only verify tokens,
+// which is how a retired key is kept until the tokens it signed have expired.
+type jwtKey struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+// jwtKeySet holds every key tokens are accepted from and the one new tokens are signed with
+type jwtKeySet struct {
+	active *jwtKey
+	byID   map[string]*jwtKey
+	order  []string
+}
+
+var jwtKeys struct {
+	once sync.Once
+	set  *jwtKeySet
+	err  error
+}
+
+// LoadJWTKeys loads the signing keys once. main calls it at startup so a bad key directory
+// stops the server instead of failing every login.
+//
+// Each *.pem file in JWT_KEYS_DIR is a key whose ID (the kid header) is the file name
+// without the extension. RSA keys sign with RS256 and Ed25519 keys with EdDSA. New tokens
+// are signed with JWT_ACTIVE_KID, or the last private key by file name when unset.
+// Without JWT_KEYS_DIR a throwaway Ed25519 key is generated, which production refuses.
+func LoadJWTKeys() error {
+	jwtKeys.once.Do(func() {
+		jwtKeys.set, jwtKeys.err = loadJWTKeySet()
+	})
+	return jwtKeys.err
+}
+
+func getJWTKeySet() (*jwtKeySet, error) {
+	if err := LoadJWTKeys(); err != nil {
+		return nil, err
+	}
+	return jwtKeys.set, nil
+}
+
+func loadJWTKeySet() (*jwtKeySet, error) {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		if os.Getenv("APP_ENV") == "production" {
+			return nil, errors.New("JWT_KEYS_DIR must be set in production")
+		}
+		return generateDevelopmentKeySet()
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	set := &jwtKeySet{byID: map[string]*jwtKey{}}
+	for _, path := range paths {
+		key, err := readJWTKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		set.byID[key.ID] = key
+		set.order = append(set.order, key.ID)
+		if key.PrivateKey != nil {
+			set.active = key
+		}
+	}
+
+	if activeID := os.Getenv("JWT_ACTIVE_KID"); activeID != "" {
+		key, ok := set.byID[activeID]
+		if !ok || key.PrivateKey == nil {
+			return nil, fmt.Errorf("JWT_ACTIVE_KID %q is not a private key in %s", activeID, dir)
+		}
+		set.active = key
+	}
+	if set.active == nil {
+		return nil, fmt.Errorf("no private key found in %s", dir)
+	}
+
+	log.Printf("Loaded %d JWT keys, signing with %s (%s)", len(set.order), set.active.ID, set.active.Method.Alg())
+	return set, nil
+}
+
+// readJWTKey parses a PKCS#8 or PKCS#1 private key, or a PKIX public key
+func readJWTKey(path string) (*jwtKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &jwtKey{ID: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.PrivateKey, key.PublicKey = k, &k.PublicKey
+	case ed25519.PrivateKey:
+		key.PrivateKey, key.PublicKey = k, k.Public()
+	case *rsa.PublicKey, ed25519.PublicKey:
+		key.PublicKey = k
+	default:
+		return nil, errors.New("only RSA and Ed25519 keys are supported")
+	}
+
+	switch pub := key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	}
+	return key, nil
+}
+
+// generateDevelopmentKeySet creates a key that only lives as long as the process, so
+// tokens stop working after a restart
+func generateDevelopmentKeySet() (*jwtKeySet, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+
+	key := &jwtKey{
+		ID:         "dev-" + hex.EncodeToString(suffix),
+		Method:     jwt.SigningMethodEdDSA,
+		PrivateKey: private,
+		PublicKey:  public,
+	}
+	log.Printf("⚠️ JWT_KEYS_DIR is not set, signing tokens with a temporary key (%s) that is lost on restart", key.ID)
+
+	return &jwtKeySet{
+		active: key,
+		byID:   map[string]*jwtKey{key.ID: key},
+		order:  []string{key.ID},
+	}, nil
+}
+
+// PublicJWKS returns the public half of every key, for other services verifying our tokens
+func PublicJWKS() (JWKS, error) {
+	set, err := getJWTKeySet()
+	if err != nil {
+		return JWKS{}, err
+	}
+
+	jwks := JWKS{Keys: []JWK{}}
+	for _, id := range set.order {
+		key := set.byID[id]
+		jwk, err := NewJWK(key.ID, key.Method.Alg(), key.PublicKey)
+		if err != nil {
+			return JWKS{}, err
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks, nil
+}
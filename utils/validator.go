@@ -49,6 +49,11 @@ func NewConflictError(message string) *CustomError {
 	return NewCustomError(http.StatusConflict, message)
 }
 
+// NewTooManyRequestsError creates a 429 error
+func NewTooManyRequestsError(message string) *CustomError {
+	return NewCustomError(http.StatusTooManyRequests, message)
+}
+
 // NewInternalServerError creates a 500 error
 func NewInternalServerError(message string) *CustomError {
 	return NewCustomError(http.StatusInternalServerError, message)